@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type grantAdminConfig struct {
+	db    dbConfig
+	email string
+}
+
+func (app *Application) parseGrantAdminArgs(args []string) (grantAdminConfig, error) {
+	config := grantAdminConfig{db: defaultConfig().db}
+
+	fs := flag.NewFlagSet("grant-admin", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.StringVar(&config.db.dsn, "db-dsn", config.db.dsn, "Database DSN")
+
+	if err := fs.Parse(args); err != nil {
+		return config, err
+	}
+
+	if fs.NArg() != 1 {
+		return config, errors.New("usage: grant-admin [-db-dsn dsn] email")
+	}
+	config.email = fs.Arg(0)
+
+	return config, nil
+}
+
+// GrantAdmin implements the grant-admin subcommand, which gives a registered
+// user the admin permission. The API has no way to grant it, so this is how
+// the first administrator is made.
+func (app *Application) GrantAdmin(ctx context.Context, args []string, out io.Writer) error {
+	config, err := app.parseGrantAdminArgs(args)
+	if err != nil {
+		return err
+	}
+
+	st, err := app.openStore(config.db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	user, err := st.users.GetByEmail(config.email)
+	if err != nil {
+		if err == models.ErrRecordNotFound {
+			return fmt.Errorf("no user with email %q", config.email)
+		}
+		return err
+	}
+
+	err = st.permissions.AddForUser(user.ID, models.PermissionAdmin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Granted %s to %s\n", models.PermissionAdmin, user.Email)
+
+	return nil
+}
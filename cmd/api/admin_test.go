@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestParseGrantAdminArgs(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	config, err := app.parseGrantAdminArgs([]string{"-db-dsn", "postgres://test", "alice@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.db.dsn != "postgres://test" {
+		t.Errorf("expected dsn to be 'postgres://test', got '%s'", config.db.dsn)
+	}
+	if config.email != "alice@example.com" {
+		t.Errorf("expected email to be 'alice@example.com', got '%s'", config.email)
+	}
+}
+
+func TestParseGrantAdminArgs_MissingEmail(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	for _, args := range [][]string{{}, {"-db-dsn", "postgres://test"}, {"alice@example.com", "bob@example.com"}} {
+		if _, err := app.parseGrantAdminArgs(args); err == nil {
+			t.Errorf("expected error for args %q", args)
+		}
+	}
+}
+
+func TestGrantAdmin(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+	ctx := context.Background()
+
+	cfg := defaultConfig().db
+	cfg.dsn = "sqlite://" + filepath.Join(t.TempDir(), "rss.db")
+
+	st, err := app.openStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer st.Close()
+
+	if err := app.migrate(ctx, st.migrator); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user := &models.User{Email: "alice@example.com", Password: models.Password{Hash: []byte("hash")}}
+	if err := st.users.Create(user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+
+	if err := app.GrantAdmin(ctx, []string{"-db-dsn", cfg.dsn, "alice@example.com"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "Granted admin to alice@example.com\n"; out.String() != want {
+		t.Errorf("got output %q, want %q", out.String(), want)
+	}
+
+	permissions, err := st.permissions.GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(permissions, models.PermissionAdmin) {
+		t.Errorf("got permissions %v, want admin granted", permissions)
+	}
+
+	err = app.GrantAdmin(ctx, []string{"-db-dsn", cfg.dsn, "bob@example.com"}, &out)
+	if err == nil || err.Error() != `no user with email "bob@example.com"` {
+		t.Errorf("got error %v, want the missing user reported", err)
+	}
+}
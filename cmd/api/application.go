@@ -4,10 +4,14 @@ import (
 	"context"
 	"flag"
 	"log/slog"
+	"net/netip"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/grodier/rss-app/internal/pgsql"
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/poller"
 	"github.com/grodier/rss-app/internal/server"
 )
 
@@ -24,20 +28,49 @@ func NewApplication(logger *slog.Logger) *Application {
 }
 
 type config struct {
-	env    string
-	server serverConfig
-	db     dbConfig
+	env     string
+	server  serverConfig
+	db      dbConfig
+	poller  pollerConfig
+	limiter limiterConfig
+	cors    corsConfig
+	metrics metricsConfig
 }
 
 type serverConfig struct {
 	port int
 }
 
+type limiterConfig struct {
+	enabled        bool
+	ipRPS          float64
+	ipBurst        int
+	userRPS        float64
+	userBurst      int
+	trustedProxies []netip.Prefix
+}
+
+type corsConfig struct {
+	trustedOrigins []string
+}
+
+type metricsConfig struct {
+	addr string
+}
+
 type dbConfig struct {
 	dsn                string
 	maxOpenConnections int
 	maxIdleConnections int
 	maxIdleTime        time.Duration
+	queryTimeout       time.Duration
+	autoMigrate        bool
+}
+
+type pollerConfig struct {
+	interval time.Duration
+	workers  int
+	jitter   time.Duration
 }
 
 func defaultConfig() config {
@@ -51,36 +84,115 @@ func defaultConfig() config {
 			maxOpenConnections: 25,
 			maxIdleConnections: 25,
 			maxIdleTime:        15 * time.Minute,
+			queryTimeout:       3 * time.Second,
+		},
+		poller: pollerConfig{
+			interval: 30 * time.Minute,
+			workers:  4,
+			jitter:   5 * time.Minute,
+		},
+		limiter: limiterConfig{
+			enabled:   true,
+			ipRPS:     20,
+			ipBurst:   40,
+			userRPS:   10,
+			userBurst: 20,
+		},
+		// Metrics are only served to the local host unless configured
+		// otherwise, as scraping them needs no credentials.
+		metrics: metricsConfig{
+			addr: "localhost:8081",
 		},
 	}
 }
 
+// Run starts the API server and feed poller, or runs the subcommand named by
+// the first argument.
 func (app *Application) Run(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "grant-admin":
+			return app.GrantAdmin(ctx, args[1:], os.Stdout)
+		case "import-opml":
+			return app.ImportOPML(ctx, args[1:], os.Stdout)
+		case "migrate":
+			return app.Migrate(ctx, args[1:], os.Stdout)
+		}
+	}
+
 	app.config = app.ParseConfigs(args)
 
-	db := pgsql.NewDB(app.config.db.dsn)
-	db.MaxOpenConnections = app.config.db.maxOpenConnections
-	db.MaxIdleConnections = app.config.db.maxIdleConnections
-	db.MaxIdleTime = app.config.db.maxIdleTime
-	if err := db.Open(); err != nil {
+	st, err := app.openStore(app.config.db)
+	if err != nil {
 		return err
 	}
-	defer db.Close()
+	defer st.Close()
 
-	app.logger.Info("database connection pool established")
+	if app.config.db.autoMigrate {
+		if err := app.migrate(ctx, st.migrator); err != nil {
+			return err
+		}
+	}
+
+	registry := metrics.NewRegistry()
+	st.db.RegisterMetrics(registry)
+
+	feedFetcher := fetcher.NewFetcher()
+	feedFetcher.UserAgent = "rss-app/" + version
+
+	p := poller.NewPoller(app.logger)
+	p.Interval = app.config.poller.interval
+	p.Workers = app.config.poller.workers
+	p.Jitter = app.config.poller.jitter
+	p.FeedService = st.feeds
+	p.ItemService = st.items
+	p.Fetcher = feedFetcher
+	p.RegisterMetrics(registry)
 
 	srv := server.NewServer(app.logger)
 	srv.Port = app.config.server.port
 	srv.Env = app.config.env
 	srv.Version = version
+	srv.RateLimit = server.RateLimitConfig{
+		Enabled:               app.config.limiter.enabled,
+		IPRequestsPerSecond:   app.config.limiter.ipRPS,
+		IPBurst:               app.config.limiter.ipBurst,
+		UserRequestsPerSecond: app.config.limiter.userRPS,
+		UserBurst:             app.config.limiter.userBurst,
+		TrustedProxies:        app.config.limiter.trustedProxies,
+	}
+	srv.TrustedOrigins = app.config.cors.trustedOrigins
+	srv.Metrics = registry
+	srv.MetricsAddr = app.config.metrics.addr
 
-	srv.FeedService = pgsql.NewFeedService(db)
+	srv.FeedService = st.feeds
+	srv.ItemService = st.items
+	srv.UserService = st.users
+	srv.TokenService = st.tokens
+	srv.PermissionService = st.permissions
+	srv.SubscriptionService = st.subscriptions
+	srv.ItemStateService = st.itemStates
+	srv.FolderService = st.folders
+	srv.FeedFetcher = feedFetcher
+	srv.Transactor = st.transactor
 
-	if err := srv.Serve(); err != nil {
-		return err
-	}
+	pollerCtx, stopPoller := context.WithCancel(ctx)
+	defer stopPoller()
+
+	pollerDone := make(chan struct{})
+	go func() {
+		defer close(pollerDone)
+		p.Run(pollerCtx)
+	}()
 
-	return nil
+	srv.RegisterOnShutdown(stopPoller)
+
+	err = srv.Serve()
+
+	stopPoller()
+	<-pollerDone
+
+	return err
 }
 
 func (app *Application) ParseConfigs(args []string) config {
@@ -91,17 +203,103 @@ func (app *Application) ParseConfigs(args []string) config {
 	fs.StringVar(&config.env, "env", config.env, "Environment (development|production)")
 	fs.IntVar(&config.server.port, "port", config.server.port, "Server port")
 
-	fs.StringVar(&config.db.dsn, "db-dsn", config.db.dsn, "Database DSN")
+	fs.StringVar(&config.db.dsn, "db-dsn", config.db.dsn, "Database DSN (postgres://... or sqlite://path/to/file.db)")
 	fs.IntVar(&config.db.maxOpenConnections, "db-max-open-conns", config.db.maxOpenConnections, "Database max open connections")
 	fs.IntVar(&config.db.maxIdleConnections, "db-max-idle-conns", config.db.maxIdleConnections, "Database max idle connections")
 	fs.DurationVar(&config.db.maxIdleTime, "db-max-idle-time", config.db.maxIdleTime, "Database max idle time")
+	fs.DurationVar(&config.db.queryTimeout, "db-query-timeout", config.db.queryTimeout, "Maximum duration of a single database query")
+	fs.BoolVar(&config.db.autoMigrate, "db-auto-migrate", config.db.autoMigrate, "Apply pending database migrations on startup")
+
+	fs.DurationVar(&config.poller.interval, "poll-interval", config.poller.interval, "Interval between fetches of each feed")
+	fs.IntVar(&config.poller.workers, "poll-workers", config.poller.workers, "Number of feeds fetched concurrently")
+	fs.DurationVar(&config.poller.jitter, "poll-jitter", config.poller.jitter, "Maximum random delay added to each feed's next fetch")
+
+	fs.BoolVar(&config.limiter.enabled, "limiter-enabled", config.limiter.enabled, "Enable rate limiting")
+	fs.Float64Var(&config.limiter.ipRPS, "limiter-ip-rps", config.limiter.ipRPS, "Rate limiter maximum requests per second per client IP")
+	fs.IntVar(&config.limiter.ipBurst, "limiter-ip-burst", config.limiter.ipBurst, "Rate limiter maximum burst per client IP")
+	fs.Float64Var(&config.limiter.userRPS, "limiter-user-rps", config.limiter.userRPS, "Rate limiter maximum requests per second per user")
+	fs.IntVar(&config.limiter.userBurst, "limiter-user-burst", config.limiter.userBurst, "Rate limiter maximum burst per user")
+
+	var trustedProxies string
+	fs.StringVar(&trustedProxies, "limiter-trusted-proxies", "", "Comma separated IPs or CIDR ranges of proxies trusted to set X-Forwarded-For")
+
+	fs.StringVar(&config.metrics.addr, "metrics-addr", config.metrics.addr, "Address serving /metrics (empty to disable)")
+
+	var trustedOrigins string
+	fs.StringVar(&trustedOrigins, "cors-trusted-origins", "", "Space separated origins allowed to make cross-origin requests")
 
 	fs.Parse(args)
 
+	config.cors.trustedOrigins = strings.Fields(trustedOrigins)
+
 	if config.env != "development" && config.env != "production" {
 		app.logger.Warn("invalid environment value, falling back to default", "provided", config.env, "default", "development")
 		config.env = "development"
 	}
 
+	if config.db.queryTimeout <= 0 {
+		app.logger.Warn("invalid database query timeout, falling back to default", "provided", config.db.queryTimeout, "default", defaultConfig().db.queryTimeout)
+		config.db.queryTimeout = defaultConfig().db.queryTimeout
+	}
+
+	if config.poller.interval <= 0 {
+		app.logger.Warn("invalid poll interval, falling back to default", "provided", config.poller.interval, "default", defaultConfig().poller.interval)
+		config.poller.interval = defaultConfig().poller.interval
+	}
+
+	if config.poller.workers < 1 {
+		app.logger.Warn("invalid poll worker count, falling back to default", "provided", config.poller.workers, "default", defaultConfig().poller.workers)
+		config.poller.workers = defaultConfig().poller.workers
+	}
+
+	if config.poller.jitter < 0 {
+		app.logger.Warn("invalid poll jitter, falling back to default", "provided", config.poller.jitter, "default", defaultConfig().poller.jitter)
+		config.poller.jitter = defaultConfig().poller.jitter
+	}
+
+	defaultLimiter := defaultConfig().limiter
+
+	if config.limiter.ipRPS <= 0 || config.limiter.ipBurst < 1 {
+		app.logger.Warn("invalid per-IP rate limit, falling back to default", "rps", config.limiter.ipRPS, "burst", config.limiter.ipBurst)
+		config.limiter.ipRPS = defaultLimiter.ipRPS
+		config.limiter.ipBurst = defaultLimiter.ipBurst
+	}
+
+	if config.limiter.userRPS <= 0 || config.limiter.userBurst < 1 {
+		app.logger.Warn("invalid per-user rate limit, falling back to default", "rps", config.limiter.userRPS, "burst", config.limiter.userBurst)
+		config.limiter.userRPS = defaultLimiter.userRPS
+		config.limiter.userBurst = defaultLimiter.userBurst
+	}
+
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			app.logger.Warn("ignoring invalid trusted proxy", "provided", proxy)
+			continue
+		}
+
+		config.limiter.trustedProxies = append(config.limiter.trustedProxies, prefix)
+	}
+
 	return config
 }
+
+// parsePrefix parses a CIDR range, or a single address as a range holding
+// only that address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"testing"
+	"time"
 )
 
 // TestLogHandler is a custom handler to capture log messages for testing
@@ -146,3 +148,255 @@ func TestParseConfigs_ValidDevelopmentEnv(t *testing.T) {
 		t.Error("should not log warning for valid 'development' env")
 	}
 }
+
+func TestParseConfigs_PollerDefaults(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{})
+
+	if config.poller.interval != 30*time.Minute {
+		t.Errorf("expected poll interval to be 30m, got %v", config.poller.interval)
+	}
+
+	if config.poller.workers != 4 {
+		t.Errorf("expected poll workers to be 4, got %d", config.poller.workers)
+	}
+
+	if config.poller.jitter != 5*time.Minute {
+		t.Errorf("expected poll jitter to be 5m, got %v", config.poller.jitter)
+	}
+}
+
+func TestParseConfigs_PollerFlags(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{"-poll-interval", "10m", "-poll-workers", "8", "-poll-jitter", "30s"})
+
+	if config.poller.interval != 10*time.Minute {
+		t.Errorf("expected poll interval to be 10m, got %v", config.poller.interval)
+	}
+
+	if config.poller.workers != 8 {
+		t.Errorf("expected poll workers to be 8, got %d", config.poller.workers)
+	}
+
+	if config.poller.jitter != 30*time.Second {
+		t.Errorf("expected poll jitter to be 30s, got %v", config.poller.jitter)
+	}
+
+	if handler.hasWarn() {
+		t.Error("should not log warning for valid poller flags")
+	}
+}
+
+func TestParseConfigs_InvalidPollerFlags(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{"-poll-interval", "0s", "-poll-workers", "0", "-poll-jitter", "-1m"})
+
+	if config.poller.interval != 30*time.Minute {
+		t.Errorf("expected poll interval to fall back to 30m, got %v", config.poller.interval)
+	}
+
+	if config.poller.workers != 4 {
+		t.Errorf("expected poll workers to fall back to 4, got %d", config.poller.workers)
+	}
+
+	if config.poller.jitter != 5*time.Minute {
+		t.Errorf("expected poll jitter to fall back to 5m, got %v", config.poller.jitter)
+	}
+
+	if !handler.hasWarn() {
+		t.Error("expected warning log for invalid poller flags")
+	}
+}
+
+func TestParseConfigs_LimiterDefaults(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{})
+
+	if !config.limiter.enabled {
+		t.Error("expected rate limiting to be enabled by default")
+	}
+
+	if config.limiter.ipRPS != 20 || config.limiter.ipBurst != 40 {
+		t.Errorf("expected per-IP limit to be 20/s burst 40, got %v/s burst %d", config.limiter.ipRPS, config.limiter.ipBurst)
+	}
+
+	if config.limiter.userRPS != 10 || config.limiter.userBurst != 20 {
+		t.Errorf("expected per-user limit to be 10/s burst 20, got %v/s burst %d", config.limiter.userRPS, config.limiter.userBurst)
+	}
+
+	if len(config.limiter.trustedProxies) != 0 {
+		t.Errorf("expected no trusted proxies, got %v", config.limiter.trustedProxies)
+	}
+}
+
+func TestParseConfigs_LimiterFlags(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{
+		"-limiter-enabled=false",
+		"-limiter-ip-rps", "5", "-limiter-ip-burst", "10",
+		"-limiter-user-rps", "2.5", "-limiter-user-burst", "4",
+		"-limiter-trusted-proxies", "10.0.0.0/8, 192.168.1.1,::1",
+	})
+
+	if config.limiter.enabled {
+		t.Error("expected rate limiting to be disabled")
+	}
+
+	if config.limiter.ipRPS != 5 || config.limiter.ipBurst != 10 {
+		t.Errorf("expected per-IP limit to be 5/s burst 10, got %v/s burst %d", config.limiter.ipRPS, config.limiter.ipBurst)
+	}
+
+	if config.limiter.userRPS != 2.5 || config.limiter.userBurst != 4 {
+		t.Errorf("expected per-user limit to be 2.5/s burst 4, got %v/s burst %d", config.limiter.userRPS, config.limiter.userBurst)
+	}
+
+	want := []string{"10.0.0.0/8", "192.168.1.1/32", "::1/128"}
+	if len(config.limiter.trustedProxies) != len(want) {
+		t.Fatalf("expected %d trusted proxies, got %v", len(want), config.limiter.trustedProxies)
+	}
+	for i, prefix := range config.limiter.trustedProxies {
+		if prefix.String() != want[i] {
+			t.Errorf("expected trusted proxy %d to be %s, got %s", i, want[i], prefix)
+		}
+	}
+
+	if handler.hasWarn() {
+		t.Error("should not log warning for valid limiter flags")
+	}
+}
+
+func TestParseConfigs_InvalidLimiterFlags(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{
+		"-limiter-ip-rps", "0",
+		"-limiter-user-burst", "0",
+		"-limiter-trusted-proxies", "not-an-ip,10.0.0.1",
+	})
+
+	if config.limiter.ipRPS != 20 || config.limiter.ipBurst != 40 {
+		t.Errorf("expected per-IP limit to fall back to 20/s burst 40, got %v/s burst %d", config.limiter.ipRPS, config.limiter.ipBurst)
+	}
+
+	if config.limiter.userRPS != 10 || config.limiter.userBurst != 20 {
+		t.Errorf("expected per-user limit to fall back to 10/s burst 20, got %v/s burst %d", config.limiter.userRPS, config.limiter.userBurst)
+	}
+
+	if len(config.limiter.trustedProxies) != 1 || config.limiter.trustedProxies[0].String() != "10.0.0.1/32" {
+		t.Errorf("expected only the valid trusted proxy to be kept, got %v", config.limiter.trustedProxies)
+	}
+
+	if !handler.hasWarn() {
+		t.Error("expected warning log for invalid limiter flags")
+	}
+}
+
+func TestParseConfigs_CORSTrustedOrigins(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{})
+
+	if len(config.cors.trustedOrigins) != 0 {
+		t.Errorf("expected no trusted origins by default, got %v", config.cors.trustedOrigins)
+	}
+
+	config = app.ParseConfigs([]string{"-cors-trusted-origins", "https://app.example.com  http://localhost:3000"})
+
+	want := []string{"https://app.example.com", "http://localhost:3000"}
+	if !slices.Equal(config.cors.trustedOrigins, want) {
+		t.Errorf("expected trusted origins %v, got %v", want, config.cors.trustedOrigins)
+	}
+}
+
+func TestParseConfigs_MetricsAddr(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{})
+
+	if config.metrics.addr != "localhost:8081" {
+		t.Errorf("expected metrics addr to be localhost:8081, got %q", config.metrics.addr)
+	}
+
+	config = app.ParseConfigs([]string{"-metrics-addr", ":9100"})
+
+	if config.metrics.addr != ":9100" {
+		t.Errorf("expected metrics addr to be :9100, got %q", config.metrics.addr)
+	}
+
+	config = app.ParseConfigs([]string{"-metrics-addr", ""})
+
+	if config.metrics.addr != "" {
+		t.Errorf("expected metrics addr to be empty, got %q", config.metrics.addr)
+	}
+}
+
+func TestParseConfigs_QueryTimeout(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{})
+
+	if config.db.queryTimeout != 3*time.Second {
+		t.Errorf("expected query timeout to be 3s, got %v", config.db.queryTimeout)
+	}
+
+	config = app.ParseConfigs([]string{"-db-query-timeout", "500ms"})
+
+	if config.db.queryTimeout != 500*time.Millisecond {
+		t.Errorf("expected query timeout to be 500ms, got %v", config.db.queryTimeout)
+	}
+
+	if handler.hasWarn() {
+		t.Error("should not log warning for a valid query timeout")
+	}
+
+	config = app.ParseConfigs([]string{"-db-query-timeout", "0s"})
+
+	if config.db.queryTimeout != 3*time.Second {
+		t.Errorf("expected query timeout to fall back to 3s, got %v", config.db.queryTimeout)
+	}
+
+	if !handler.hasWarn() {
+		t.Error("expected warning log for invalid query timeout")
+	}
+}
+
+func TestParseConfigs_AutoMigrate(t *testing.T) {
+	handler := &TestLogHandler{}
+	logger := slog.New(handler)
+	app := NewApplication(logger)
+
+	config := app.ParseConfigs([]string{})
+
+	if config.db.autoMigrate {
+		t.Error("expected auto-migrate to be disabled by default")
+	}
+
+	config = app.ParseConfigs([]string{"-db-auto-migrate"})
+
+	if !config.db.autoMigrate {
+		t.Error("expected auto-migrate to be enabled")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/grodier/rss-app/migrations"
+)
+
+const migrateUsage = "usage: migrate [-db-dsn dsn] up|down|status|to version"
+
+type migrateConfig struct {
+	db      dbConfig
+	command string
+	version int64
+}
+
+func (app *Application) parseMigrateArgs(args []string) (migrateConfig, error) {
+	config := migrateConfig{db: defaultConfig().db}
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.StringVar(&config.db.dsn, "db-dsn", config.db.dsn, "Database DSN")
+
+	if err := fs.Parse(args); err != nil {
+		return config, err
+	}
+
+	config.command = fs.Arg(0)
+
+	switch {
+	case fs.NArg() == 1 && (config.command == "up" || config.command == "down" || config.command == "status"):
+	case fs.NArg() == 2 && config.command == "to":
+		version, err := strconv.ParseInt(fs.Arg(1), 10, 64)
+		if err != nil || version < 0 {
+			return config, fmt.Errorf("invalid version %q", fs.Arg(1))
+		}
+		config.version = version
+	default:
+		return config, errors.New(migrateUsage)
+	}
+
+	return config, nil
+}
+
+// Migrate implements the migrate subcommand, which applies the embedded
+// migrations or reports their status.
+func (app *Application) Migrate(ctx context.Context, args []string, out io.Writer) error {
+	config, err := app.parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	st, err := app.openStore(config.db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	if config.command == "status" {
+		statuses, err := st.migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(out, statuses)
+		return nil
+	}
+
+	var (
+		ran  []*migrations.Migration
+		verb string
+	)
+
+	switch config.command {
+	case "up":
+		verb = "applied"
+		ran, err = st.migrator.Up(ctx)
+	case "down":
+		verb = "rolled back"
+		var migration *migrations.Migration
+		migration, err = st.migrator.Down(ctx)
+		if migration != nil {
+			ran = append(ran, migration)
+		}
+	case "to":
+		verb = "ran"
+		ran, err = st.migrator.To(ctx, config.version)
+	}
+
+	// Migrations that succeeded before a failure are still reported, since
+	// they stay applied.
+	for _, migration := range ran {
+		fmt.Fprintf(out, "%s %s\n", verb, migration.Name)
+	}
+
+	if err == nil && len(ran) == 0 {
+		fmt.Fprintln(out, "no migrations to run")
+	}
+
+	return err
+}
+
+// migrate brings the schema up to date, for -db-auto-migrate.
+func (app *Application) migrate(ctx context.Context, m migrator) error {
+	ran, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("database schema up to date", "applied", len(ran))
+
+	return nil
+}
+
+func printMigrationStatus(out io.Writer, statuses []*migrations.Status) {
+	fmt.Fprintf(out, "%-24s %s\n", "Applied At", "Migration")
+
+	for _, status := range statuses {
+		appliedAt := "Pending"
+		if status.Applied() {
+			appliedAt = status.AppliedAt.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(out, "%-24s %s\n", appliedAt, status.Name)
+	}
+}
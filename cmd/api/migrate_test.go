@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/migrations"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	tests := []struct {
+		args        []string
+		wantCommand string
+		wantVersion int64
+	}{
+		{[]string{"up"}, "up", 0},
+		{[]string{"down"}, "down", 0},
+		{[]string{"-db-dsn", "postgres://test", "status"}, "status", 0},
+		{[]string{"to", "7"}, "to", 7},
+		{[]string{"to", "0"}, "to", 0},
+	}
+
+	for _, tt := range tests {
+		config, err := app.parseMigrateArgs(tt.args)
+		if err != nil {
+			t.Errorf("args %q: unexpected error: %v", tt.args, err)
+			continue
+		}
+
+		if config.command != tt.wantCommand {
+			t.Errorf("args %q: expected command %q, got %q", tt.args, tt.wantCommand, config.command)
+		}
+		if config.version != tt.wantVersion {
+			t.Errorf("args %q: expected version %d, got %d", tt.args, tt.wantVersion, config.version)
+		}
+	}
+
+	config, _ := app.parseMigrateArgs([]string{"-db-dsn", "postgres://test", "up"})
+	if config.db.dsn != "postgres://test" {
+		t.Errorf("expected dsn to be 'postgres://test', got '%s'", config.db.dsn)
+	}
+}
+
+func TestParseMigrateArgs_Invalid(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	for _, args := range [][]string{{}, {"sideways"}, {"up", "3"}, {"to"}, {"to", "three"}, {"to", "-1"}} {
+		if _, err := app.parseMigrateArgs(args); err == nil {
+			t.Errorf("expected error for args %q", args)
+		}
+	}
+}
+
+func TestPrintMigrationStatus(t *testing.T) {
+	var out bytes.Buffer
+
+	printMigrationStatus(&out, []*migrations.Status{
+		{Migration: &migrations.Migration{Version: 1, Name: "00001_create_feeds_table.sql"}, AppliedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		{Migration: &migrations.Migration{Version: 2, Name: "00002_add_version_to_feeds.sql"}},
+	})
+
+	want := "Applied At               Migration\n" +
+		"2024-05-01 12:30:00      00001_create_feeds_table.sql\n" +
+		"Pending                  00002_add_version_to_feeds.sql\n"
+
+	if out.String() != want {
+		t.Errorf("got output\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/opml"
+)
+
+type importOPMLConfig struct {
+	db   dbConfig
+	user string
+	path string
+}
+
+func (app *Application) parseImportOPMLArgs(args []string) (importOPMLConfig, error) {
+	config := importOPMLConfig{db: defaultConfig().db}
+
+	fs := flag.NewFlagSet("import-opml", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.StringVar(&config.db.dsn, "db-dsn", config.db.dsn, "Database DSN")
+	fs.StringVar(&config.user, "user", "", "Email of the user to subscribe; only the feed catalog is updated when empty")
+
+	if err := fs.Parse(args); err != nil {
+		return config, err
+	}
+
+	if fs.NArg() != 1 {
+		return config, errors.New("usage: import-opml [-db-dsn dsn] [-user email] file.opml")
+	}
+	config.path = fs.Arg(0)
+
+	return config, nil
+}
+
+// ImportOPML implements the import-opml subcommand, which imports an OPML
+// file the same way as POST /v1/opml and prints the result of each outline.
+func (app *Application) ImportOPML(ctx context.Context, args []string, out io.Writer) error {
+	config, err := app.parseImportOPMLArgs(args)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(config.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	doc, err := opml.Parse(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", config.path, err)
+	}
+
+	st, err := app.openStore(config.db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var userID int64
+
+	if config.user != "" {
+		user, err := st.users.GetByEmail(config.user)
+		if err != nil {
+			if err == models.ErrRecordNotFound {
+				return fmt.Errorf("no user with email %q", config.user)
+			}
+			return err
+		}
+		userID = user.ID
+	}
+
+	feedFetcher := fetcher.NewFetcher()
+	feedFetcher.UserAgent = "rss-app/" + version
+
+	importer := &opml.Importer{
+		FeedService: st.feeds,
+		FeedFetcher: feedFetcher,
+		Transactor:  st.transactor,
+	}
+
+	results, err := importer.Import(ctx, doc, userID)
+	if err != nil {
+		return err
+	}
+
+	printImportResults(out, results)
+
+	return nil
+}
+
+func printImportResults(out io.Writer, results []*opml.Result) {
+	counts := make(map[opml.Status]int)
+
+	for _, result := range results {
+		counts[result.Status]++
+		fmt.Fprintf(out, "%-8s %s\n", result.Status, result.URL)
+
+		fields := make([]string, 0, len(result.Errors))
+		for field := range result.Errors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			fmt.Fprintf(out, "         %s %s\n", field, result.Errors[field])
+		}
+	}
+
+	fmt.Fprintf(out, "%d created, %d already existed, %d invalid\n",
+		counts[opml.StatusCreated], counts[opml.StatusExists], counts[opml.StatusInvalid])
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/opml"
+)
+
+func TestParseImportOPMLArgs(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	config, err := app.parseImportOPMLArgs([]string{"-db-dsn", "postgres://test", "-user", "alice@example.com", "feeds.opml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.db.dsn != "postgres://test" {
+		t.Errorf("expected dsn to be 'postgres://test', got '%s'", config.db.dsn)
+	}
+	if config.user != "alice@example.com" {
+		t.Errorf("expected user to be 'alice@example.com', got '%s'", config.user)
+	}
+	if config.path != "feeds.opml" {
+		t.Errorf("expected path to be 'feeds.opml', got '%s'", config.path)
+	}
+}
+
+func TestParseImportOPMLArgs_MissingFile(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	for _, args := range [][]string{{}, {"-user", "alice@example.com"}, {"a.opml", "b.opml"}} {
+		if _, err := app.parseImportOPMLArgs(args); err == nil {
+			t.Errorf("expected error for args %q", args)
+		}
+	}
+}
+
+func TestPrintImportResults(t *testing.T) {
+	var out bytes.Buffer
+
+	printImportResults(&out, []*opml.Result{
+		{URL: "https://one.example.com/feed", Status: opml.StatusCreated},
+		{URL: "https://two.example.com/feed", Status: opml.StatusExists},
+		{URL: "/relative", Status: opml.StatusInvalid, Errors: map[string]string{"url": "must be an absolute http or https URL"}},
+	})
+
+	got := out.String()
+
+	for _, want := range []string{
+		"created  https://one.example.com/feed\n",
+		"url must be an absolute http or https URL\n",
+		"1 created, 1 already existed, 1 invalid\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/pgsql"
+	"github.com/grodier/rss-app/internal/sqlite"
+	"github.com/grodier/rss-app/migrations"
+)
+
+// sqliteDSNPrefix selects the SQLite backend, with the rest of the DSN naming
+// the database file. Any other DSN is handed to Postgres.
+const sqliteDSNPrefix = "sqlite://"
+
+// migrator is implemented by each backend's schema migrator.
+type migrator interface {
+	Up(ctx context.Context) ([]*migrations.Migration, error)
+	Down(ctx context.Context) (*migrations.Migration, error)
+	To(ctx context.Context, version int64) ([]*migrations.Migration, error)
+	Status(ctx context.Context) ([]*migrations.Status, error)
+}
+
+// Verify both backends' migrators implement migrator at compile time.
+var (
+	_ migrator = (*pgsql.Migrator)(nil)
+	_ migrator = (*sqlite.Migrator)(nil)
+)
+
+// store is an open database and the services backed by it, for whichever
+// backend the DSN selects.
+type store struct {
+	db interface {
+		Close() error
+		RegisterMetrics(r *metrics.Registry)
+	}
+	migrator migrator
+
+	feeds         models.FeedService
+	items         models.ItemService
+	users         models.UserService
+	tokens        models.TokenService
+	permissions   models.PermissionService
+	subscriptions models.SubscriptionService
+	itemStates    models.ItemStateService
+	folders       models.FolderService
+	transactor    models.Transactor
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
+
+func (app *Application) openStore(cfg dbConfig) (*store, error) {
+	var (
+		s   *store
+		err error
+	)
+
+	if path, ok := strings.CutPrefix(cfg.dsn, sqliteDSNPrefix); ok {
+		s, err = openSQLite(path, cfg)
+	} else {
+		s, err = openPostgres(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.Info("database connection pool established")
+
+	return s, nil
+}
+
+func openPostgres(cfg dbConfig) (*store, error) {
+	ms, err := migrations.Parse(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	db := pgsql.NewDB(cfg.dsn)
+	db.MaxOpenConnections = cfg.maxOpenConnections
+	db.MaxIdleConnections = cfg.maxIdleConnections
+	db.MaxIdleTime = cfg.maxIdleTime
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+
+	feedService := pgsql.NewFeedService(db)
+	feedService.QueryTimeout = cfg.queryTimeout
+
+	transactor := pgsql.NewTransactor(db)
+	transactor.QueryTimeout = cfg.queryTimeout
+
+	return &store{
+		db:            db,
+		migrator:      pgsql.NewMigrator(db, ms),
+		feeds:         feedService,
+		items:         pgsql.NewItemService(db),
+		users:         pgsql.NewUserService(db),
+		tokens:        pgsql.NewTokenService(db),
+		permissions:   pgsql.NewPermissionService(db),
+		subscriptions: pgsql.NewSubscriptionService(db),
+		itemStates:    pgsql.NewItemStateService(db),
+		folders:       pgsql.NewFolderService(db),
+		transactor:    transactor,
+	}, nil
+}
+
+func openSQLite(path string, cfg dbConfig) (*store, error) {
+	if path == "" {
+		return nil, errors.New("sqlite DSN must name the database file, e.g. sqlite://rss.db")
+	}
+
+	ms, err := migrations.Parse(migrations.SQLiteFS)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sqlite.NewDB(path)
+	db.MaxOpenConnections = cfg.maxOpenConnections
+	db.MaxIdleConnections = cfg.maxIdleConnections
+	db.MaxIdleTime = cfg.maxIdleTime
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+
+	feedService := sqlite.NewFeedService(db)
+	feedService.QueryTimeout = cfg.queryTimeout
+
+	transactor := sqlite.NewTransactor(db)
+	transactor.QueryTimeout = cfg.queryTimeout
+
+	return &store{
+		db:            db,
+		migrator:      sqlite.NewMigrator(db, ms),
+		feeds:         feedService,
+		items:         sqlite.NewItemService(db),
+		users:         sqlite.NewUserService(db),
+		tokens:        sqlite.NewTokenService(db),
+		permissions:   sqlite.NewPermissionService(db),
+		subscriptions: sqlite.NewSubscriptionService(db),
+		itemStates:    sqlite.NewItemStateService(db),
+		folders:       sqlite.NewFolderService(db),
+		transactor:    transactor,
+	}, nil
+}
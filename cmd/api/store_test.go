@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/sqlite"
+)
+
+func TestOpenStore_SQLite(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	cfg := defaultConfig().db
+	cfg.dsn = "sqlite://" + filepath.Join(t.TempDir(), "rss.db")
+	cfg.queryTimeout = 7 * time.Second
+
+	st, err := app.openStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer st.Close()
+
+	feeds, ok := st.feeds.(*sqlite.FeedService)
+	if !ok {
+		t.Fatalf("got feed service %T, want *sqlite.FeedService", st.feeds)
+	}
+	if feeds.QueryTimeout != cfg.queryTimeout {
+		t.Errorf("got query timeout %v, want %v", feeds.QueryTimeout, cfg.queryTimeout)
+	}
+
+	if err := app.migrate(context.Background(), st.migrator); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := st.users.GetByEmail("alice@example.com"); err != sqlite.ErrRecordNotFound {
+		t.Errorf("got error %v, want the migrated database to be queryable", err)
+	}
+}
+
+func TestOpenStore_SQLiteMissingPath(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	cfg := defaultConfig().db
+	cfg.dsn = "sqlite://"
+
+	if _, err := app.openStore(cfg); err == nil {
+		t.Error("got no error, want the missing path reported")
+	}
+}
+
+func TestOpenStore_Postgres(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+
+	// Anything that isn't a sqlite:// DSN is handed to Postgres, which
+	// rejects this one before connecting.
+	cfg := defaultConfig().db
+	cfg.dsn = "postgres://localhost:-1/rss"
+
+	_, err := app.openStore(cfg)
+	if err == nil {
+		t.Fatal("got no error, want the invalid Postgres DSN rejected")
+	}
+	if strings.Contains(err.Error(), "sqlite") {
+		t.Errorf("got error %v, want it from the Postgres driver", err)
+	}
+}
+
+func TestMigrate_SQLite(t *testing.T) {
+	app := NewApplication(slog.New(&TestLogHandler{}))
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "rss.db")
+
+	var out bytes.Buffer
+	if err := app.Migrate(context.Background(), []string{"-db-dsn", dsn, "up"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "applied 00001_create_feeds_table.sql") {
+		t.Errorf("got output %q, want the SQLite migrations applied", out.String())
+	}
+}
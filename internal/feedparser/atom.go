@@ -0,0 +1,130 @@
+package feedparser
+
+import (
+	"strings"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type atomFeed struct {
+	Lang     string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title    atomText     `xml:"http://www.w3.org/2005/Atom title"`
+	Subtitle atomText     `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Links    []atomLink   `xml:"http://www.w3.org/2005/Atom link"`
+	Authors  []atomPerson `xml:"http://www.w3.org/2005/Atom author"`
+	Entries  []atomEntry  `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"http://www.w3.org/2005/Atom id"`
+	Title     atomText     `xml:"http://www.w3.org/2005/Atom title"`
+	Links     []atomLink   `xml:"http://www.w3.org/2005/Atom link"`
+	Published string       `xml:"http://www.w3.org/2005/Atom published"`
+	Updated   string       `xml:"http://www.w3.org/2005/Atom updated"`
+	Summary   atomText     `xml:"http://www.w3.org/2005/Atom summary"`
+	Content   atomText     `xml:"http://www.w3.org/2005/Atom content"`
+	Authors   []atomPerson `xml:"http://www.w3.org/2005/Atom author"`
+	DCCreator string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	mediaElements
+}
+
+// atomText is an Atom text construct. XHTML content is kept as markup, while
+// text and HTML content are returned unescaped.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) value() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"http://www.w3.org/2005/Atom name"`
+	Email string `xml:"http://www.w3.org/2005/Atom email"`
+}
+
+func parseAtom(data []byte) (*Result, error) {
+	var doc atomFeed
+	if err := newDecoder(data).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Feed: &models.Feed{
+			Title:       doc.Title.value(),
+			Description: doc.Subtitle.value(),
+			SiteURL:     alternateLink(doc.Links),
+			Language:    strings.TrimSpace(doc.Lang),
+		},
+	}
+
+	for _, e := range doc.Entries {
+		entry := &Entry{
+			GUID:      strings.TrimSpace(e.ID),
+			Title:     firstNonEmpty(e.Title.value(), e.title()),
+			Link:      alternateLink(e.Links),
+			Author:    firstNonEmpty(personName(e.Authors), e.DCCreator, personName(doc.Authors)),
+			Content:   e.Content.value(),
+			Summary:   firstNonEmpty(e.Summary.value(), e.description()),
+			Published: parseTime(firstNonEmpty(e.Published, e.Updated)),
+			Updated:   parseTime(e.Updated),
+			ImageURL:  e.thumbnail(),
+		}
+
+		for _, link := range e.Links {
+			if link.Rel == "enclosure" && link.Href != "" {
+				entry.Enclosures = appendEnclosures(entry.Enclosures, Enclosure{
+					URL:    link.Href,
+					Type:   link.Type,
+					Length: link.Length,
+				})
+			}
+		}
+		entry.Enclosures = appendEnclosures(entry.Enclosures, e.enclosures()...)
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// alternateLink returns the link pointing at the HTML representation of a
+// feed or entry, preferring an explicit text/html type when several exist.
+func alternateLink(links []atomLink) string {
+	var fallback string
+
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return strings.TrimSpace(link.Href)
+		}
+		if fallback == "" {
+			fallback = strings.TrimSpace(link.Href)
+		}
+	}
+
+	return fallback
+}
+
+func personName(people []atomPerson) string {
+	for _, person := range people {
+		if name := firstNonEmpty(person.Name, person.Email); name != "" {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,278 @@
+package feedparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+var ErrUnknownFormat = errors.New("unrecognized feed format")
+
+// Result is a feed document normalized into a format independent shape.
+// Feed.URL is left empty, since only the caller knows where the document was
+// fetched from.
+type Result struct {
+	Feed    *models.Feed
+	Entries []*Entry
+}
+
+type Entry struct {
+	GUID       string
+	Title      string
+	Link       string
+	Author     string
+	Content    string
+	Summary    string
+	Published  time.Time
+	Updated    time.Time
+	ImageURL   string
+	Enclosures []Enclosure
+}
+
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 (RDF), Atom 1.0 or JSON Feed document and
+// normalizes it into a Result.
+func Parse(r io.Reader) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if len(trimmed) == 0 {
+		return nil, ErrUnknownFormat
+	}
+
+	var result *Result
+
+	if trimmed[0] == '{' {
+		result, err = parseJSONFeed(trimmed)
+	} else {
+		result, err = parseXML(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range result.Entries {
+		entry.Link = resolveURL(result.Feed.SiteURL, entry.Link)
+		if entry.GUID == "" {
+			entry.GUID = entry.Link
+		}
+		if entry.Content == "" {
+			entry.Content = entry.Summary
+		}
+		if entry.Updated.IsZero() {
+			entry.Updated = entry.Published
+		}
+	}
+
+	return result, nil
+}
+
+func parseXML(data []byte) (*Result, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case root.Local == "rss", root.Local == "RDF" && root.Space == nsRDF:
+		return parseRSS(data)
+	case root.Local == "feed" && root.Space == nsAtom:
+		return parseAtom(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+const (
+	nsAtom    = "http://www.w3.org/2005/Atom"
+	nsContent = "http://purl.org/rss/1.0/modules/content/"
+	nsDC      = "http://purl.org/dc/elements/1.1/"
+	nsMedia   = "http://search.yahoo.com/mrss/"
+	nsRDF     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsITunes  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+)
+
+func newDecoder(data []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charsetReader
+	return d
+}
+
+func rootElement(data []byte) (xml.Name, error) {
+	d := newDecoder(data)
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return xml.Name{}, ErrUnknownFormat
+			}
+			return xml.Name{}, err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "windows-1252", "cp1252":
+		return &latin1Reader{r: input}, nil
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+}
+
+// latin1Reader transcodes ISO-8859-1 input to UTF-8. windows-1252 is treated
+// the same way, which only differs for a handful of punctuation characters.
+type latin1Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.buf) == 0 {
+		raw := make([]byte, (len(p)+1)/2)
+		n, err := l.r.Read(raw)
+		for _, b := range raw[:n] {
+			l.buf = append(l.buf, string(rune(b))...)
+		}
+		if len(l.buf) == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+// element captures the name of a matched element so callers can pick between
+// elements that share a local name across namespaces, e.g. <link> and
+// <atom:link> inside an RSS channel.
+type element struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+}
+
+type elements []element
+
+var extensionNamespaces = map[string]bool{
+	nsAtom:    true,
+	nsContent: true,
+	nsDC:      true,
+	nsMedia:   true,
+	nsRDF:     true,
+	nsITunes:  true,
+}
+
+// native returns the first non-empty element that does not belong to one of
+// the known extension namespaces.
+func (es elements) native() string {
+	for _, e := range es {
+		if extensionNamespaces[e.XMLName.Space] {
+			continue
+		}
+		if text := strings.TrimSpace(e.Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// in returns the first non-empty element from the given namespace.
+func (es elements) in(space string) string {
+	for _, e := range es {
+		if e.XMLName.Space != space {
+			continue
+		}
+		if text := strings.TrimSpace(e.Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC850,
+	time.ANSIC,
+}
+
+// parseTime parses the date formats commonly found in feeds. Unparseable
+// dates yield the zero time rather than failing the whole document.
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+
+	return time.Time{}
+}
+
+func resolveURL(base, ref string) string {
+	if ref == "" || base == "" {
+		return ref
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil || refURL.IsAbs() {
+		return ref
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
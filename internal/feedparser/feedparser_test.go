@@ -0,0 +1,377 @@
+package feedparser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rss2Document = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:media="http://search.yahoo.com/mrss/">
+	<channel>
+		<title>Test Blog</title>
+		<link>https://example.com/</link>
+		<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+		<description>A blog used for testing</description>
+		<language>en-us</language>
+		<item>
+			<title>First Post</title>
+			<link>/posts/first</link>
+			<guid isPermaLink="false">post-1</guid>
+			<dc:creator>Jane Doe</dc:creator>
+			<pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate>
+			<description>Short summary</description>
+			<content:encoded><![CDATA[<p>Full <b>content</b></p>]]></content:encoded>
+			<enclosure url="https://example.com/episode.mp3" type="audio/mpeg" length="1234"/>
+			<media:content url="https://example.com/episode.mp3" type="audio/mpeg"/>
+			<media:thumbnail url="https://example.com/thumb.jpg"/>
+		</item>
+		<item>
+			<title>Second Post</title>
+			<link>https://example.com/posts/second</link>
+			<author>jane@example.com (Jane Doe)</author>
+			<pubDate>Tue, 3 Jan 2006 10:00:00 GMT</pubDate>
+			<description>Only a description</description>
+		</item>
+	</channel>
+</rss>`
+
+const rdfDocument = `<?xml version="1.0"?>
+<rdf:RDF
+	xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="https://example.org/">
+		<title>RDF Site</title>
+		<link>https://example.org/</link>
+		<description>An RSS 1.0 feed</description>
+		<dc:language>fr</dc:language>
+	</channel>
+	<item rdf:about="https://example.org/articles/1">
+		<title>RDF Article</title>
+		<link>https://example.org/articles/1</link>
+		<dc:creator>Jean</dc:creator>
+		<dc:date>2024-05-01T08:30:00+02:00</dc:date>
+	</item>
+</rdf:RDF>`
+
+const atomDocument = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/" xml:lang="de">
+	<title>Atom Site</title>
+	<subtitle type="html">Atom &lt;em&gt;subtitle&lt;/em&gt;</subtitle>
+	<link rel="self" href="https://example.net/atom.xml"/>
+	<link rel="alternate" type="text/html" href="https://example.net/"/>
+	<author><name>Feed Author</name></author>
+	<entry>
+		<id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+		<title type="text">Atom Entry</title>
+		<link href="https://example.net/entries/1"/>
+		<link rel="enclosure" href="https://example.net/video.mp4" type="video/mp4" length="99"/>
+		<published>2024-01-02T03:04:05Z</published>
+		<updated>2024-01-03T03:04:05Z</updated>
+		<summary>Entry summary</summary>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Entry content</p></div></content>
+		<media:group>
+			<media:title>Group Title</media:title>
+			<media:thumbnail url="https://example.net/thumb.png"/>
+		</media:group>
+	</entry>
+	<entry>
+		<id>entry-2</id>
+		<title>Second Entry</title>
+		<updated>2024-02-01T00:00:00Z</updated>
+		<author><name>Entry Author</name></author>
+		<content type="html">&lt;p&gt;HTML content&lt;/p&gt;</content>
+	</entry>
+</feed>`
+
+const jsonFeedDocument = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "JSON Site",
+	"home_page_url": "https://example.io/",
+	"feed_url": "https://example.io/feed.json",
+	"description": "A JSON feed",
+	"language": "es",
+	"authors": [{"name": "Feed Author"}],
+	"items": [
+		{
+			"id": "1",
+			"url": "https://example.io/1",
+			"title": "JSON Item",
+			"content_html": "<p>JSON content</p>",
+			"summary": "JSON summary",
+			"image": "https://example.io/image.png",
+			"date_published": "2024-03-04T05:06:07Z",
+			"attachments": [{"url": "https://example.io/a.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 42}]
+		},
+		{
+			"id": 2,
+			"url": "https://example.io/2",
+			"content_text": "Plain text",
+			"author": {"name": "Old Style Author"}
+		}
+	]
+}`
+
+func TestParse_RSS2(t *testing.T) {
+	result, err := Parse(strings.NewReader(rss2Document))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feed := result.Feed
+	if feed.Title != "Test Blog" {
+		t.Errorf("got Title %q, want %q", feed.Title, "Test Blog")
+	}
+	if feed.Description != "A blog used for testing" {
+		t.Errorf("got Description %q, want %q", feed.Description, "A blog used for testing")
+	}
+	if feed.SiteURL != "https://example.com/" {
+		t.Errorf("got SiteURL %q, want %q", feed.SiteURL, "https://example.com/")
+	}
+	if feed.Language != "en-us" {
+		t.Errorf("got Language %q, want %q", feed.Language, "en-us")
+	}
+
+	if len(result.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(result.Entries))
+	}
+
+	first := result.Entries[0]
+	if first.GUID != "post-1" {
+		t.Errorf("got GUID %q, want %q", first.GUID, "post-1")
+	}
+	if first.Link != "https://example.com/posts/first" {
+		t.Errorf("got Link %q, want %q", first.Link, "https://example.com/posts/first")
+	}
+	if first.Author != "Jane Doe" {
+		t.Errorf("got Author %q, want %q", first.Author, "Jane Doe")
+	}
+	if first.Content != "<p>Full <b>content</b></p>" {
+		t.Errorf("got Content %q, want %q", first.Content, "<p>Full <b>content</b></p>")
+	}
+	if first.Summary != "Short summary" {
+		t.Errorf("got Summary %q, want %q", first.Summary, "Short summary")
+	}
+	wantPublished := time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)
+	if !first.Published.Equal(wantPublished) {
+		t.Errorf("got Published %v, want %v", first.Published, wantPublished)
+	}
+	if first.ImageURL != "https://example.com/thumb.jpg" {
+		t.Errorf("got ImageURL %q, want %q", first.ImageURL, "https://example.com/thumb.jpg")
+	}
+	if len(first.Enclosures) != 1 {
+		t.Fatalf("got %d enclosures, want 1", len(first.Enclosures))
+	}
+	if first.Enclosures[0].Length != 1234 {
+		t.Errorf("got enclosure Length %d, want 1234", first.Enclosures[0].Length)
+	}
+
+	second := result.Entries[1]
+	if second.GUID != "https://example.com/posts/second" {
+		t.Errorf("got GUID %q, want link fallback", second.GUID)
+	}
+	if second.Author != "Jane Doe" {
+		t.Errorf("got Author %q, want %q", second.Author, "Jane Doe")
+	}
+	if second.Content != "Only a description" {
+		t.Errorf("got Content %q, want description fallback", second.Content)
+	}
+}
+
+func TestParse_RDF(t *testing.T) {
+	result, err := Parse(strings.NewReader(rdfDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Feed.Title != "RDF Site" {
+		t.Errorf("got Title %q, want %q", result.Feed.Title, "RDF Site")
+	}
+	if result.Feed.Language != "fr" {
+		t.Errorf("got Language %q, want %q", result.Feed.Language, "fr")
+	}
+
+	if len(result.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(result.Entries))
+	}
+
+	entry := result.Entries[0]
+	if entry.GUID != "https://example.org/articles/1" {
+		t.Errorf("got GUID %q, want rdf:about", entry.GUID)
+	}
+	if entry.Author != "Jean" {
+		t.Errorf("got Author %q, want %q", entry.Author, "Jean")
+	}
+	wantPublished := time.Date(2024, 5, 1, 6, 30, 0, 0, time.UTC)
+	if !entry.Published.Equal(wantPublished) {
+		t.Errorf("got Published %v, want %v", entry.Published, wantPublished)
+	}
+}
+
+func TestParse_Atom(t *testing.T) {
+	result, err := Parse(strings.NewReader(atomDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feed := result.Feed
+	if feed.Title != "Atom Site" {
+		t.Errorf("got Title %q, want %q", feed.Title, "Atom Site")
+	}
+	if feed.Description != "Atom <em>subtitle</em>" {
+		t.Errorf("got Description %q, want %q", feed.Description, "Atom <em>subtitle</em>")
+	}
+	if feed.SiteURL != "https://example.net/" {
+		t.Errorf("got SiteURL %q, want %q", feed.SiteURL, "https://example.net/")
+	}
+	if feed.Language != "de" {
+		t.Errorf("got Language %q, want %q", feed.Language, "de")
+	}
+
+	if len(result.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(result.Entries))
+	}
+
+	first := result.Entries[0]
+	if first.GUID != "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a" {
+		t.Errorf("got GUID %q", first.GUID)
+	}
+	if first.Link != "https://example.net/entries/1" {
+		t.Errorf("got Link %q, want %q", first.Link, "https://example.net/entries/1")
+	}
+	if first.Author != "Feed Author" {
+		t.Errorf("got Author %q, want feed level author", first.Author)
+	}
+	if !strings.Contains(first.Content, "<p>Entry content</p>") {
+		t.Errorf("got Content %q, want xhtml markup", first.Content)
+	}
+	if first.ImageURL != "https://example.net/thumb.png" {
+		t.Errorf("got ImageURL %q, want %q", first.ImageURL, "https://example.net/thumb.png")
+	}
+	if len(first.Enclosures) != 1 || first.Enclosures[0].Type != "video/mp4" {
+		t.Errorf("got Enclosures %+v, want one video/mp4 enclosure", first.Enclosures)
+	}
+	if !first.Updated.Equal(time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("got Updated %v", first.Updated)
+	}
+
+	second := result.Entries[1]
+	if second.Author != "Entry Author" {
+		t.Errorf("got Author %q, want %q", second.Author, "Entry Author")
+	}
+	if second.Content != "<p>HTML content</p>" {
+		t.Errorf("got Content %q, want %q", second.Content, "<p>HTML content</p>")
+	}
+	if !second.Published.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got Published %v, want updated fallback", second.Published)
+	}
+}
+
+func TestParse_JSONFeed(t *testing.T) {
+	result, err := Parse(strings.NewReader(jsonFeedDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feed := result.Feed
+	if feed.Title != "JSON Site" {
+		t.Errorf("got Title %q, want %q", feed.Title, "JSON Site")
+	}
+	if feed.Description != "A JSON feed" {
+		t.Errorf("got Description %q, want %q", feed.Description, "A JSON feed")
+	}
+	if feed.SiteURL != "https://example.io/" {
+		t.Errorf("got SiteURL %q, want %q", feed.SiteURL, "https://example.io/")
+	}
+	if feed.Language != "es" {
+		t.Errorf("got Language %q, want %q", feed.Language, "es")
+	}
+
+	if len(result.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(result.Entries))
+	}
+
+	first := result.Entries[0]
+	if first.Content != "<p>JSON content</p>" {
+		t.Errorf("got Content %q, want %q", first.Content, "<p>JSON content</p>")
+	}
+	if first.Author != "Feed Author" {
+		t.Errorf("got Author %q, want %q", first.Author, "Feed Author")
+	}
+	if len(first.Enclosures) != 1 || first.Enclosures[0].Length != 42 {
+		t.Errorf("got Enclosures %+v, want one attachment", first.Enclosures)
+	}
+
+	second := result.Entries[1]
+	if second.GUID != "2" {
+		t.Errorf("got GUID %q, want %q", second.GUID, "2")
+	}
+	if second.Author != "Old Style Author" {
+		t.Errorf("got Author %q, want %q", second.Author, "Old Style Author")
+	}
+	if second.Content != "Plain text" {
+		t.Errorf("got Content %q, want %q", second.Content, "Plain text")
+	}
+}
+
+func TestParse_Latin1(t *testing.T) {
+	doc := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss version=\"2.0\"><channel><title>Caf\xe9</title></channel></rss>"
+
+	result, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Feed.Title != "Café" {
+		t.Errorf("got Title %q, want %q", result.Feed.Title, "Café")
+	}
+}
+
+func TestParse_UnknownFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"html", "<html><body>Not a feed</body></html>"},
+		{"json without version", `{"title": "Not a feed"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.body))
+			if !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("got error %v, want %v", err, ErrUnknownFormat)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 2 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05.123+01:00", time.Date(2006, 1, 2, 14, 4, 5, 123000000, time.UTC)},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"not a date", time.Time{}},
+		{"", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseTime(tt.value); !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
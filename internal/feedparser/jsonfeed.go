@@ -0,0 +1,135 @@
+package feedparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Author      *jsonAuthor    `json:"author"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            jsonID           `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	Image         string           `json:"image"`
+	BannerImage   string           `json:"banner_image"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonAuthor     `json:"authors"`
+	Author        *jsonAuthor      `json:"author"`
+	Attachments   []jsonAttachment `json:"attachments"`
+}
+
+// jsonAuthor covers both the 1.1 "authors" array and the deprecated 1.0
+// "author" object.
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type jsonAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+// jsonID accepts numeric ids, which the spec forbids but publishers emit.
+type jsonID string
+
+func (id *jsonID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jsonID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = jsonID(n.String())
+	return nil
+}
+
+func parseJSONFeed(data []byte) (*Result, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, ErrUnknownFormat
+	}
+
+	result := &Result{
+		Feed: &models.Feed{
+			Title:       strings.TrimSpace(doc.Title),
+			Description: strings.TrimSpace(doc.Description),
+			SiteURL:     strings.TrimSpace(doc.HomePageURL),
+			Language:    strings.TrimSpace(doc.Language),
+		},
+	}
+
+	feedAuthor := authorsName(doc.Authors, doc.Author)
+
+	for _, item := range doc.Items {
+		entry := &Entry{
+			GUID:      strings.TrimSpace(string(item.ID)),
+			Title:     strings.TrimSpace(item.Title),
+			Link:      firstNonEmpty(item.URL, item.ExternalURL),
+			Author:    firstNonEmpty(authorsName(item.Authors, item.Author), feedAuthor),
+			Content:   firstNonEmpty(item.ContentHTML, item.ContentText),
+			Summary:   strings.TrimSpace(item.Summary),
+			Published: parseTime(firstNonEmpty(item.DatePublished, item.DateModified)),
+			Updated:   parseTime(item.DateModified),
+			ImageURL:  firstNonEmpty(item.Image, item.BannerImage),
+		}
+
+		for _, attachment := range item.Attachments {
+			if attachment.URL == "" {
+				continue
+			}
+			entry.Enclosures = appendEnclosures(entry.Enclosures, Enclosure{
+				URL:    attachment.URL,
+				Type:   attachment.MimeType,
+				Length: attachment.SizeInBytes,
+			})
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+func authorsName(authors []jsonAuthor, author *jsonAuthor) string {
+	for _, a := range authors {
+		if name := strings.TrimSpace(a.Name); name != "" {
+			return name
+		}
+	}
+	if author != nil {
+		return strings.TrimSpace(author.Name)
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package feedparser
+
+import "strings"
+
+// mediaElements holds the Media RSS (media:*) extension, which is used by both
+// RSS and Atom documents.
+type mediaElements struct {
+	MediaTitle       string           `xml:"http://search.yahoo.com/mrss/ title"`
+	MediaDescription string           `xml:"http://search.yahoo.com/mrss/ description"`
+	MediaContents    []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails  []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups      []mediaElements  `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type mediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	FileSize   int64            `xml:"fileSize,attr"`
+	Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+func (m mediaElements) title() string {
+	if title := strings.TrimSpace(m.MediaTitle); title != "" {
+		return title
+	}
+	for _, group := range m.MediaGroups {
+		if title := group.title(); title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
+func (m mediaElements) description() string {
+	if description := strings.TrimSpace(m.MediaDescription); description != "" {
+		return description
+	}
+	for _, group := range m.MediaGroups {
+		if description := group.description(); description != "" {
+			return description
+		}
+	}
+	return ""
+}
+
+func (m mediaElements) thumbnail() string {
+	for _, thumbnail := range m.MediaThumbnails {
+		if thumbnail.URL != "" {
+			return thumbnail.URL
+		}
+	}
+	for _, content := range m.MediaContents {
+		for _, thumbnail := range content.Thumbnails {
+			if thumbnail.URL != "" {
+				return thumbnail.URL
+			}
+		}
+		if content.Medium == "image" || strings.HasPrefix(content.Type, "image/") {
+			return content.URL
+		}
+	}
+	for _, group := range m.MediaGroups {
+		if thumbnail := group.thumbnail(); thumbnail != "" {
+			return thumbnail
+		}
+	}
+	return ""
+}
+
+func (m mediaElements) enclosures() []Enclosure {
+	var enclosures []Enclosure
+
+	for _, content := range m.MediaContents {
+		if content.URL == "" {
+			continue
+		}
+		enclosures = append(enclosures, Enclosure{
+			URL:    content.URL,
+			Type:   content.Type,
+			Length: content.FileSize,
+		})
+	}
+
+	for _, group := range m.MediaGroups {
+		enclosures = append(enclosures, group.enclosures()...)
+	}
+
+	return enclosures
+}
+
+// appendEnclosures adds enclosures that are not already present, since
+// publishers frequently repeat the same file as <enclosure> and <media:content>.
+func appendEnclosures(dst []Enclosure, src ...Enclosure) []Enclosure {
+	for _, enclosure := range src {
+		duplicate := false
+		for _, existing := range dst {
+			if existing.URL == enclosure.URL {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			dst = append(dst, enclosure)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,110 @@
+package feedparser
+
+import (
+	"strings"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// rssDocument covers both RSS 2.0, where items live inside <channel>, and
+// RSS 1.0 (RDF), where they are siblings of it.
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+	Items   []rssItem  `xml:"item"`
+}
+
+type rssChannel struct {
+	Title       elements  `xml:"title"`
+	Link        elements  `xml:"link"`
+	Description elements  `xml:"description"`
+	Language    elements  `xml:"language"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	About          string         `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title          elements       `xml:"title"`
+	Link           elements       `xml:"link"`
+	Description    elements       `xml:"description"`
+	GUID           elements       `xml:"guid"`
+	Author         elements       `xml:"author"`
+	PubDate        elements       `xml:"pubDate"`
+	ContentEncoded string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	DCCreator      string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	DCDate         string         `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Enclosures     []rssEnclosure `xml:"enclosure"`
+
+	// The un-namespaced title and description fields above shadow the
+	// media:title and media:description fields of the embedded struct, so
+	// those are read back out of Title and Description instead.
+	mediaElements
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+func parseRSS(data []byte) (*Result, error) {
+	var doc rssDocument
+	if err := newDecoder(data).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Feed: &models.Feed{
+			Title:       doc.Channel.Title.native(),
+			Description: doc.Channel.Description.native(),
+			SiteURL:     doc.Channel.Link.native(),
+			Language:    firstNonEmpty(doc.Channel.Language.native(), doc.Channel.Language.in(nsDC)),
+		},
+	}
+
+	items := append(doc.Channel.Items, doc.Items...)
+	for _, item := range items {
+		result.Entries = append(result.Entries, item.entry())
+	}
+
+	return result, nil
+}
+
+func (item rssItem) entry() *Entry {
+	entry := &Entry{
+		GUID:      firstNonEmpty(item.GUID.native(), item.About),
+		Title:     firstNonEmpty(item.Title.native(), item.Title.in(nsMedia), item.title()),
+		Link:      item.Link.native(),
+		Author:    firstNonEmpty(item.DCCreator, authorName(item.Author.native())),
+		Content:   strings.TrimSpace(item.ContentEncoded),
+		Summary:   firstNonEmpty(item.Description.native(), item.Description.in(nsMedia), item.description()),
+		Published: parseTime(firstNonEmpty(item.PubDate.native(), item.DCDate)),
+		ImageURL:  item.thumbnail(),
+	}
+
+	for _, enclosure := range item.Enclosures {
+		if enclosure.URL == "" {
+			continue
+		}
+		entry.Enclosures = appendEnclosures(entry.Enclosures, Enclosure{
+			URL:    enclosure.URL,
+			Type:   enclosure.Type,
+			Length: enclosure.Length,
+		})
+	}
+	entry.Enclosures = appendEnclosures(entry.Enclosures, item.enclosures()...)
+
+	return entry
+}
+
+// authorName extracts the display name from RSS 2.0 author values, which are
+// specified as an email address optionally followed by a name in parentheses.
+func authorName(author string) string {
+	open := strings.Index(author, "(")
+	close := strings.LastIndex(author, ")")
+	if open >= 0 && close > open {
+		if name := strings.TrimSpace(author[open+1 : close]); name != "" {
+			return name
+		}
+	}
+	return author
+}
@@ -0,0 +1,87 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a feed URL, or a URL it redirects to,
+// resolves to an address that is not publicly routable. Feeds are fetched on
+// behalf of users, who must not be able to reach the server's own network,
+// such as its metrics listener or a cloud provider's metadata service.
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// nonPublicPrefixes are the special-purpose ranges not covered by the
+// netip.Addr predicates used in isPublic.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, which can reach any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// isPublic reports whether addr is a publicly routable unicast address.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// control is the dialer's Control hook, run for every connection after the
+// host name has been resolved. Checking there rather than on the URL also
+// covers redirects and host names that resolve differently on each lookup.
+func (f *Fetcher) control(network, address string, _ syscall.RawConn) error {
+	if f.AllowPrivateAddresses {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !isPublic(addrPort.Addr()) {
+		return fmt.Errorf("dial %s: %w", addrPort.Addr(), ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+// newTransport returns a transport like http.DefaultTransport that dials
+// through f.control. Proxies are not used, as the check would then apply to
+// the proxy instead of the feed's host.
+func (f *Fetcher) newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   f.control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestFetcher_Fetch_ForbiddenAddress(t *testing.T) {
+	var requests int
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(testFeed))
+	}))
+	defer upstream.Close()
+
+	// Host names are checked once resolved, so localhost is refused too.
+	port := upstream.URL[strings.LastIndex(upstream.URL, ":"):]
+
+	for _, url := range []string{upstream.URL, "http://localhost" + port} {
+		_, err := NewFetcher().Fetch(context.Background(), url)
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s: got error %v, want %v", url, err, ErrForbiddenAddress)
+		}
+	}
+
+	if requests != 0 {
+		t.Errorf("got %d requests upstream, want none", requests)
+	}
+}
+
+func TestFetcher_Discover_ForbiddenAddress(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFeed))
+	}))
+	defer upstream.Close()
+
+	_, err := NewFetcher().Discover(context.Background(), upstream.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("got error %v, want %v", err, ErrForbiddenAddress)
+	}
+}
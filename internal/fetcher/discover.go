@@ -0,0 +1,194 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/grodier/rss-app/internal/feedparser"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxCandidates bounds how many advertised feeds Discover will fetch, and
+// discoverWorkers how many of them are fetched at once.
+const (
+	maxCandidates   = 10
+	discoverWorkers = 4
+)
+
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// wellKnownPaths are probed when a page does not advertise any feeds.
+var wellKnownPaths = []string{"/feed", "/rss.xml", "/atom.xml"}
+
+// Candidate is a feed found by Discover. Title comes from the parsed feed
+// document, falling back to the title attribute of the advertising link.
+type Candidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Type  string `json:"type,omitzero"`
+}
+
+// Discover finds the feeds offered by the page at pageURL. If pageURL is
+// itself a feed it is returned as the only candidate. Otherwise the page's
+// <link rel="alternate"> tags are used, or a few well-known feed paths when it
+// has none. Only candidates that can be fetched and parsed are returned.
+//
+// Candidates are fetched concurrently. Should ctx end before every candidate
+// has been checked, its error is returned rather than a partial list, so
+// callers should give ctx a deadline that fits their own.
+func (f *Fetcher) Discover(ctx context.Context, pageURL string) ([]*Candidate, error) {
+	header := make(http.Header)
+	header.Set("Accept", "text/html, "+acceptHeader)
+
+	res, err := f.get(ctx, pageURL, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: res.StatusCode}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, res.Body, f.MaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	base := res.Request.URL
+
+	if result, err := feedparser.Parse(bytes.NewReader(body)); err == nil {
+		return []*Candidate{{URL: base.String(), Title: result.Feed.Title}}, nil
+	}
+
+	links := findFeedLinks(body, base)
+	if len(links) == 0 {
+		for _, path := range wellKnownPaths {
+			links = append(links, &Candidate{URL: base.ResolveReference(&url.URL{Path: path}).String()})
+		}
+	}
+
+	if len(links) > maxCandidates {
+		links = links[:maxCandidates]
+	}
+
+	found := make([]bool, len(links))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for range min(discoverWorkers, len(links)) {
+		wg.Go(func() {
+			for i := range jobs {
+				feed, err := f.Fetch(ctx, links[i].URL)
+				if err != nil {
+					continue
+				}
+
+				if title := feed.Result.Feed.Title; title != "" {
+					links[i].Title = title
+				}
+				found[i] = true
+			}
+		})
+	}
+
+	for i := range links {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	candidates := []*Candidate{}
+
+	for i, link := range links {
+		if found[i] {
+			candidates = append(candidates, link)
+		}
+	}
+
+	return candidates, nil
+}
+
+// findFeedLinks returns the feeds advertised by <link rel="alternate"> tags in
+// an HTML document, resolved against the document's base URL.
+func findFeedLinks(body []byte, base *url.URL) []*Candidate {
+	var links []*Candidate
+	seen := make(map[string]bool)
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+
+	for {
+		tt := z.Next()
+
+		switch tt {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+
+			switch tok.DataAtom {
+			case atom.Base:
+				if href := attr(tok, "href"); href != "" {
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case atom.Link:
+				if !hasToken(attr(tok, "rel"), "alternate") {
+					continue
+				}
+
+				mediaType := strings.ToLower(strings.TrimSpace(attr(tok, "type")))
+				if !feedTypes[mediaType] {
+					continue
+				}
+
+				u, err := base.Parse(strings.TrimSpace(attr(tok, "href")))
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || seen[u.String()] {
+					continue
+				}
+				seen[u.String()] = true
+
+				links = append(links, &Candidate{
+					URL:   u.String(),
+					Title: strings.TrimSpace(attr(tok, "title")),
+					Type:  mediaType,
+				})
+			}
+		}
+	}
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether the space separated list contains token.
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
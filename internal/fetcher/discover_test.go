@@ -0,0 +1,234 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAtomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Atom Title</title>
+</feed>`
+
+func newDiscoveryServer(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	for path, body := range routes {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(body))
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetcher_Discover_LinkTags(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Blog</title>
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate" type="application/rss+xml" title="RSS" href="/rss.xml">
+	<link rel="alternate" type="application/atom+xml" title="Atom" href="feeds/atom.xml">
+	<link rel="alternate" type="application/json" href="/wp-json/">
+	<link rel="alternate" type="application/rss+xml" href="/missing.xml">
+	<link rel="alternate" type="application/rss+xml" href="/rss.xml">
+</head>
+<body></body>
+</html>`
+
+	server := newDiscoveryServer(t, map[string]string{
+		"/":               page,
+		"/rss.xml":        testFeed,
+		"/feeds/atom.xml": testAtomFeed,
+	})
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Candidate{
+		{URL: server.URL + "/rss.xml", Title: "Upstream Feed", Type: "application/rss+xml"},
+		{URL: server.URL + "/feeds/atom.xml", Title: "Atom Title", Type: "application/atom+xml"},
+	}
+
+	if len(candidates) != len(want) {
+		t.Fatalf("got %d candidates, want %d: %+v", len(candidates), len(want), candidates)
+	}
+
+	for i, c := range candidates {
+		if *c != want[i] {
+			t.Errorf("candidate %d: got %+v, want %+v", i, *c, want[i])
+		}
+	}
+}
+
+func TestFetcher_Discover_BaseHref(t *testing.T) {
+	page := `<html><head>
+	<base href="/blog/">
+	<link rel="alternate" type="application/atom+xml" href="atom.xml">
+</head></html>`
+
+	server := newDiscoveryServer(t, map[string]string{
+		"/":              page,
+		"/blog/atom.xml": testAtomFeed,
+	})
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 1 || candidates[0].URL != server.URL+"/blog/atom.xml" {
+		t.Errorf("got %+v, want single candidate at /blog/atom.xml", candidates)
+	}
+}
+
+func TestFetcher_Discover_WellKnownPaths(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{
+		"/":         "<html><head><title>No feeds advertised</title></head></html>",
+		"/atom.xml": testAtomFeed,
+	})
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 1 {
+		t.Fatalf("got %d candidates, want 1", len(candidates))
+	}
+	if candidates[0].URL != server.URL+"/atom.xml" || candidates[0].Title != "Atom Title" {
+		t.Errorf("got %+v, want /atom.xml titled %q", candidates[0], "Atom Title")
+	}
+}
+
+func TestFetcher_Discover_PageError(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{})
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL+"/missing")
+	if candidates != nil {
+		t.Errorf("got %+v, want nil candidates", candidates)
+	}
+
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, want StatusError with code 404", err)
+	}
+}
+
+func TestFetcher_Discover_FeedURL(t *testing.T) {
+	server := newDiscoveryServer(t, map[string]string{
+		"/rss.xml": testFeed,
+	})
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL+"/rss.xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 1 {
+		t.Fatalf("got %d candidates, want 1", len(candidates))
+	}
+	if candidates[0].URL != server.URL+"/rss.xml" || candidates[0].Title != "Upstream Feed" {
+		t.Errorf("got %+v, want the feed itself", candidates[0])
+	}
+}
+
+// newSlowDiscoveryServer serves a page linking to n feeds that each take
+// delay to respond, or until the request is abandoned.
+func newSlowDiscoveryServer(t *testing.T, n int, delay time.Duration) *httptest.Server {
+	t.Helper()
+
+	var page strings.Builder
+	for i := range n {
+		fmt.Fprintf(&page, `<link rel="alternate" type="application/rss+xml" href="/feed/%d.xml">`, i)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(page.String()))
+	})
+	mux.HandleFunc("/feed/", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Write([]byte(testFeed))
+		case <-r.Context().Done():
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetcher_Discover_Concurrent(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	server := newSlowDiscoveryServer(t, maxCandidates, delay)
+
+	start := time.Now()
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != maxCandidates {
+		t.Errorf("got %d candidates, want %d", len(candidates), maxCandidates)
+	}
+
+	for i, c := range candidates {
+		if want := fmt.Sprintf("%s/feed/%d.xml", server.URL, i); c.URL != want {
+			t.Errorf("candidate %d: got %s, want %s in page order", i, c.URL, want)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed >= maxCandidates*delay/2 {
+		t.Errorf("took %v, want candidates fetched concurrently", elapsed)
+	}
+}
+
+func TestFetcher_Discover_MaxCandidates(t *testing.T) {
+	server := newSlowDiscoveryServer(t, maxCandidates+5, 0)
+
+	candidates, err := newTestFetcher().Discover(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != maxCandidates {
+		t.Errorf("got %d candidates, want %d", len(candidates), maxCandidates)
+	}
+}
+
+func TestFetcher_Discover_Deadline(t *testing.T) {
+	server := newSlowDiscoveryServer(t, 3, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := newTestFetcher().Discover(ctx, server.URL+"/")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v, want Discover to give up at the deadline", elapsed)
+	}
+}
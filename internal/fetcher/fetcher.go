@@ -0,0 +1,153 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grodier/rss-app/internal/feedparser"
+)
+
+const acceptHeader = "application/rss+xml, application/atom+xml, application/feed+json, application/json;q=0.9, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.8"
+
+// StatusError is returned when the upstream server answers with a status code
+// other than 200 OK or, for conditional requests, 304 Not Modified.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+}
+
+// ErrorMessage describes why a feed URL could not be fetched, in terms that
+// are safe to show to whoever supplied the URL.
+func ErrorMessage(err error) string {
+	var statusErr *StatusError
+
+	switch {
+	case errors.Is(err, feedparser.ErrUnknownFormat):
+		return "does not point to a supported feed format"
+	case errors.Is(err, ErrForbiddenAddress):
+		return "must not point to a private or local network address"
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("could not be fetched (status %d)", statusErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "took too long to respond"
+	default:
+		return "could not be fetched"
+	}
+}
+
+type Fetcher struct {
+	Client      *http.Client
+	UserAgent   string
+	MaxBodySize int64
+
+	// AllowPrivateAddresses lets the client built by NewFetcher connect to
+	// loopback, private and other non-public addresses, which it otherwise
+	// refuses with ErrForbiddenAddress.
+	AllowPrivateAddresses bool
+}
+
+func NewFetcher() *Fetcher {
+	f := &Fetcher{
+		UserAgent:   "rss-app",
+		MaxBodySize: 10 << 20,
+	}
+	f.Client = &http.Client{Timeout: 30 * time.Second, Transport: f.newTransport()}
+
+	return f
+}
+
+type Response struct {
+	// URL is the address the document was served from after following
+	// redirects.
+	URL string
+
+	// NotModified reports that the server answered a conditional request
+	// with 304, in which case Result is nil.
+	NotModified bool
+
+	// ETag and LastModified are the validators to send on the next request.
+	ETag         string
+	LastModified string
+
+	Result *feedparser.Result
+}
+
+// Fetch downloads and parses the feed document at url.
+func (f *Fetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	return f.FetchConditional(ctx, url, "", "")
+}
+
+// FetchConditional is like Fetch, but sends If-None-Match and
+// If-Modified-Since using validators from a previous response so unchanged
+// documents are not downloaded again.
+func (f *Fetcher) FetchConditional(ctx context.Context, url, etag, lastModified string) (*Response, error) {
+	header := make(http.Header)
+	header.Set("Accept", acceptHeader)
+
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+
+	res, err := f.get(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		return &Response{
+			URL:          res.Request.URL.String(),
+			NotModified:  true,
+			ETag:         headerOr(res.Header, "ETag", etag),
+			LastModified: headerOr(res.Header, "Last-Modified", lastModified),
+		}, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: res.StatusCode}
+	}
+
+	result, err := feedparser.Parse(http.MaxBytesReader(nil, res.Body, f.MaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		URL:          res.Request.URL.String(),
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Result:       result,
+	}, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	return f.Client.Do(req)
+}
+
+// headerOr returns the named header, or fallback when a 304 response omits it.
+func headerOr(header http.Header, key, fallback string) string {
+	if value := header.Get(key); value != "" {
+		return value
+	}
+	return fallback
+}
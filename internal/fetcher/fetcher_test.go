@@ -0,0 +1,214 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/feedparser"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0">
+	<channel>
+		<title>Upstream Feed</title>
+		<link>https://upstream.example.com/</link>
+		<description>Served by httptest</description>
+		<item>
+			<title>Upstream Item</title>
+			<guid>upstream-1</guid>
+		</item>
+	</channel>
+</rss>`
+
+// newTestFetcher returns a fetcher that can reach httptest servers, which
+// listen on the loopback interface.
+func newTestFetcher() *Fetcher {
+	f := NewFetcher()
+	f.AllowPrivateAddresses = true
+	return f
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	var gotUserAgent string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeed))
+	}))
+	defer upstream.Close()
+
+	f := newTestFetcher()
+	f.UserAgent = "rss-app-test"
+
+	res, err := f.Fetch(context.Background(), upstream.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotUserAgent != "rss-app-test" {
+		t.Errorf("got User-Agent %q, want %q", gotUserAgent, "rss-app-test")
+	}
+	if res.URL != upstream.URL {
+		t.Errorf("got URL %q, want %q", res.URL, upstream.URL)
+	}
+	if res.Result.Feed.Title != "Upstream Feed" {
+		t.Errorf("got Title %q, want %q", res.Result.Feed.Title, "Upstream Feed")
+	}
+	if len(res.Result.Entries) != 1 {
+		t.Errorf("got %d entries, want 1", len(res.Result.Entries))
+	}
+}
+
+func TestFetcher_Fetch_FollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFeed))
+	})
+
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	res, err := newTestFetcher().Fetch(context.Background(), upstream.URL+"/old")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.URL != upstream.URL+"/new" {
+		t.Errorf("got URL %q, want %q", res.URL, upstream.URL+"/new")
+	}
+}
+
+func TestFetcher_Fetch_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		maxSize int64
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name:   "non-200 status",
+			status: http.StatusInternalServerError,
+			check: func(t *testing.T, err error) {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+					t.Errorf("got error %v, want StatusError with code 500", err)
+				}
+			},
+		},
+		{
+			name:   "not a feed",
+			status: http.StatusOK,
+			body:   "<html><body>hello</body></html>",
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, feedparser.ErrUnknownFormat) {
+					t.Errorf("got error %v, want %v", err, feedparser.ErrUnknownFormat)
+				}
+			},
+		},
+		{
+			name:    "body too large",
+			status:  http.StatusOK,
+			body:    testFeed + strings.Repeat(" ", 1024),
+			maxSize: 64,
+			check: func(t *testing.T, err error) {
+				var maxBytesErr *http.MaxBytesError
+				if !errors.As(err, &maxBytesErr) {
+					t.Errorf("got error %v, want MaxBytesError", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer upstream.Close()
+
+			f := newTestFetcher()
+			if tt.maxSize > 0 {
+				f.MaxBodySize = tt.maxSize
+			}
+
+			res, err := f.Fetch(context.Background(), upstream.URL)
+			if res != nil {
+				t.Error("expected nil response")
+			}
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestFetcher_FetchConditional(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(testFeed))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name            string
+		etag            string
+		lastModified    string
+		wantNotModified bool
+	}{
+		{"no validators", "", "", false},
+		{"stale etag", `"v0"`, "", false},
+		{"matching etag", etag, "", true},
+		{"matching last modified", "", lastModified, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := newTestFetcher().FetchConditional(context.Background(), upstream.URL, tt.etag, tt.lastModified)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.NotModified != tt.wantNotModified {
+				t.Errorf("got NotModified %v, want %v", res.NotModified, tt.wantNotModified)
+			}
+
+			if tt.wantNotModified {
+				if res.Result != nil {
+					t.Error("expected nil Result for a 304 response")
+				}
+				if res.ETag != tt.etag || res.LastModified != tt.lastModified {
+					t.Errorf("got validators (%q, %q), want previous validators (%q, %q)", res.ETag, res.LastModified, tt.etag, tt.lastModified)
+				}
+				return
+			}
+
+			if res.Result == nil {
+				t.Fatal("expected parsed Result for a 200 response")
+			}
+			if res.ETag != etag {
+				t.Errorf("got ETag %q, want %q", res.ETag, etag)
+			}
+			if res.LastModified != lastModified {
+				t.Errorf("got LastModified %q, want %q", res.LastModified, lastModified)
+			}
+		})
+	}
+}
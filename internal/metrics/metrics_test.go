@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func collect(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return sb.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests served.", "method", "status")
+
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(3, "POST", "201")
+
+	want := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 2
+requests_total{method="POST",status="201"} 3
+`
+	if got := collect(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("in_flight", "Requests in flight.")
+
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	g.Add(2.5)
+
+	want := `# HELP in_flight Requests in flight.
+# TYPE in_flight gauge
+in_flight 3.5
+`
+	if got := collect(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	g.Set(0)
+	if got := collect(t, r); !strings.Contains(got, "in_flight 0\n") {
+		t.Errorf("expected gauge to be set to 0, got\n%s", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("duration_seconds", "Request duration.", []float64{1, 0.1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(2, "/a")
+
+	want := `# HELP duration_seconds Request duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 2
+duration_seconds_bucket{route="/a",le="1"} 3
+duration_seconds_bucket{route="/a",le="+Inf"} 4
+duration_seconds_sum{route="/a"} 2.65
+duration_seconds_count{route="/a"} 4
+`
+	if got := collect(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	r := NewRegistry()
+
+	value := 1.0
+	r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return value })
+	r.NewCounterFunc("waits_total", "Waits.", func() float64 { return value * 10 })
+
+	value = 4
+
+	want := `# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections 4
+# HELP waits_total Waits.
+# TYPE waits_total counter
+waits_total 40
+`
+	if got := collect(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("escaped_total", "Line one\nline \\two.", "value")
+
+	c.Inc("say \"hi\"\n\\")
+
+	want := `# HELP escaped_total Line one\nline \\two.
+# TYPE escaped_total counter
+escaped_total{value="say \"hi\"\n\\"} 1
+`
+	if got := collect(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("things_total", "Things.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a duplicate name to panic")
+		}
+	}()
+
+	r.NewGauge("things_total", "Things again.")
+}
+
+func TestCounter_WrongLabelCount(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("things_total", "Things.", "kind")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a missing label value to panic")
+		}
+	}()
+
+	c.Inc()
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("things_total", "Things.").Inc()
+
+	rr := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type %q, want the Prometheus text format", got)
+	}
+
+	if !strings.Contains(rr.Body.String(), "things_total 1\n") {
+		t.Errorf("expected body to contain the counter, got\n%s", rr.Body.String())
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "errors"
+
+// Errors returned by every storage backend, so that callers can tell these
+// cases apart without knowing which database they are talking to.
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("edit conflict")
+	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrDuplicateURL   = errors.New("duplicate url")
+
+	ErrDuplicateSubscription = errors.New("duplicate subscription")
+	ErrDuplicateFolder       = errors.New("duplicate folder")
+)
@@ -1,33 +1,51 @@
 package models
 
 import (
+	"context"
+	"net/url"
 	"time"
 
 	"github.com/grodier/rss-app/internal/validator"
 )
 
 type Feed struct {
-	ID          int64     `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	URL         string    `json:"url"`
-	SiteURL     string    `json:"site_url"`
-	CreatedAt   time.Time `json:"-"`
-	Language    string    `json:"language,omitzero"`
-	Version     int32     `json:"version"`
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	URL          string    `json:"url"`
+	SiteURL      string    `json:"site_url"`
+	CreatedAt    time.Time `json:"-"`
+	Language     string    `json:"language,omitzero"`
+	Version      int32     `json:"version"`
+	NextFetchAt  time.Time `json:"-"`
+	ETag         string    `json:"-"`
+	LastModified string    `json:"-"`
 }
 
 type FeedService interface {
-	Create(feed *Feed) error
-	Get(id int64) (*Feed, error)
-	Update(feed *Feed) error
-	Delete(id int64) error
+	Create(ctx context.Context, feed *Feed) error
+	Get(ctx context.Context, id int64) (*Feed, error)
+	GetByURL(ctx context.Context, url string) (*Feed, error)
+	GetAll(ctx context.Context, title string, language string, filters Filters) ([]*Feed, Metadata, error)
+	Update(ctx context.Context, feed *Feed) error
+	Delete(ctx context.Context, id int64) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*Feed, error)
+	UpdateFetchState(ctx context.Context, feed *Feed) error
 }
 
+// ValidateFeed checks a feed before it is stored. The description is
+// optional, since feeds are filled in from their own document and many
+// don't carry one.
 func ValidateFeed(v *validator.Validator, feed *Feed) {
 	v.Check(feed.Title != "", "title", "must be provided")
 	v.Check(len(feed.Title) <= 500, "title", "must not be more than 500 bytes long")
-	v.Check(feed.Description != "", "description", "must be provided")
-	v.Check(feed.URL != "", "url", "must be provided")
+	ValidateFeedURL(v, feed.URL)
 	v.Check(feed.SiteURL != "", "site_url", "must be provided")
 }
+
+func ValidateFeedURL(v *validator.Validator, feedURL string) {
+	v.Check(feedURL != "", "url", "must be provided")
+
+	u, err := url.Parse(feedURL)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "url", "must be an absolute http or https URL")
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+type Filters struct {
+	Page         int
+	PageSize     int
+	Sort         string
+	SortSafelist []string
+}
+
+func ValidateFilters(v *validator.Validator, f Filters) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+
+	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+}
+
+// SortColumn returns the column to order by. It panics if Sort is not in the
+// safelist, as the value is interpolated into SQL and must never come
+// straight from the client.
+func (f Filters) SortColumn() string {
+	for _, safeValue := range f.SortSafelist {
+		if f.Sort == safeValue {
+			return strings.TrimPrefix(f.Sort, "-")
+		}
+	}
+
+	panic("unsafe sort parameter: " + f.Sort)
+}
+
+func (f Filters) SortDirection() string {
+	if strings.HasPrefix(f.Sort, "-") {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func (f Filters) Limit() int {
+	return f.PageSize
+}
+
+func (f Filters) Offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitzero"`
+	PageSize     int `json:"page_size,omitzero"`
+	FirstPage    int `json:"first_page,omitzero"`
+	LastPage     int `json:"last_page,omitzero"`
+	TotalRecords int `json:"total_records,omitzero"`
+}
+
+func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered by publication time, newest
+// first. The zero Cursor is the start of the list.
+type Cursor struct {
+	PublishedAt time.Time
+	ID          int64
+}
+
+// Encode returns the cursor as an opaque token for clients to send back.
+// The time is kept as seconds and nanoseconds, since UnixNano overflows for
+// dates outside 1678-2262 and feeds do carry dates like that.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d:%d", c.PublishedAt.Unix(), c.PublishedAt.Nanosecond(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(token string) (Cursor, error) {
+	if token == "" {
+		return Cursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var secs, nanos, id int64
+	if _, err := fmt.Sscanf(string(raw), "%d:%d:%d", &secs, &nanos, &id); err != nil || id < 1 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	if nanos < 0 || nanos >= int64(time.Second) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{PublishedAt: time.Unix(secs, nanos).UTC(), ID: id}, nil
+}
+
+type CursorFilters struct {
+	After    Cursor
+	PageSize int
+}
+
+func ValidateCursorFilters(v *validator.Validator, f CursorFilters) {
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+}
+
+type CursorMetadata struct {
+	PageSize   int    `json:"page_size"`
+	NextCursor string `json:"next_cursor,omitzero"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+// Folder groups a user's subscriptions.
+type Folder struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"-"`
+	Name        string    `json:"name"`
+	CreatedAt   time.Time `json:"created_at"`
+	Version     int32     `json:"version"`
+	UnreadCount int       `json:"unread_count"`
+}
+
+type FolderService interface {
+	Create(folder *Folder) error
+	Get(id int64, userID int64) (*Folder, error)
+	GetAllForUser(userID int64) ([]*Folder, error)
+	Update(folder *Folder) error
+	Delete(id int64, userID int64) error
+}
+
+func ValidateFolder(v *validator.Validator, folder *Folder) {
+	v.Check(folder.Name != "", "name", "must be provided")
+	v.Check(len(folder.Name) <= 100, "name", "must not be more than 100 bytes long")
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+type Item struct {
+	ID          int64     `json:"id"`
+	FeedID      int64     `json:"feed_id"`
+	GUID        string    `json:"guid"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Author      string    `json:"author,omitzero"`
+	Content     string    `json:"content,omitzero"`
+	Summary     string    `json:"summary,omitzero"`
+	PublishedAt time.Time `json:"published_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ContentHash string    `json:"-"`
+	CreatedAt   time.Time `json:"-"`
+
+	// State is the requesting user's state for the item, when loaded.
+	State *ItemState `json:"state,omitzero"`
+}
+
+type ItemService interface {
+	Upsert(item *Item) error
+	Get(id int64) (*Item, error)
+	GetAllForFeed(feedID int64, userID int64, filters CursorFilters) ([]*Item, CursorMetadata, error)
+	GetAllForFolder(folderID int64, userID int64, filters CursorFilters) ([]*Item, CursorMetadata, error)
+	Search(userID int64, search ItemSearch, filters Filters) ([]*SearchResult, Metadata, error)
+}
+
+// ItemSearch finds items in a user's subscriptions. Query uses web search
+// syntax: quoted phrases, "or" and a leading "-" to exclude words. A zero
+// PublishedAfter or PublishedBefore leaves that end of the range open.
+type ItemSearch struct {
+	Query           string
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+}
+
+// SearchResult is an item matching a search, with its relevance and a
+// snippet of the matching text. Headline is safe to render as HTML: it is
+// escaped text in which only the matches are wrapped in <mark> tags.
+type SearchResult struct {
+	*Item
+	Rank     float64 `json:"rank"`
+	Headline string  `json:"headline"`
+}
+
+// Highlight delimiters the storage backends wrap matches in.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// EscapeHeadline turns a snippet built from item text into a Headline. The
+// text must have had every HTML tag removed with the pattern <[^>]*>, which
+// leaves no '>' after any remaining '<', so the highlight delimiters can only
+// come from the backend. Whatever markup is left, such as an unclosed tag, is
+// escaped along with the rest of the text; entities are decoded first so that
+// they aren't escaped twice.
+func EscapeHeadline(snippet string) string {
+	var b strings.Builder
+
+	for {
+		before, rest, found := strings.Cut(snippet, HighlightStart)
+		b.WriteString(escapeText(before))
+		if !found {
+			return b.String()
+		}
+
+		match, after, _ := strings.Cut(rest, HighlightStop)
+		b.WriteString(HighlightStart + escapeText(match) + HighlightStop)
+		snippet = after
+	}
+}
+
+func escapeText(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+// Hash returns a digest of the item's user visible fields, used to detect
+// whether a re-fetched entry actually changed.
+func (i *Item) Hash() string {
+	h := sha256.New()
+	for _, field := range []string{i.Title, i.Link, i.Author, i.Content, i.Summary} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func ValidateItem(v *validator.Validator, item *Item) {
+	v.Check(item.FeedID > 0, "feed_id", "must be provided")
+	v.Check(item.GUID != "", "guid", "must be provided")
+	v.Check(len(item.GUID) <= 2048, "guid", "must not be more than 2048 bytes long")
+}
+
+func ValidateItemSearch(v *validator.Validator, search ItemSearch) {
+	v.Check(search.Query != "", "q", "must be provided")
+	v.Check(len(search.Query) <= 500, "q", "must not be more than 500 bytes long")
+
+	if !search.PublishedAfter.IsZero() && !search.PublishedBefore.IsZero() {
+		v.Check(search.PublishedAfter.Before(search.PublishedBefore), "published_before", "must be later than published_after")
+	}
+}
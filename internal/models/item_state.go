@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+// ItemState is a user's read and starred flags for an item.
+type ItemState struct {
+	Read    bool `json:"read"`
+	Starred bool `json:"starred"`
+}
+
+// ItemStateUpdate changes the flags that are non-nil and leaves the others as
+// they are.
+type ItemStateUpdate struct {
+	Read    *bool
+	Starred *bool
+}
+
+type ItemStateService interface {
+	// Update applies update to the given items in feeds the user subscribes
+	// to, returning how many items were updated.
+	Update(userID int64, itemIDs []int64, update ItemStateUpdate) (int64, error)
+	// MarkSubscriptionRead marks every item in the subscription's feed
+	// published at or before before as read.
+	MarkSubscriptionRead(subscriptionID int64, userID int64, before time.Time) error
+}
+
+func ValidateItemStateUpdate(v *validator.Validator, update ItemStateUpdate) {
+	v.Check(update.Read != nil || update.Starred != nil, "state", "must change read or starred")
+}
+
+func ValidateItemIDs(v *validator.Validator, itemIDs []int64) {
+	v.Check(len(itemIDs) > 0, "item_ids", "must contain at least 1 item")
+	v.Check(len(itemIDs) <= 1000, "item_ids", "must not contain more than 1000 items")
+	v.Check(validator.Unique(itemIDs), "item_ids", "must not contain duplicate values")
+
+	for _, id := range itemIDs {
+		v.Check(id > 0, "item_ids", "must only contain positive ids")
+	}
+}
@@ -0,0 +1,470 @@
+// Package modelstest holds conformance tests run against every storage
+// backend, so that they stay interchangeable: the same calls must store the
+// same data and fail with the same errors.
+package modelstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// TestFeedService checks a models.FeedService implementation. newService is
+// called once per subtest and must return a service backed by a database
+// holding no feeds.
+func TestFeedService(t *testing.T, newService func(t *testing.T) models.FeedService) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, fs models.FeedService)
+	}{
+		{"Create", testFeedCreate},
+		{"CreateDuplicateURL", testFeedCreateDuplicateURL},
+		{"GetMissing", testFeedGetMissing},
+		{"Update", testFeedUpdate},
+		{"UpdateConflict", testFeedUpdateConflict},
+		{"UpdateDuplicateURL", testFeedUpdateDuplicateURL},
+		{"UpdateClearsValidators", testFeedUpdateClearsValidators},
+		{"Delete", testFeedDelete},
+		{"GetAll", testFeedGetAll},
+		{"GetAllPaging", testFeedGetAllPaging},
+		{"ClaimDue", testFeedClaimDue},
+		{"UpdateFetchState", testFeedUpdateFetchState},
+		{"CancelledContext", testFeedCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newService(t))
+		})
+	}
+}
+
+func createFeed(t *testing.T, fs models.FeedService, title, language string) *models.Feed {
+	t.Helper()
+
+	feed := &models.Feed{
+		Title:       title,
+		Description: "About " + title,
+		URL:         fmt.Sprintf("https://example.com/%d/feed.xml", time.Now().UnixNano()),
+		SiteURL:     "https://example.com",
+		Language:    language,
+	}
+
+	if err := fs.Create(context.Background(), feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+
+	return feed
+}
+
+func testFeedCreate(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+
+	feed := &models.Feed{
+		Title:       "Go Blog",
+		Description: "News from the Go team",
+		URL:         "https://go.dev/blog/feed.atom",
+		SiteURL:     "https://go.dev/blog",
+		Language:    "en",
+	}
+
+	if err := fs.Create(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if feed.ID < 1 {
+		t.Errorf("got ID %d, want a positive ID", feed.ID)
+	}
+	if feed.Version != 1 {
+		t.Errorf("got version %d, want 1", feed.Version)
+	}
+	if feed.CreatedAt.Before(before) || feed.CreatedAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("got created at %v, want about now", feed.CreatedAt)
+	}
+
+	for name, get := range map[string]func() (*models.Feed, error){
+		"Get":      func() (*models.Feed, error) { return fs.Get(ctx, feed.ID) },
+		"GetByURL": func() (*models.Feed, error) { return fs.GetByURL(ctx, feed.URL) },
+	} {
+		got, err := get()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		if got.ID != feed.ID || got.Title != feed.Title || got.Description != feed.Description ||
+			got.URL != feed.URL || got.SiteURL != feed.SiteURL || got.Language != feed.Language ||
+			got.Version != feed.Version || !got.CreatedAt.Equal(feed.CreatedAt) {
+			t.Errorf("%s: got %+v, want %+v", name, got, feed)
+		}
+	}
+}
+
+func testFeedCreateDuplicateURL(t *testing.T, fs models.FeedService) {
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	duplicate := &models.Feed{Title: "Other", URL: feed.URL, SiteURL: "https://example.org"}
+
+	if err := fs.Create(context.Background(), duplicate); err != models.ErrDuplicateURL {
+		t.Errorf("got error %v, want %v", err, models.ErrDuplicateURL)
+	}
+}
+
+func testFeedGetMissing(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	for _, id := range []int64{0, -1, feed.ID + 1000} {
+		if _, err := fs.Get(ctx, id); err != models.ErrRecordNotFound {
+			t.Errorf("Get(%d): got error %v, want %v", id, err, models.ErrRecordNotFound)
+		}
+	}
+
+	if _, err := fs.GetByURL(ctx, "https://example.com/missing.xml"); err != models.ErrRecordNotFound {
+		t.Errorf("GetByURL: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+}
+
+func testFeedUpdate(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	feed.Title = "The Go Blog"
+	feed.Language = "en-US"
+
+	if err := fs.Update(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if feed.Version != 2 {
+		t.Errorf("got version %d, want 2", feed.Version)
+	}
+
+	got, err := fs.Get(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Title != "The Go Blog" || got.Language != "en-US" || got.Version != 2 {
+		t.Errorf("got %+v, want the updated feed", got)
+	}
+}
+
+func testFeedUpdateConflict(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	first, _ := fs.Get(ctx, feed.ID)
+	second, _ := fs.Get(ctx, feed.ID)
+
+	first.Title = "First"
+	if err := fs.Update(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second.Title = "Second"
+	if err := fs.Update(ctx, second); err != models.ErrEditConflict {
+		t.Errorf("stale version: got error %v, want %v", err, models.ErrEditConflict)
+	}
+	if second.Version != 1 {
+		t.Errorf("got version %d after a conflict, want it unchanged", second.Version)
+	}
+
+	got, _ := fs.Get(ctx, feed.ID)
+	if got.Title != "First" || got.Version != 2 {
+		t.Errorf("got %+v, want the first update to stand", got)
+	}
+
+	missing := *got
+	missing.ID = got.ID + 1000
+	if err := fs.Update(ctx, &missing); err != models.ErrEditConflict {
+		t.Errorf("missing feed: got error %v, want %v", err, models.ErrEditConflict)
+	}
+
+	missing.ID = 0
+	if err := fs.Update(ctx, &missing); err != models.ErrRecordNotFound {
+		t.Errorf("zero ID: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+}
+
+func testFeedUpdateDuplicateURL(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	taken := createFeed(t, fs, "Go Blog", "en")
+	feed := createFeed(t, fs, "Rust Blog", "en")
+	url := feed.URL
+
+	feed.URL = taken.URL
+	if err := fs.Update(ctx, feed); err != models.ErrDuplicateURL {
+		t.Errorf("got error %v, want %v", err, models.ErrDuplicateURL)
+	}
+
+	got, _ := fs.Get(ctx, feed.ID)
+	if got.URL != url || got.Version != 1 {
+		t.Errorf("got %+v, want the feed unchanged", got)
+	}
+}
+
+func testFeedUpdateClearsValidators(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	feed.ETag = `"v1"`
+	feed.LastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	feed.NextFetchAt = time.Now().Add(-time.Minute)
+	if err := fs.UpdateFetchState(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feed.Title = "The Go Blog"
+	if err := fs.Update(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.ETag != `"v1"` || feed.LastModified == "" {
+		t.Errorf("got etag %q and last modified %q, want them kept for the same url", feed.ETag, feed.LastModified)
+	}
+
+	feed.URL = "https://moved.example.com/feed.xml"
+	if err := fs.Update(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.ETag != "" || feed.LastModified != "" {
+		t.Errorf("got etag %q and last modified %q, want them cleared for a new url", feed.ETag, feed.LastModified)
+	}
+
+	claimed, err := fs.ClaimDue(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ETag != "" || claimed[0].LastModified != "" {
+		t.Errorf("got claimed feeds %+v, want the validators cleared in storage", claimed)
+	}
+}
+
+func testFeedDelete(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	if err := fs.Delete(ctx, feed.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := fs.Get(ctx, feed.ID); err != models.ErrRecordNotFound {
+		t.Errorf("Get after Delete: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	if err := fs.Delete(ctx, feed.ID); err != models.ErrRecordNotFound {
+		t.Errorf("second Delete: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	if err := fs.Delete(ctx, 0); err != models.ErrRecordNotFound {
+		t.Errorf("Delete(0): got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	if err := fs.Update(ctx, feed); err != models.ErrEditConflict {
+		t.Errorf("Update after Delete: got error %v, want %v", err, models.ErrEditConflict)
+	}
+}
+
+func testFeedGetAll(t *testing.T, fs models.FeedService) {
+	goBlog := createFeed(t, fs, "Go Blog", "en")
+	rustBlog := createFeed(t, fs, "Rust Blog", "en")
+	leBlog := createFeed(t, fs, "Le blog de Go", "fr")
+
+	tests := []struct {
+		name     string
+		title    string
+		language string
+		want     []int64
+	}{
+		{"everything", "", "", []int64{goBlog.ID, rustBlog.ID, leBlog.ID}},
+		{"one word", "blog", "", []int64{goBlog.ID, rustBlog.ID, leBlog.ID}},
+		{"every word must match", "go blog", "", []int64{goBlog.ID, leBlog.ID}},
+		{"case insensitive", "RUST", "", []int64{rustBlog.ID}},
+		{"whole words only", "bl", "", []int64{}},
+		{"language", "", "fr", []int64{leBlog.ID}},
+		{"title and language", "go", "en", []int64{goBlog.ID}},
+		{"no match", "rust", "fr", []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := models.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
+
+			feeds, metadata, err := fs.GetAll(context.Background(), tt.title, tt.language, filters)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := feedIDs(feeds); !slices.Equal(got, tt.want) {
+				t.Errorf("got feeds %v, want %v", got, tt.want)
+			}
+
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("got %d total records, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+}
+
+func testFeedGetAllPaging(t *testing.T, fs models.FeedService) {
+	var ids []int64
+	for _, title := range []string{"One", "Two", "Three"} {
+		ids = append(ids, createFeed(t, fs, title, "en").ID)
+	}
+
+	filters := models.Filters{Page: 2, PageSize: 2, Sort: "-id", SortSafelist: []string{"id", "-id"}}
+
+	feeds, metadata, err := fs.GetAll(context.Background(), "", "", filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := feedIDs(feeds); !slices.Equal(got, []int64{ids[0]}) {
+		t.Errorf("got feeds %v, want %v", got, []int64{ids[0]})
+	}
+
+	want := models.Metadata{CurrentPage: 2, PageSize: 2, FirstPage: 1, LastPage: 2, TotalRecords: 3}
+	if metadata != want {
+		t.Errorf("got metadata %+v, want %+v", metadata, want)
+	}
+
+	// A page past the end is empty, and without metadata as there are no
+	// records to count.
+	filters.Page = 3
+	feeds, metadata, err = fs.GetAll(context.Background(), "", "", filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(feeds) != 0 || metadata != (models.Metadata{}) {
+		t.Errorf("got %d feeds and metadata %+v, want none", len(feeds), metadata)
+	}
+}
+
+func testFeedClaimDue(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+
+	var ids []int64
+	for _, title := range []string{"One", "Two", "Three"} {
+		ids = append(ids, createFeed(t, fs, title, "en").ID)
+	}
+
+	claimed, err := fs.ClaimDue(ctx, 2, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(claimed) != 2 {
+		t.Fatalf("got %d feeds claimed, want 2", len(claimed))
+	}
+
+	for _, feed := range claimed {
+		if until := time.Until(feed.NextFetchAt); until < 59*time.Minute || until > 61*time.Minute {
+			t.Errorf("feed %d: got next fetch in %v, want the lease of 1h", feed.ID, until)
+		}
+		if feed.Title == "" || feed.URL == "" || feed.Version != 1 {
+			t.Errorf("got %+v, want the claimed feed loaded", feed)
+		}
+	}
+
+	// Leased feeds are skipped until the lease runs out.
+	rest, err := fs.ClaimDue(ctx, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	all := append(feedIDs(claimed), feedIDs(rest)...)
+	slices.Sort(all)
+
+	if !slices.Equal(all, ids) {
+		t.Errorf("got feeds %v claimed across both calls, want each of %v once", all, ids)
+	}
+
+	none, err := fs.ClaimDue(ctx, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(none) != 0 {
+		t.Errorf("got %d feeds claimed, want none while leased", len(none))
+	}
+}
+
+func testFeedUpdateFetchState(t *testing.T, fs models.FeedService) {
+	ctx := context.Background()
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	feed.NextFetchAt = time.Now().Add(-time.Minute).Truncate(time.Second)
+	feed.ETag = `"abc"`
+	feed.LastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	if err := fs.UpdateFetchState(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := fs.Get(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Version != 1 {
+		t.Errorf("got version %d, want fetches to leave it alone", got.Version)
+	}
+
+	claimed, err := fs.ClaimDue(ctx, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(claimed) != 1 {
+		t.Fatalf("got %d feeds claimed, want 1", len(claimed))
+	}
+
+	if claimed[0].ETag != feed.ETag || claimed[0].LastModified != feed.LastModified {
+		t.Errorf("got etag %q and last modified %q, want %q and %q",
+			claimed[0].ETag, claimed[0].LastModified, feed.ETag, feed.LastModified)
+	}
+
+	// A next fetch in the future takes the feed out of rotation.
+	feed.NextFetchAt = time.Now().Add(time.Hour)
+	if err := fs.UpdateFetchState(ctx, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	missing := *feed
+	for _, id := range []int64{0, feed.ID + 1000} {
+		missing.ID = id
+		if err := fs.UpdateFetchState(ctx, &missing); err != models.ErrRecordNotFound {
+			t.Errorf("ID %d: got error %v, want %v", id, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func testFeedCancelledContext(t *testing.T, fs models.FeedService) {
+	feed := createFeed(t, fs, "Go Blog", "en")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := fs.Get(ctx, feed.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	feed.Title = "Renamed"
+	if err := fs.Update(ctx, feed); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func feedIDs(feeds []*models.Feed) []int64 {
+	ids := []int64{}
+	for _, feed := range feeds {
+		ids = append(ids, feed.ID)
+	}
+	return ids
+}
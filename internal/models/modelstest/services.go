@@ -0,0 +1,828 @@
+package modelstest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// TestServices checks the implementations of every storage service but
+// models.FeedService, which TestFeedService covers. newServices is called
+// once per subtest and must return services backed by a database holding no
+// users and no feeds.
+func TestServices(t *testing.T, newServices func(t *testing.T) *models.Services) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s *models.Services)
+	}{
+		{"UserCreate", testUserCreate},
+		{"UserGetForToken", testUserGetForToken},
+		{"PermissionAddForUser", testPermissionAddForUser},
+		{"FolderCreate", testFolderCreate},
+		{"FolderGetAllForUser", testFolderGetAllForUser},
+		{"FolderUpdate", testFolderUpdate},
+		{"FolderDelete", testFolderDelete},
+		{"SubscriptionCreate", testSubscriptionCreate},
+		{"SubscriptionGetAllForUser", testSubscriptionGetAllForUser},
+		{"SubscriptionUpdate", testSubscriptionUpdate},
+		{"SubscriptionDelete", testSubscriptionDelete},
+		{"ItemUpsert", testItemUpsert},
+		{"ItemGetAllForFeed", testItemGetAllForFeed},
+		{"ItemGetAllForFolder", testItemGetAllForFolder},
+		{"ItemSearch", testItemSearch},
+		{"ItemStateUpdate", testItemStateUpdate},
+		{"ItemStateMarkSubscriptionRead", testItemStateMarkSubscriptionRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newServices(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, s *models.Services, email string) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, Password: models.Password{Hash: []byte("hash")}}
+	if err := s.Users.Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return user
+}
+
+func createFolder(t *testing.T, s *models.Services, userID int64, name string) *models.Folder {
+	t.Helper()
+
+	folder := &models.Folder{UserID: userID, Name: name}
+	if err := s.Folders.Create(folder); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+
+	return folder
+}
+
+func createSubscription(t *testing.T, s *models.Services, userID, feedID, folderID int64) *models.Subscription {
+	t.Helper()
+
+	subscription := &models.Subscription{UserID: userID, FeedID: feedID, FolderID: folderID}
+	if err := s.Subscriptions.Create(subscription); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	return subscription
+}
+
+// createItems stores an item per content in feedID, published an hour apart
+// from start, and returns their IDs oldest first.
+func createItems(t *testing.T, s *models.Services, feedID int64, start time.Time, contents ...string) []int64 {
+	t.Helper()
+
+	var ids []int64
+	for i, content := range contents {
+		published := start.Add(time.Duration(i) * time.Hour)
+
+		item := &models.Item{
+			FeedID:      feedID,
+			GUID:        fmt.Sprintf("item-%d", i),
+			Title:       fmt.Sprintf("Item %d", i),
+			Link:        fmt.Sprintf("https://example.com/items/%d", i),
+			Content:     content,
+			PublishedAt: published,
+			UpdatedAt:   published,
+		}
+		if err := s.Items.Upsert(item); err != nil {
+			t.Fatalf("failed to create item: %v", err)
+		}
+
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
+
+func testUserCreate(t *testing.T, s *models.Services) {
+	user := createUser(t, s, "alice@example.com")
+
+	if user.ID < 1 || user.Version != 1 {
+		t.Errorf("got ID %d and version %d, want a positive ID and version 1", user.ID, user.Version)
+	}
+
+	// Emails are compared without regard to case.
+	got, err := s.Users.GetByEmail("Alice@Example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.ID != user.ID || got.Email != user.Email || string(got.Password.Hash) != "hash" {
+		t.Errorf("got %+v, want %+v", got, user)
+	}
+
+	duplicate := &models.User{Email: "ALICE@example.com", Password: models.Password{Hash: []byte("hash")}}
+	if err := s.Users.Create(duplicate); err != models.ErrDuplicateEmail {
+		t.Errorf("got error %v, want %v", err, models.ErrDuplicateEmail)
+	}
+
+	if _, err := s.Users.GetByEmail("bob@example.com"); err != models.ErrRecordNotFound {
+		t.Errorf("GetByEmail: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+}
+
+func testUserGetForToken(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	token := models.GenerateToken(alice.ID, time.Hour, models.ScopeAuthentication)
+	expired := models.GenerateToken(alice.ID, -time.Hour, models.ScopeAuthentication)
+	other := models.GenerateToken(bob.ID, time.Hour, models.ScopeAuthentication)
+
+	for _, tok := range []*models.Token{token, expired, other} {
+		if err := s.Tokens.Create(tok); err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+	}
+
+	got, err := s.Users.GetForToken(models.ScopeAuthentication, token.Plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != alice.ID || got.Email != alice.Email {
+		t.Errorf("got %+v, want %+v", got, alice)
+	}
+
+	for name, tt := range map[string]struct{ scope, plaintext string }{
+		"expired":       {models.ScopeAuthentication, expired.Plaintext},
+		"other scope":   {"activation", token.Plaintext},
+		"unknown token": {models.ScopeAuthentication, models.GenerateToken(alice.ID, time.Hour, "").Plaintext},
+	} {
+		if _, err := s.Users.GetForToken(tt.scope, tt.plaintext); err != models.ErrRecordNotFound {
+			t.Errorf("%s: got error %v, want %v", name, err, models.ErrRecordNotFound)
+		}
+	}
+
+	if err := s.Tokens.DeleteAllForUser(models.ScopeAuthentication, alice.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.Users.GetForToken(models.ScopeAuthentication, token.Plaintext); err != models.ErrRecordNotFound {
+		t.Errorf("after DeleteAllForUser: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	if got, err := s.Users.GetForToken(models.ScopeAuthentication, other.Plaintext); err != nil || got.ID != bob.ID {
+		t.Errorf("got user %+v and error %v, want other users' tokens kept", got, err)
+	}
+}
+
+func testPermissionAddForUser(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	if err := s.Permissions.AddForUser(alice.ID, models.DefaultPermissions...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Codes the user already has, and unknown codes, are skipped.
+	if err := s.Permissions.AddForUser(alice.ID, models.PermissionFeedsRead, models.PermissionAdmin, "unknown"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := s.Permissions.GetAllForUser(alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slices.Sort(got)
+	want := models.Permissions{models.PermissionAdmin, models.PermissionFeedsRead, models.PermissionFeedsWrite}
+	if !slices.Equal(got, want) {
+		t.Errorf("got permissions %v, want %v", got, want)
+	}
+
+	got, err = s.Permissions.GetAllForUser(bob.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got permissions %v for another user, want none", got)
+	}
+}
+
+func testFolderCreate(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	folder := createFolder(t, s, alice.ID, "News")
+	if folder.ID < 1 || folder.Version != 1 {
+		t.Errorf("got ID %d and version %d, want a positive ID and version 1", folder.ID, folder.Version)
+	}
+
+	got, err := s.Folders.Get(folder.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "News" || got.UserID != alice.ID || got.Version != 1 || got.UnreadCount != 0 {
+		t.Errorf("got %+v, want %+v", got, folder)
+	}
+
+	duplicate := &models.Folder{UserID: alice.ID, Name: "News"}
+	if err := s.Folders.Create(duplicate); err != models.ErrDuplicateFolder {
+		t.Errorf("got error %v, want %v", err, models.ErrDuplicateFolder)
+	}
+
+	// Names only need to be unique per user.
+	createFolder(t, s, bob.ID, "News")
+
+	for name, tt := range map[string]struct{ id, userID int64 }{
+		"other user": {folder.ID, bob.ID},
+		"zero ID":    {0, alice.ID},
+		"missing":    {folder.ID + 1000, alice.ID},
+	} {
+		if _, err := s.Folders.Get(tt.id, tt.userID); err != models.ErrRecordNotFound {
+			t.Errorf("%s: got error %v, want %v", name, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func testFolderGetAllForUser(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	b := createFolder(t, s, alice.ID, "b")
+	a := createFolder(t, s, alice.ID, "A")
+	c := createFolder(t, s, alice.ID, "c")
+	createFolder(t, s, bob.ID, "Bob's")
+
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	createSubscription(t, s, alice.ID, feed.ID, b.ID)
+	createItems(t, s, feed.ID, time.Now().Add(-time.Hour), "one", "two")
+
+	folders, err := s.Folders.GetAllForUser(alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []int64
+	for _, folder := range folders {
+		got = append(got, folder.ID)
+	}
+
+	// Folders are sorted by name, ignoring case.
+	if want := []int64{a.ID, b.ID, c.ID}; !slices.Equal(got, want) {
+		t.Fatalf("got folders %v, want %v", got, want)
+	}
+
+	for i, want := range []int{0, 2, 0} {
+		if folders[i].UnreadCount != want {
+			t.Errorf("folder %q: got %d unread, want %d", folders[i].Name, folders[i].UnreadCount, want)
+		}
+	}
+}
+
+func testFolderUpdate(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	folder := createFolder(t, s, alice.ID, "News")
+	createFolder(t, s, alice.ID, "Tech")
+	stale := *folder
+
+	folder.Name = "Daily news"
+	if err := s.Folders.Update(folder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if folder.Version != 2 {
+		t.Errorf("got version %d, want 2", folder.Version)
+	}
+
+	stale.Name = "Stale"
+	if err := s.Folders.Update(&stale); err != models.ErrEditConflict {
+		t.Errorf("stale version: got error %v, want %v", err, models.ErrEditConflict)
+	}
+
+	taken := *folder
+	taken.Name = "Tech"
+	if err := s.Folders.Update(&taken); err != models.ErrDuplicateFolder {
+		t.Errorf("taken name: got error %v, want %v", err, models.ErrDuplicateFolder)
+	}
+
+	other := *folder
+	other.UserID = bob.ID
+	if err := s.Folders.Update(&other); err != models.ErrEditConflict {
+		t.Errorf("other user: got error %v, want %v", err, models.ErrEditConflict)
+	}
+
+	other.ID = 0
+	if err := s.Folders.Update(&other); err != models.ErrRecordNotFound {
+		t.Errorf("zero ID: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	got, _ := s.Folders.Get(folder.ID, alice.ID)
+	if got.Name != "Daily news" || got.Version != 2 {
+		t.Errorf("got %+v, want the first update to stand", got)
+	}
+}
+
+func testFolderDelete(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+
+	folder := createFolder(t, s, alice.ID, "News")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	subscription := createSubscription(t, s, alice.ID, feed.ID, folder.ID)
+
+	if err := s.Folders.Delete(folder.ID, bob.ID); err != models.ErrRecordNotFound {
+		t.Errorf("other user: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	if err := s.Folders.Delete(folder.ID, alice.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Subscriptions in the folder are kept, outside of any folder.
+	got, err := s.Subscriptions.Get(subscription.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.FolderID != 0 {
+		t.Errorf("got folder ID %d, want 0", got.FolderID)
+	}
+
+	for _, id := range []int64{folder.ID, 0} {
+		if err := s.Folders.Delete(id, alice.ID); err != models.ErrRecordNotFound {
+			t.Errorf("Delete(%d): got error %v, want %v", id, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func testSubscriptionCreate(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+	folder := createFolder(t, s, alice.ID, "News")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+
+	subscription := &models.Subscription{UserID: alice.ID, FeedID: feed.ID, Title: "Go", FolderID: folder.ID}
+	if err := s.Subscriptions.Create(subscription); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subscription.ID < 1 || subscription.Version != 1 {
+		t.Errorf("got ID %d and version %d, want a positive ID and version 1", subscription.ID, subscription.Version)
+	}
+
+	got, err := s.Subscriptions.Get(subscription.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.UserID != alice.ID || got.FeedID != feed.ID || got.Title != "Go" || got.FolderID != folder.ID || got.Version != 1 {
+		t.Errorf("got %+v, want %+v", got, subscription)
+	}
+
+	duplicate := &models.Subscription{UserID: alice.ID, FeedID: feed.ID}
+	if err := s.Subscriptions.Create(duplicate); err != models.ErrDuplicateSubscription {
+		t.Errorf("got error %v, want %v", err, models.ErrDuplicateSubscription)
+	}
+
+	// Other users can subscribe to the same feed.
+	createSubscription(t, s, bob.ID, feed.ID, 0)
+
+	for name, tt := range map[string]struct{ id, userID int64 }{
+		"other user": {subscription.ID, bob.ID},
+		"zero ID":    {0, alice.ID},
+		"missing":    {subscription.ID + 1000, alice.ID},
+	} {
+		if _, err := s.Subscriptions.Get(tt.id, tt.userID); err != models.ErrRecordNotFound {
+			t.Errorf("%s: got error %v, want %v", name, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func testSubscriptionGetAllForUser(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+	folder := createFolder(t, s, alice.ID, "News")
+
+	goBlog := createFeed(t, s.Feeds, "Go Blog", "en")
+	rustBlog := createFeed(t, s.Feeds, "Rust Blog", "en")
+	zigNews := createFeed(t, s.Feeds, "Zig News", "en")
+
+	rust := createSubscription(t, s, alice.ID, rustBlog.ID, 0)
+	zig := createSubscription(t, s, alice.ID, zigNews.ID, folder.ID)
+	createSubscription(t, s, bob.ID, goBlog.ID, 0)
+
+	// A custom title is sorted in place of the feed's.
+	goSub := &models.Subscription{UserID: alice.ID, FeedID: goBlog.ID, Title: "the go blog"}
+	if err := s.Subscriptions.Create(goSub); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	createItems(t, s, rustBlog.ID, time.Now().Add(-time.Hour), "one", "two", "three")
+
+	subscriptions, err := s.Subscriptions.GetAllForUser(alice.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []int64
+	for _, subscription := range subscriptions {
+		got = append(got, subscription.ID)
+	}
+
+	// Subscriptions outside of folders come first.
+	if want := []int64{rust.ID, goSub.ID, zig.ID}; !slices.Equal(got, want) {
+		t.Fatalf("got subscriptions %v, want %v", got, want)
+	}
+
+	if feed := subscriptions[0].Feed; feed == nil || feed.ID != rustBlog.ID || feed.Title != "Rust Blog" || feed.URL != rustBlog.URL {
+		t.Errorf("got feed %+v, want %+v", feed, rustBlog)
+	}
+
+	for i, want := range []int{3, 0, 0} {
+		if subscriptions[i].UnreadCount != want {
+			t.Errorf("subscription %d: got %d unread, want %d", subscriptions[i].ID, subscriptions[i].UnreadCount, want)
+		}
+	}
+}
+
+func testSubscriptionUpdate(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+	folder := createFolder(t, s, alice.ID, "News")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+
+	subscription := createSubscription(t, s, alice.ID, feed.ID, 0)
+	stale := *subscription
+
+	subscription.Title = "Go"
+	subscription.FolderID = folder.ID
+	if err := s.Subscriptions.Update(subscription); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subscription.Version != 2 {
+		t.Errorf("got version %d, want 2", subscription.Version)
+	}
+
+	stale.Title = "Stale"
+	if err := s.Subscriptions.Update(&stale); err != models.ErrEditConflict {
+		t.Errorf("stale version: got error %v, want %v", err, models.ErrEditConflict)
+	}
+
+	other := *subscription
+	other.UserID = bob.ID
+	if err := s.Subscriptions.Update(&other); err != models.ErrEditConflict {
+		t.Errorf("other user: got error %v, want %v", err, models.ErrEditConflict)
+	}
+
+	other.ID = 0
+	if err := s.Subscriptions.Update(&other); err != models.ErrRecordNotFound {
+		t.Errorf("zero ID: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	got, _ := s.Subscriptions.Get(subscription.ID, alice.ID)
+	if got.Title != "Go" || got.FolderID != folder.ID || got.Version != 2 {
+		t.Errorf("got %+v, want the first update to stand", got)
+	}
+
+	// A zero folder ID moves the subscription out of its folder.
+	subscription.FolderID = 0
+	if err := s.Subscriptions.Update(subscription); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ = s.Subscriptions.Get(subscription.ID, alice.ID)
+	if got.FolderID != 0 {
+		t.Errorf("got folder ID %d, want 0", got.FolderID)
+	}
+}
+
+func testSubscriptionDelete(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	subscription := createSubscription(t, s, alice.ID, feed.ID, 0)
+
+	if err := s.Subscriptions.Delete(subscription.ID, bob.ID); err != models.ErrRecordNotFound {
+		t.Errorf("other user: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	if err := s.Subscriptions.Delete(subscription.ID, alice.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.Subscriptions.Get(subscription.ID, alice.ID); err != models.ErrRecordNotFound {
+		t.Errorf("Get after Delete: got error %v, want %v", err, models.ErrRecordNotFound)
+	}
+
+	for _, id := range []int64{subscription.ID, 0} {
+		if err := s.Subscriptions.Delete(id, alice.ID); err != models.ErrRecordNotFound {
+			t.Errorf("Delete(%d): got error %v, want %v", id, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func testItemUpsert(t *testing.T, s *models.Services) {
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	item := &models.Item{
+		FeedID:      feed.ID,
+		GUID:        "guid-1",
+		Title:       "First",
+		Link:        "https://example.com/first",
+		Author:      "Gopher",
+		Content:     "<p>Hello</p>",
+		Summary:     "Hello",
+		PublishedAt: published,
+		UpdatedAt:   published,
+	}
+	if err := s.Items.Upsert(item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.ID < 1 {
+		t.Fatalf("got ID %d, want a positive ID", item.ID)
+	}
+
+	got, err := s.Items.Get(item.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.FeedID != feed.ID || got.GUID != item.GUID || got.Title != item.Title || got.Link != item.Link ||
+		got.Author != item.Author || got.Content != item.Content || got.Summary != item.Summary ||
+		!got.PublishedAt.Equal(published) || got.ContentHash != item.ContentHash {
+		t.Errorf("got %+v, want %+v", got, item)
+	}
+
+	// Storing an unchanged copy is a no-op.
+	unchanged := *item
+	unchanged.ID = 0
+	if err := s.Items.Upsert(&unchanged); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if unchanged.ID != 0 {
+		t.Errorf("got ID %d for an unchanged item, want 0", unchanged.ID)
+	}
+
+	changed := *item
+	changed.ID = 0
+	changed.Title = "First, edited"
+	if err := s.Items.Upsert(&changed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed.ID != item.ID {
+		t.Errorf("got ID %d for a changed item, want %d", changed.ID, item.ID)
+	}
+
+	got, _ = s.Items.Get(item.ID)
+	if got.Title != "First, edited" {
+		t.Errorf("got title %q, want the edit stored", got.Title)
+	}
+
+	for _, id := range []int64{0, item.ID + 1000} {
+		if _, err := s.Items.Get(id); err != models.ErrRecordNotFound {
+			t.Errorf("Get(%d): got error %v, want %v", id, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func testItemGetAllForFeed(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	other := createFeed(t, s.Feeds, "Rust Blog", "en")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, s, feed.ID, start, "one", "two", "three")
+	createItems(t, s, other.ID, start, "other")
+
+	filters := models.CursorFilters{PageSize: 2}
+
+	items, metadata, err := s.Items.GetAllForFeed(feed.ID, alice.ID, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{ids[2], ids[1]}; !slices.Equal(got, want) {
+		t.Errorf("first page: got items %v, want %v", got, want)
+	}
+	if metadata.PageSize != 2 || metadata.NextCursor == "" {
+		t.Fatalf("got metadata %+v, want a next cursor", metadata)
+	}
+
+	// Items of feeds the user hasn't subscribed to have the default state.
+	for _, item := range items {
+		if item.State == nil || item.State.Read || item.State.Starred {
+			t.Errorf("item %d: got state %+v, want unread and unstarred", item.ID, item.State)
+		}
+	}
+
+	filters.After, err = models.DecodeCursor(metadata.NextCursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, metadata, err = s.Items.GetAllForFeed(feed.ID, alice.ID, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{ids[0]}; !slices.Equal(got, want) {
+		t.Errorf("last page: got items %v, want %v", got, want)
+	}
+	if metadata.NextCursor != "" {
+		t.Errorf("got next cursor %q on the last page, want none", metadata.NextCursor)
+	}
+}
+
+func testItemGetAllForFolder(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+	folder := createFolder(t, s, alice.ID, "News")
+
+	goBlog := createFeed(t, s.Feeds, "Go Blog", "en")
+	rustBlog := createFeed(t, s.Feeds, "Rust Blog", "en")
+	zigNews := createFeed(t, s.Feeds, "Zig News", "en")
+
+	createSubscription(t, s, alice.ID, goBlog.ID, folder.ID)
+	createSubscription(t, s, alice.ID, rustBlog.ID, folder.ID)
+	createSubscription(t, s, alice.ID, zigNews.ID, 0)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	goIDs := createItems(t, s, goBlog.ID, start, "one", "two")
+	rustIDs := createItems(t, s, rustBlog.ID, start.Add(30*time.Minute), "three")
+	createItems(t, s, zigNews.ID, start, "four")
+
+	items, _, err := s.Items.GetAllForFolder(folder.ID, alice.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{goIDs[1], rustIDs[0], goIDs[0]}; !slices.Equal(got, want) {
+		t.Errorf("got items %v, want %v", got, want)
+	}
+
+	items, _, err = s.Items.GetAllForFolder(folder.ID, bob.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("got %d items in another user's folder, want none", len(items))
+	}
+}
+
+func testItemSearch(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	other := createFeed(t, s.Feeds, "Rust Blog", "en")
+	createSubscription(t, s, alice.ID, feed.ID, 0)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, s, feed.ID, start,
+		"A faster compiler ships today, <b>with</b> fixes & more.",
+		"Nothing to see here.",
+	)
+	createItems(t, s, other.ID, start, "Another compiler, in a feed alice doesn't read.")
+
+	filters := models.Filters{Page: 1, PageSize: 20, Sort: "-rank", SortSafelist: []string{"-rank"}}
+
+	results, metadata, err := s.Items.Search(alice.ID, models.ItemSearch{Query: "compiler"}, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != ids[0] {
+		t.Fatalf("got %d results, want item %d alone", len(results), ids[0])
+	}
+	if metadata.TotalRecords != 1 {
+		t.Errorf("got %d total records, want 1", metadata.TotalRecords)
+	}
+
+	headline := results[0].Headline
+	if !strings.Contains(headline, "<mark>compiler</mark>") {
+		t.Errorf("got headline %q, want the match highlighted", headline)
+	}
+	if strings.Contains(headline, "<b>") || strings.Contains(headline, " & ") {
+		t.Errorf("got headline %q, want no markup but the highlights", headline)
+	}
+
+	for name, search := range map[string]models.ItemSearch{
+		"no words":         {Query: ""},
+		"no match":         {Query: "interpreter"},
+		"published after":  {Query: "compiler", PublishedAfter: start.Add(time.Minute)},
+		"published before": {Query: "compiler", PublishedBefore: start},
+	} {
+		results, _, err := s.Items.Search(alice.ID, search, filters)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(results) != 0 {
+			t.Errorf("%s: got %d results, want none", name, len(results))
+		}
+	}
+}
+
+func testItemStateUpdate(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	other := createFeed(t, s.Feeds, "Rust Blog", "en")
+	createSubscription(t, s, alice.ID, feed.ID, 0)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, s, feed.ID, start, "one", "two")
+	otherIDs := createItems(t, s, other.ID, start, "three")
+
+	yes := true
+
+	// Items in feeds the user doesn't subscribe to are skipped.
+	n, err := s.ItemStates.Update(alice.ID, []int64{ids[0], otherIDs[0]}, models.ItemStateUpdate{Read: &yes})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("got %d items updated, want 1", n)
+	}
+
+	// Flags left nil keep their value.
+	n, err = s.ItemStates.Update(alice.ID, ids, models.ItemStateUpdate{Starred: &yes})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d items updated, want 2", n)
+	}
+
+	items, _, err := s.Items.GetAllForFeed(feed.ID, alice.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[int64]models.ItemState{
+		ids[0]: {Read: true, Starred: true},
+		ids[1]: {Read: false, Starred: true},
+	}
+	for _, item := range items {
+		if *item.State != want[item.ID] {
+			t.Errorf("item %d: got state %+v, want %+v", item.ID, *item.State, want[item.ID])
+		}
+	}
+}
+
+func testItemStateMarkSubscriptionRead(t *testing.T, s *models.Services) {
+	alice := createUser(t, s, "alice@example.com")
+	bob := createUser(t, s, "bob@example.com")
+	feed := createFeed(t, s.Feeds, "Go Blog", "en")
+	subscription := createSubscription(t, s, alice.ID, feed.ID, 0)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, s, feed.ID, start, "one", "two", "three")
+
+	// An item explicitly marked unread is marked read by a newer watermark.
+	no := false
+	if _, err := s.ItemStates.Update(alice.ID, ids[:1], models.ItemStateUpdate{Read: &no}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.ItemStates.MarkSubscriptionRead(subscription.ID, alice.ID, start.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An older watermark doesn't undo the newer one.
+	if err := s.ItemStates.MarkSubscriptionRead(subscription.ID, alice.ID, start); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, _, err := s.Items.GetAllForFeed(feed.ID, alice.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[int64]bool{ids[0]: true, ids[1]: true, ids[2]: false}
+	for _, item := range items {
+		if item.State.Read != want[item.ID] {
+			t.Errorf("item %d: got read %v, want %v", item.ID, item.State.Read, want[item.ID])
+		}
+	}
+
+	got, _ := s.Subscriptions.GetAllForUser(alice.ID)
+	if len(got) != 1 || got[0].UnreadCount != 1 {
+		t.Errorf("got subscriptions %+v, want 1 unread item", got)
+	}
+
+	for name, tt := range map[string]struct{ id, userID int64 }{
+		"other user": {subscription.ID, bob.ID},
+		"zero ID":    {0, alice.ID},
+		"missing":    {subscription.ID + 1000, alice.ID},
+	} {
+		if err := s.ItemStates.MarkSubscriptionRead(tt.id, tt.userID, start); err != models.ErrRecordNotFound {
+			t.Errorf("%s: got error %v, want %v", name, err, models.ErrRecordNotFound)
+		}
+	}
+}
+
+func itemIDs(items []*models.Item) []int64 {
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
@@ -0,0 +1,23 @@
+package models
+
+import "slices"
+
+const (
+	PermissionFeedsRead  = "feeds:read"
+	PermissionFeedsWrite = "feeds:write"
+	PermissionAdmin      = "admin"
+)
+
+// DefaultPermissions are granted to every newly registered user.
+var DefaultPermissions = []string{PermissionFeedsRead, PermissionFeedsWrite}
+
+type Permissions []string
+
+func (p Permissions) Include(code string) bool {
+	return slices.Contains(p, code)
+}
+
+type PermissionService interface {
+	GetAllForUser(userID int64) (Permissions, error)
+	AddForUser(userID int64, codes ...string) error
+}
@@ -0,0 +1,25 @@
+package models
+
+import "context"
+
+// Services are the storage services of one backend, all backed by the same
+// database or transaction.
+type Services struct {
+	Feeds         FeedService
+	Items         ItemService
+	Users         UserService
+	Tokens        TokenService
+	Permissions   PermissionService
+	Subscriptions SubscriptionService
+	ItemStates    ItemStateService
+	Folders       FolderService
+}
+
+// Transactor groups writes that must succeed or fail together.
+type Transactor interface {
+	// WithTx calls fn with services bound to a new transaction, committing
+	// it if fn returns nil and rolling it back otherwise. The error from fn
+	// is returned as is. fn may be called again should the transaction have
+	// to be retried, so it must be safe to run more than once.
+	WithTx(ctx context.Context, fn func(tx *Services) error) error
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+// Subscription links a user to a feed from the shared catalog. Title, when
+// set, overrides the feed's own title for that user. A zero FolderID means the
+// subscription is not in a folder.
+type Subscription struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	FeedID    int64     `json:"feed_id"`
+	Title     string    `json:"title,omitzero"`
+	FolderID  int64     `json:"folder_id,omitzero"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int32     `json:"version"`
+	Feed      *Feed     `json:"feed,omitzero"`
+
+	UnreadCount int `json:"unread_count"`
+}
+
+type SubscriptionService interface {
+	Create(subscription *Subscription) error
+	Get(id int64, userID int64) (*Subscription, error)
+	GetAllForUser(userID int64) ([]*Subscription, error)
+	Update(subscription *Subscription) error
+	Delete(id int64, userID int64) error
+}
+
+func ValidateSubscription(v *validator.Validator, subscription *Subscription) {
+	v.Check(len(subscription.Title) <= 500, "title", "must not be more than 500 bytes long")
+	v.Check(subscription.FolderID >= 0, "folder_id", "must not be negative")
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+const (
+	ScopeAuthentication = "authentication"
+)
+
+// Token is a bearer token. Only the SHA-256 hash is stored; the plaintext is
+// returned to the client once, when the token is created.
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     string    `json:"-"`
+}
+
+type TokenService interface {
+	Create(token *Token) error
+	DeleteAllForUser(scope string, userID int64) error
+}
+
+func GenerateToken(userID int64, ttl time.Duration, scope string) *Token {
+	token := &Token{
+		Plaintext: rand.Text(),
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+	}
+
+	token.Hash = HashToken(token.Plaintext)
+
+	return token
+}
+
+func HashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(tokenPlaintext != "", "token", "must be provided")
+	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+}
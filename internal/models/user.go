@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grodier/rss-app/internal/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AnonymousUser is the user for requests that carry no authentication token.
+var AnonymousUser = &User{}
+
+type User struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Email     string    `json:"email"`
+	Password  Password  `json:"-"`
+	Version   int32     `json:"-"`
+}
+
+type UserService interface {
+	Create(user *User) error
+	GetByEmail(email string) (*User, error)
+	GetForToken(scope, tokenPlaintext string) (*User, error)
+}
+
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
+// Password holds a bcrypt hash. The plaintext should be checked with
+// ValidatePasswordPlaintext before calling Set, as bcrypt rejects input longer
+// than 72 bytes.
+type Password struct {
+	Hash []byte
+}
+
+func (p *Password) Set(plaintextPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	if err != nil {
+		return err
+	}
+
+	p.Hash = hash
+
+	return nil
+}
+
+func (p *Password) Matches(plaintextPassword string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(p.Hash, []byte(plaintextPassword))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+}
+
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
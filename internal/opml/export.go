@@ -0,0 +1,60 @@
+package opml
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// Export builds an OPML 2.0 document listing the subscriptions, with those in
+// a folder nested under an outline named after it. Subscriptions must have
+// their Feed set. Folders without subscriptions are kept so that importing
+// the document elsewhere recreates them.
+func Export(title string, folders []*models.Folder, subscriptions []*models.Subscription) *Document {
+	doc := &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(http.TimeFormat),
+		},
+		Body: Body{Outlines: []*Outline{}},
+	}
+
+	byFolder := make(map[int64]*Outline, len(folders))
+	for _, folder := range folders {
+		byFolder[folder.ID] = &Outline{Text: folder.Name, Title: folder.Name}
+	}
+
+	for _, subscription := range subscriptions {
+		outline := feedOutline(subscription)
+
+		if parent, ok := byFolder[subscription.FolderID]; ok {
+			parent.Outlines = append(parent.Outlines, outline)
+			continue
+		}
+
+		doc.Body.Outlines = append(doc.Body.Outlines, outline)
+	}
+
+	for _, folder := range folders {
+		doc.Body.Outlines = append(doc.Body.Outlines, byFolder[folder.ID])
+	}
+
+	return doc
+}
+
+func feedOutline(subscription *models.Subscription) *Outline {
+	title := subscription.Title
+	if title == "" {
+		title = subscription.Feed.Title
+	}
+
+	return &Outline{
+		Text:    title,
+		Title:   title,
+		Type:    "rss",
+		XMLURL:  subscription.Feed.URL,
+		HTMLURL: subscription.Feed.SiteURL,
+	}
+}
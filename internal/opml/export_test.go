@@ -0,0 +1,60 @@
+package opml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestExport(t *testing.T) {
+	folders := []*models.Folder{
+		{ID: 1, Name: "Empty"},
+		{ID: 2, Name: "Tech"},
+	}
+
+	subscriptions := []*models.Subscription{
+		{FeedID: 1, Feed: &models.Feed{Title: "One", URL: "https://one.example.com/feed", SiteURL: "https://one.example.com/"}},
+		{FeedID: 2, FolderID: 2, Title: "Renamed", Feed: &models.Feed{Title: "Two", URL: "https://two.example.com/feed", SiteURL: "https://two.example.com/"}},
+	}
+
+	doc := Export("Subscriptions", folders, subscriptions)
+
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The exported document must be readable by our own importer.
+	parsed, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse exported document: %v\n%s", err, data)
+	}
+
+	if parsed.Version != "2.0" || parsed.Head.Title != "Subscriptions" || parsed.Head.DateCreated == "" {
+		t.Errorf("got version %q and head %+v", parsed.Version, parsed.Head)
+	}
+
+	outlines := parsed.Body.Outlines
+	if len(outlines) != 3 {
+		t.Fatalf("got %d top-level outlines, want 3", len(outlines))
+	}
+
+	one := outlines[0]
+	if one.Text != "One" || one.XMLURL != "https://one.example.com/feed" || one.HTMLURL != "https://one.example.com/" {
+		t.Errorf("got outline %+v", one)
+	}
+
+	if empty := outlines[1]; empty.Text != "Empty" || empty.IsFeed() || len(empty.Outlines) != 0 {
+		t.Errorf("got outline %+v, want the empty folder", empty)
+	}
+
+	tech := outlines[2]
+	if tech.Text != "Tech" || len(tech.Outlines) != 1 {
+		t.Fatalf("got outline %+v, want Tech with one feed", tech)
+	}
+	if two := tech.Outlines[0]; two.Text != "Renamed" || two.Title != "Renamed" || two.XMLURL != "https://two.example.com/feed" {
+		t.Errorf("got outline %+v, want the subscription title to be used", two)
+	}
+}
@@ -0,0 +1,364 @@
+package opml
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+type Status string
+
+const (
+	StatusCreated Status = "created"
+	StatusExists  Status = "exists"
+	StatusInvalid Status = "invalid"
+)
+
+// Result reports what happened to a single feed outline. Errors is only set
+// for invalid outlines.
+type Result struct {
+	Title  string            `json:"title"`
+	URL    string            `json:"url"`
+	Folder string            `json:"folder,omitzero"`
+	Status Status            `json:"status"`
+	Errors map[string]string `json:"errors,omitzero"`
+}
+
+// fetchWorkers bounds how many new feeds are fetched at once.
+const fetchWorkers = 4
+
+// FeedFetcher retrieves and parses a remote feed.
+type FeedFetcher interface {
+	Fetch(ctx context.Context, url string) (*fetcher.Response, error)
+}
+
+// Importer adds the feeds listed in an OPML document to the catalog and,
+// for a user, subscribes them to each one.
+type Importer struct {
+	// FeedService tells feeds already in the catalog from new ones. New
+	// feeds are fetched with FeedFetcher and only added once they parse, as
+	// when subscribing to a single feed, so the catalog records what the
+	// feed says about itself rather than what the outline claimed.
+	FeedService models.FeedService
+	FeedFetcher FeedFetcher
+
+	// FetchTimeout, when positive, bounds fetching all of the new feeds.
+	// Outlines whose feed hasn't been fetched by then are reported invalid.
+	FetchTimeout time.Duration
+
+	// Transactor writes each outline's feed, folder and subscription in a
+	// transaction of its own, so that a failing import leaves no outline
+	// half imported.
+	Transactor models.Transactor
+}
+
+// Import adds every feed outline in doc. Outlines that can't be imported,
+// including feeds that can't be fetched, are reported as invalid without
+// stopping the import; an error is only returned when a service fails or ctx
+// is done.
+//
+// When userID is non-zero the user is subscribed to each feed, and feeds
+// nested in a category are put in the folder of the same name, creating it
+// if needed. Folders are not nested, so outlines in deeper categories use the
+// top-level category. When userID is zero only the catalog is updated.
+func (im *Importer) Import(ctx context.Context, doc *Document, userID int64) ([]*Result, error) {
+	run := &importRun{
+		Importer: im,
+		userID:   userID,
+		folders:  map[string]int64{},
+		results:  []*Result{},
+	}
+
+	if userID != 0 {
+		err := im.Transactor.WithTx(ctx, func(tx *models.Services) error {
+			folders, err := tx.Folders.GetAllForUser(userID)
+			if err != nil {
+				return err
+			}
+
+			for _, folder := range folders {
+				run.folders[folder.Name] = folder.ID
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	run.walk(doc.Body.Outlines, "")
+
+	if err := run.fetchNew(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, e := range run.entries {
+		if e.result.Status == StatusInvalid {
+			continue
+		}
+
+		if err := run.importFeed(ctx, e); err != nil {
+			return nil, err
+		}
+	}
+
+	return run.results, nil
+}
+
+type importRun struct {
+	*Importer
+
+	userID  int64
+	folders map[string]int64
+	results []*Result
+	entries []*entry
+}
+
+// entry is a valid feed outline on its way into the catalog. feed starts out
+// as described by the outline and is replaced by the fetched feed for URLs
+// that are new to the catalog.
+type entry struct {
+	result *Result
+	feed   *models.Feed
+	folder string
+}
+
+// walk validates every feed outline, recording a result for each and an
+// entry for those that can be imported.
+func (run *importRun) walk(outlines []*Outline, folder string) {
+	for _, outline := range outlines {
+		if !outline.IsFeed() {
+			name := folder
+			if name == "" {
+				name = outline.Name()
+			}
+
+			run.walk(outline.Outlines, name)
+			continue
+		}
+
+		result, feed := run.validate(outline, folder)
+
+		run.results = append(run.results, result)
+		if result.Status != StatusInvalid {
+			run.entries = append(run.entries, &entry{result: result, feed: feed, folder: folder})
+		}
+	}
+}
+
+func (run *importRun) validate(outline *Outline, folder string) (*Result, *models.Feed) {
+	feed := &models.Feed{
+		Title:   outline.Name(),
+		URL:     strings.TrimSpace(outline.XMLURL),
+		SiteURL: strings.TrimSpace(outline.HTMLURL),
+	}
+
+	if feed.SiteURL == "" {
+		feed.SiteURL = siteURL(feed.URL)
+	}
+
+	result := &Result{Title: feed.Title, URL: feed.URL, Folder: folder}
+
+	v := validator.NewValidator()
+
+	models.ValidateFeed(v, feed)
+
+	if run.userID != 0 && folder != "" {
+		fv := validator.NewValidator()
+
+		if models.ValidateFolder(fv, &models.Folder{Name: folder}); !fv.Valid() {
+			v.AddError("folder", fv.Errors["name"])
+		}
+	}
+
+	if !v.Valid() {
+		result.Status = StatusInvalid
+		result.Errors = v.Errors
+	}
+
+	return result, feed
+}
+
+// fetchNew fetches the feeds that aren't in the catalog yet, a few at a
+// time, and validates what they say about themselves. Outlines whose feed
+// can't be fetched or doesn't validate are marked invalid.
+func (run *importRun) fetchNew(ctx context.Context) error {
+	var fetching []*entry
+
+	for _, e := range run.entries {
+		_, err := run.FeedService.GetByURL(ctx, e.feed.URL)
+		switch {
+		case err == models.ErrRecordNotFound:
+			fetching = append(fetching, e)
+		case err != nil:
+			return err
+		}
+	}
+
+	if len(fetching) == 0 {
+		return nil
+	}
+
+	fetchCtx := ctx
+	if run.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, run.FetchTimeout)
+		defer cancel()
+	}
+
+	jobs := make(chan *entry)
+
+	var wg sync.WaitGroup
+	for range min(fetchWorkers, len(fetching)) {
+		wg.Go(func() {
+			for e := range jobs {
+				run.fetch(fetchCtx, e)
+			}
+		})
+	}
+
+	for _, e := range fetching {
+		jobs <- e
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Running out of FetchTimeout only fails the outlines still being
+	// fetched, but the import as a whole stops if its own context is done.
+	return ctx.Err()
+}
+
+func (run *importRun) fetch(ctx context.Context, e *entry) {
+	res, err := run.FeedFetcher.Fetch(ctx, e.feed.URL)
+	if err != nil {
+		e.result.Status = StatusInvalid
+		e.result.Errors = map[string]string{"url": fetcher.ErrorMessage(err)}
+		return
+	}
+
+	feed := res.Result.Feed
+	feed.URL = e.feed.URL
+
+	// Fall back to the outline for what the feed leaves out.
+	if feed.Title == "" {
+		feed.Title = e.feed.Title
+	}
+	if feed.SiteURL == "" {
+		feed.SiteURL = e.feed.SiteURL
+	}
+
+	v := validator.NewValidator()
+
+	if models.ValidateFeed(v, feed); !v.Valid() {
+		e.result.Status = StatusInvalid
+		e.result.Errors = v.Errors
+		return
+	}
+
+	e.feed = feed
+}
+
+func (run *importRun) importFeed(ctx context.Context, e *entry) error {
+	err := run.save(ctx, e.result, e.feed, e.folder)
+	if err == models.ErrDuplicateURL {
+		// Another request added the feed since it was looked up.
+		err = run.save(ctx, e.result, e.feed, e.folder)
+	}
+
+	switch {
+	case err == nil:
+	case err == models.ErrDuplicateSubscription:
+		e.result.Status = StatusExists
+	default:
+		return err
+	}
+
+	return nil
+}
+
+// save adds the feed to the catalog unless it is already there and, for a
+// user, subscribes them to it in the given folder, all in one transaction.
+// result.Status is set to whether anything was created.
+func (run *importRun) save(ctx context.Context, result *Result, feed *models.Feed, folder string) error {
+	var created *models.Folder
+
+	err := run.Transactor.WithTx(ctx, func(tx *models.Services) error {
+		created = nil
+
+		// A failed statement aborts a Postgres transaction, so the feed is
+		// looked up first rather than created and looked up on conflict.
+		stored, err := tx.Feeds.GetByURL(ctx, feed.URL)
+		switch {
+		case err == nil:
+			result.Status = StatusExists
+		case err == models.ErrRecordNotFound:
+			result.Status = StatusCreated
+			stored = feed
+			err = tx.Feeds.Create(ctx, stored)
+		}
+		if err != nil {
+			return err
+		}
+
+		if run.userID == 0 {
+			return nil
+		}
+
+		subscription := &models.Subscription{UserID: run.userID, FeedID: stored.ID}
+
+		// Keep the name the user gave the feed in their previous reader.
+		if result.Title != stored.Title {
+			subscription.Title = result.Title
+		}
+
+		if folder != "" {
+			id, ok := run.folders[folder]
+			if !ok {
+				created = &models.Folder{UserID: run.userID, Name: folder}
+				if err := tx.Folders.Create(created); err != nil {
+					return err
+				}
+				id = created.ID
+			}
+
+			subscription.FolderID = id
+		}
+
+		err = tx.Subscriptions.Create(subscription)
+		if err != nil {
+			return err
+		}
+
+		result.Status = StatusCreated
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Folders are only remembered once their transaction has committed.
+	if created != nil {
+		run.folders[folder] = created.ID
+	}
+
+	return nil
+}
+
+// siteURL derives a site address from a feed URL, for outlines that don't
+// carry an htmlUrl.
+func siteURL(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+}
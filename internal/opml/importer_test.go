@@ -0,0 +1,372 @@
+package opml
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/feedparser"
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// The fakes below keep just enough state in memory to exercise the importer;
+// methods it doesn't call are left unimplemented.
+
+type fakeFeedService struct {
+	models.FeedService
+	feeds  map[string]*models.Feed
+	nextID int64
+}
+
+func (f *fakeFeedService) Create(ctx context.Context, feed *models.Feed) error {
+	if _, ok := f.feeds[feed.URL]; ok {
+		return models.ErrDuplicateURL
+	}
+	f.nextID++
+	feed.ID = f.nextID
+	f.feeds[feed.URL] = feed
+	return nil
+}
+
+func (f *fakeFeedService) GetByURL(ctx context.Context, url string) (*models.Feed, error) {
+	feed, ok := f.feeds[url]
+	if !ok {
+		return nil, models.ErrRecordNotFound
+	}
+	return feed, nil
+}
+
+type fakeFolderService struct {
+	models.FolderService
+	folders []*models.Folder
+}
+
+func (f *fakeFolderService) Create(folder *models.Folder) error {
+	folder.ID = int64(len(f.folders) + 1)
+	folder.CreatedAt = time.Now()
+	f.folders = append(f.folders, folder)
+	return nil
+}
+
+func (f *fakeFolderService) GetAllForUser(userID int64) ([]*models.Folder, error) {
+	return f.folders, nil
+}
+
+type fakeSubscriptionService struct {
+	models.SubscriptionService
+	subscriptions []*models.Subscription
+	err           error
+}
+
+func (f *fakeSubscriptionService) Create(subscription *models.Subscription) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, s := range f.subscriptions {
+		if s.UserID == subscription.UserID && s.FeedID == subscription.FeedID {
+			return models.ErrDuplicateSubscription
+		}
+	}
+	f.subscriptions = append(f.subscriptions, subscription)
+	return nil
+}
+
+// fakeFetcher serves the configured feeds, fails for the configured errors
+// and otherwise returns a feed that advertises no metadata. URLs in slow
+// block until ctx is done.
+type fakeFetcher struct {
+	feeds map[string]*models.Feed
+	errs  map[string]error
+	slow  map[string]bool
+
+	mu      sync.Mutex
+	fetched []string
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, url string) (*fetcher.Response, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, url)
+	f.mu.Unlock()
+
+	if f.slow[url] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err := f.errs[url]; err != nil {
+		return nil, err
+	}
+
+	feed := &models.Feed{}
+	if configured, ok := f.feeds[url]; ok {
+		*feed = *configured
+	}
+
+	return &fetcher.Response{URL: url, Result: &feedparser.Result{Feed: feed}}, nil
+}
+
+// fakeTransactor calls fn with the fakes, which can't roll back, so it counts
+// the transactions that would have been rolled back instead.
+type fakeTransactor struct {
+	services  *models.Services
+	rollbacks int
+}
+
+func (f *fakeTransactor) WithTx(ctx context.Context, fn func(tx *models.Services) error) error {
+	err := fn(f.services)
+	if err != nil {
+		f.rollbacks++
+	}
+	return err
+}
+
+func newTestImporter() (*Importer, *fakeFeedService, *fakeFolderService, *fakeSubscriptionService) {
+	feeds := &fakeFeedService{feeds: map[string]*models.Feed{}}
+	folders := &fakeFolderService{}
+	subscriptions := &fakeSubscriptionService{}
+
+	return &Importer{
+		FeedService: feeds,
+		FeedFetcher: &fakeFetcher{},
+		Transactor: &fakeTransactor{services: &models.Services{
+			Feeds:         feeds,
+			Folders:       folders,
+			Subscriptions: subscriptions,
+		}},
+	}, feeds, folders, subscriptions
+}
+
+func TestImporter_Import(t *testing.T) {
+	im, feeds, folders, subscriptions := newTestImporter()
+
+	// One feed is already in the catalog under a different title.
+	feeds.Create(context.Background(), &models.Feed{Title: "Feed Two", URL: "https://two.example.com/rss.xml", SiteURL: "https://two.example.com/"})
+
+	// And the user already has the Tech folder.
+	folders.Create(&models.Folder{UserID: 1, Name: "Tech"})
+
+	doc, err := Parse(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results, err := im.Import(context.Background(), doc, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		url    string
+		folder string
+		status Status
+	}{
+		{"https://one.example.com/feed", "", StatusCreated},
+		{"https://two.example.com/rss.xml", "Tech", StatusCreated},
+		{"https://three.example.com/atom.xml", "Tech", StatusCreated},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+
+	for i, w := range want {
+		if results[i].URL != w.url || results[i].Folder != w.folder || results[i].Status != w.status {
+			t.Errorf("result %d: got %+v, want %+v", i, results[i], w)
+		}
+	}
+
+	if len(folders.folders) != 1 {
+		t.Errorf("got %d folders, want the existing folder to be reused", len(folders.folders))
+	}
+
+	if len(subscriptions.subscriptions) != 3 {
+		t.Fatalf("got %d subscriptions, want 3", len(subscriptions.subscriptions))
+	}
+	if got := subscriptions.subscriptions[1]; got.FolderID != 1 || got.Title != "Two & Co" {
+		t.Errorf("got subscription %+v, want folder 1 and the OPML title", got)
+	}
+	if got := subscriptions.subscriptions[0]; got.Title != "" {
+		t.Errorf("got title %q, want no override when it matches the feed", got.Title)
+	}
+
+	if feed := feeds.feeds["https://three.example.com/atom.xml"]; feed.SiteURL != "https://three.example.com/" {
+		t.Errorf("got site url %q, want it derived from the feed url", feed.SiteURL)
+	}
+
+	// Importing again reports everything as already existing.
+	results, err = im.Import(context.Background(), doc, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusExists {
+			t.Errorf("got status %q for %s on second import, want %q", result.Status, result.URL, StatusExists)
+		}
+	}
+}
+
+func TestImporter_Import_Invalid(t *testing.T) {
+	im, _, _, subscriptions := newTestImporter()
+
+	doc, err := Parse(strings.NewReader(`<opml version="2.0"><body>
+		<outline xmlUrl="https://untitled.example.com/feed"/>
+		<outline text="Relative" xmlUrl="/feed.xml"/>
+		<outline text="` + strings.Repeat("f", 101) + `">
+			<outline text="Long folder" xmlUrl="https://long.example.com/feed"/>
+		</outline>
+		<outline text="Good" xmlUrl="https://good.example.com/feed"/>
+	</body></opml>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results, err := im.Import(context.Background(), doc, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		status Status
+		field  string
+	}{
+		{StatusInvalid, "title"},
+		{StatusInvalid, "url"},
+		{StatusInvalid, "folder"},
+		{StatusCreated, ""},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+
+	for i, w := range want {
+		if results[i].Status != w.status {
+			t.Errorf("result %d: got status %q, want %q", i, results[i].Status, w.status)
+		}
+		if w.field != "" && results[i].Errors[w.field] == "" {
+			t.Errorf("result %d: got errors %v, want one for %q", i, results[i].Errors, w.field)
+		}
+	}
+
+	if len(subscriptions.subscriptions) != 1 {
+		t.Errorf("got %d subscriptions, want 1", len(subscriptions.subscriptions))
+	}
+}
+
+func TestImporter_Import_Fetch(t *testing.T) {
+	im, feeds, _, subscriptions := newTestImporter()
+	im.FetchTimeout = 50 * time.Millisecond
+
+	feeds.Create(context.Background(), &models.Feed{Title: "Known", URL: "https://known.example.com/feed", SiteURL: "https://known.example.com/"})
+
+	ff := &fakeFetcher{
+		feeds: map[string]*models.Feed{
+			"https://new.example.com/feed": {Title: "New Feed", SiteURL: "https://www.new.example.com/"},
+		},
+		errs: map[string]error{
+			"https://html.example.com/": feedparser.ErrUnknownFormat,
+		},
+		slow: map[string]bool{
+			"https://slow.example.com/feed": true,
+		},
+	}
+	im.FeedFetcher = ff
+
+	doc, err := Parse(strings.NewReader(`<opml version="2.0"><body>
+		<outline text="Known" xmlUrl="https://known.example.com/feed"/>
+		<outline text="My name for it" xmlUrl="https://new.example.com/feed"/>
+		<outline text="Not a feed" xmlUrl="https://html.example.com/"/>
+		<outline text="Slow" xmlUrl="https://slow.example.com/feed"/>
+	</body></opml>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results, err := im.Import(context.Background(), doc, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		status Status
+		err    string
+	}{
+		{StatusCreated, ""},
+		{StatusCreated, ""},
+		{StatusInvalid, "does not point to a supported feed format"},
+		{StatusInvalid, "took too long to respond"},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+
+	for i, w := range want {
+		if results[i].Status != w.status || results[i].Errors["url"] != w.err {
+			t.Errorf("result %d: got %+v, want status %q and url error %q", i, results[i], w.status, w.err)
+		}
+	}
+
+	if slices.Contains(ff.fetched, "https://known.example.com/feed") {
+		t.Errorf("got fetches %v, want feeds already in the catalog left alone", ff.fetched)
+	}
+
+	if len(feeds.feeds) != 2 {
+		t.Errorf("got %d feeds, want only the fetched feed added", len(feeds.feeds))
+	}
+	if feed := feeds.feeds["https://new.example.com/feed"]; feed.Title != "New Feed" || feed.SiteURL != "https://www.new.example.com/" {
+		t.Errorf("got feed %+v, want the fetched title and site url", feed)
+	}
+
+	if len(subscriptions.subscriptions) != 2 {
+		t.Fatalf("got %d subscriptions, want 2", len(subscriptions.subscriptions))
+	}
+	if got := subscriptions.subscriptions[1]; got.Title != "My name for it" {
+		t.Errorf("got title %q, want the OPML title kept for the fetched feed", got.Title)
+	}
+}
+
+func TestImporter_Import_CatalogOnly(t *testing.T) {
+	im, feeds, _, subscriptions := newTestImporter()
+
+	doc, err := Parse(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results, err := im.Import(context.Background(), doc, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 3 || len(feeds.feeds) != 3 {
+		t.Errorf("got %d results and %d feeds, want 3 of each", len(results), len(feeds.feeds))
+	}
+	if len(subscriptions.subscriptions) != 0 {
+		t.Errorf("got %d subscriptions, want none without a user", len(subscriptions.subscriptions))
+	}
+}
+
+func TestImporter_Import_ServiceError(t *testing.T) {
+	im, _, _, subscriptions := newTestImporter()
+	subscriptions.err = errors.New("database connection failed")
+
+	doc, err := Parse(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = im.Import(context.Background(), doc, 1)
+	if err != subscriptions.err {
+		t.Errorf("got error %v, want %v", err, subscriptions.err)
+	}
+
+	// The feed created for the first outline goes with its subscription.
+	if tx := im.Transactor.(*fakeTransactor); tx.rollbacks != 1 {
+		t.Errorf("got %d rollbacks, want 1", tx.rollbacks)
+	}
+}
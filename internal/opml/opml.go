@@ -0,0 +1,83 @@
+// Package opml reads and writes OPML subscription lists, the format feed
+// readers use to move subscriptions between each other.
+package opml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrInvalidDocument = errors.New("not an OPML document")
+
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []*Outline `xml:"outline"`
+}
+
+// Outline is either a feed, when XMLURL is set, or a category grouping the
+// outlines nested inside it.
+type Outline struct {
+	Text     string     `xml:"text,attr"`
+	Title    string     `xml:"title,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	XMLURL   string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string     `xml:"htmlUrl,attr,omitempty"`
+	Outlines []*Outline `xml:"outline"`
+}
+
+// Name returns the outline's title, falling back to its text.
+func (o *Outline) Name() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// IsFeed reports whether the outline points at a feed rather than grouping
+// other outlines.
+func (o *Outline) IsFeed() bool {
+	return strings.TrimSpace(o.XMLURL) != ""
+}
+
+// Parse reads an OPML 1.0 or 2.0 document.
+func Parse(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	var doc Document
+
+	err = d.Decode(&doc)
+	if err != nil {
+		var syntaxErr *xml.SyntaxError
+		var unmarshalErr xml.UnmarshalError
+
+		switch {
+		case errors.Is(err, io.EOF), errors.As(err, &syntaxErr), errors.As(err, &unmarshalErr):
+			return nil, ErrInvalidDocument
+		default:
+			return nil, err
+		}
+	}
+
+	return &doc, nil
+}
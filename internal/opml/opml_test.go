@@ -0,0 +1,71 @@
+package opml
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+	<head><title>Subscriptions</title></head>
+	<body>
+		<outline text="Uncategorized" type="rss" xmlUrl="https://one.example.com/feed" htmlUrl="https://one.example.com/"/>
+		<outline text="Tech" title="Tech">
+			<outline text="Two &amp; Co" type="rss" xmlUrl="https://two.example.com/rss.xml"/>
+			<outline text="Nested">
+				<outline title="Three" text="ignored" xmlUrl="https://three.example.com/atom.xml"/>
+			</outline>
+		</outline>
+	</body>
+</opml>`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if doc.Version != "2.0" || doc.Head.Title != "Subscriptions" {
+		t.Errorf("got version %q and title %q", doc.Version, doc.Head.Title)
+	}
+
+	outlines := doc.Body.Outlines
+	if len(outlines) != 2 {
+		t.Fatalf("got %d top-level outlines, want 2", len(outlines))
+	}
+
+	if !outlines[0].IsFeed() || outlines[0].HTMLURL != "https://one.example.com/" {
+		t.Errorf("got first outline %+v, want a feed", outlines[0])
+	}
+
+	tech := outlines[1]
+	if tech.IsFeed() || len(tech.Outlines) != 2 {
+		t.Fatalf("got category %+v with %d children, want 2", tech, len(tech.Outlines))
+	}
+	if name := tech.Outlines[0].Name(); name != "Two & Co" {
+		t.Errorf("got name %q, want %q", name, "Two & Co")
+	}
+	if name := tech.Outlines[1].Outlines[0].Name(); name != "Three" {
+		t.Errorf("got name %q, want title to take precedence over text", name)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"empty", ""},
+		{"not xml", "{}"},
+		{"rss feed", `<rss version="2.0"><channel><title>Feed</title></channel></rss>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.doc))
+			if err != ErrInvalidDocument {
+				t.Errorf("got error %v, want %v", err, ErrInvalidDocument)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package pgsql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/grodier/rss-app/internal/models"
@@ -10,13 +11,17 @@ import (
 
 type FeedService struct {
 	db DBTX
+
+	// QueryTimeout bounds each query, on top of any deadline already on the
+	// caller's context.
+	QueryTimeout time.Duration
 }
 
 func NewFeedService(db DBTX) *FeedService {
-	return &FeedService{db: db}
+	return &FeedService{db: db, QueryTimeout: defaultQueryTimeout}
 }
 
-func (fs *FeedService) Create(feed *models.Feed) error {
+func (fs *FeedService) Create(ctx context.Context, feed *models.Feed) error {
 	query := `
     INSERT INTO feeds (title, description, url, site_url, language)
     VALUES ($1, $2, $3, $4, $5)
@@ -24,13 +29,23 @@ func (fs *FeedService) Create(feed *models.Feed) error {
 
 	args := []any{feed.Title, feed.Description, feed.URL, feed.SiteURL, feed.Language}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
 	defer cancel()
 
-	return fs.db.QueryRowContext(ctx, query, args...).Scan(&feed.ID, &feed.CreatedAt, &feed.Version)
+	err := fs.db.QueryRowContext(ctx, query, args...).Scan(&feed.ID, &feed.CreatedAt, &feed.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "feeds_url_key"):
+			return ErrDuplicateURL
+		default:
+			return contextErr(ctx, err)
+		}
+	}
+
+	return nil
 }
 
-func (fs *FeedService) Get(id int64) (*models.Feed, error) {
+func (fs *FeedService) Get(ctx context.Context, id int64) (*models.Feed, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
@@ -42,7 +57,7 @@ func (fs *FeedService) Get(id int64) (*models.Feed, error) {
 
 	var feed models.Feed
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
 	defer cancel()
 
 	err := fs.db.QueryRowContext(ctx, query, id).Scan(
@@ -61,23 +76,117 @@ func (fs *FeedService) Get(id int64) (*models.Feed, error) {
 		case err == sql.ErrNoRows:
 			return nil, ErrRecordNotFound
 		default:
-			return nil, err
+			return nil, contextErr(ctx, err)
 		}
 	}
 
 	return &feed, nil
 }
 
-func (fs *FeedService) Update(feed *models.Feed) error {
+func (fs *FeedService) GetByURL(ctx context.Context, url string) (*models.Feed, error) {
+	query := `
+    SELECT id, title, description, url, site_url, language, created_at, version
+    FROM feeds
+    WHERE url = $1`
+
+	var feed models.Feed
+
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
+	defer cancel()
+
+	err := fs.db.QueryRowContext(ctx, query, url).Scan(
+		&feed.ID,
+		&feed.Title,
+		&feed.Description,
+		&feed.URL,
+		&feed.SiteURL,
+		&feed.Language,
+		&feed.CreatedAt,
+		&feed.Version,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, contextErr(ctx, err)
+		}
+	}
+
+	return &feed, nil
+}
+
+// GetAll returns a page of feeds. An empty title or language matches every
+// feed; otherwise title is matched as full-text search terms and language
+// exactly.
+func (fs *FeedService) GetAll(ctx context.Context, title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+	query := fmt.Sprintf(`
+    SELECT count(*) OVER(), id, title, description, url, site_url, language, created_at, version
+    FROM feeds
+    WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+    AND (language = $2 OR $2 = '')
+    ORDER BY %s %s, id ASC
+    LIMIT $3 OFFSET $4`, filters.SortColumn(), filters.SortDirection())
+
+	args := []any{title, language, filters.Limit(), filters.Offset()}
+
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
+	defer cancel()
+
+	rows, err := fs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Metadata{}, contextErr(ctx, err)
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	feeds := []*models.Feed{}
+
+	for rows.Next() {
+		var feed models.Feed
+
+		err := rows.Scan(
+			&totalRecords,
+			&feed.ID,
+			&feed.Title,
+			&feed.Description,
+			&feed.URL,
+			&feed.SiteURL,
+			&feed.Language,
+			&feed.CreatedAt,
+			&feed.Version,
+		)
+		if err != nil {
+			return nil, models.Metadata{}, contextErr(ctx, err)
+		}
+
+		feeds = append(feeds, &feed)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, contextErr(ctx, err)
+	}
+
+	metadata := models.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return feeds, metadata, nil
+}
+
+func (fs *FeedService) Update(ctx context.Context, feed *models.Feed) error {
 	if feed.ID < 1 {
 		return ErrRecordNotFound
 	}
 
+	// The conditional GET validators belong to the old address, so they are
+	// dropped when the URL changes.
 	query := `
     UPDATE feeds
-    SET title = $1, description = $2, url = $3, site_url = $4, language = $5, version = version + 1
+    SET title = $1, description = $2, url = $3, site_url = $4, language = $5, version = version + 1,
+        etag = CASE WHEN url = $3 THEN etag ELSE '' END,
+        last_modified = CASE WHEN url = $3 THEN last_modified ELSE '' END
     WHERE id = $6 AND version = $7
-    RETURNING version`
+    RETURNING version, etag, last_modified`
 
 	args := []any{
 		feed.Title,
@@ -89,23 +198,25 @@ func (fs *FeedService) Update(feed *models.Feed) error {
 		feed.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
 	defer cancel()
 
-	err := fs.db.QueryRowContext(ctx, query, args...).Scan(&feed.Version)
+	err := fs.db.QueryRowContext(ctx, query, args...).Scan(&feed.Version, &feed.ETag, &feed.LastModified)
 	if err != nil {
 		switch {
 		case err == sql.ErrNoRows:
 			return ErrEditConflict
+		case isUniqueViolation(err, "feeds_url_key"):
+			return ErrDuplicateURL
 		default:
-			return err
+			return contextErr(ctx, err)
 		}
 	}
 
 	return nil
 }
 
-func (fs *FeedService) Delete(id int64) error {
+func (fs *FeedService) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
@@ -114,14 +225,106 @@ func (fs *FeedService) Delete(id int64) error {
         DELETE FROM feeds
         WHERE id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
 	defer cancel()
 
 	result, err := fs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ClaimDue returns up to limit feeds whose next fetch time has passed and
+// pushes their next_fetch_at forward by lease, so that concurrent pollers skip
+// them while they are being fetched.
+func (fs *FeedService) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Feed, error) {
+	query := `
+    UPDATE feeds
+    SET next_fetch_at = NOW() + make_interval(secs => $2)
+    WHERE id IN (
+        SELECT id
+        FROM feeds
+        WHERE next_fetch_at <= NOW()
+        ORDER BY next_fetch_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING id, title, description, url, site_url, language, created_at, version, next_fetch_at, etag, last_modified`
+
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
+	defer cancel()
+
+	rows, err := fs.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, contextErr(ctx, err)
+	}
+	defer rows.Close()
+
+	feeds := []*models.Feed{}
+
+	for rows.Next() {
+		var feed models.Feed
+
+		err := rows.Scan(
+			&feed.ID,
+			&feed.Title,
+			&feed.Description,
+			&feed.URL,
+			&feed.SiteURL,
+			&feed.Language,
+			&feed.CreatedAt,
+			&feed.Version,
+			&feed.NextFetchAt,
+			&feed.ETag,
+			&feed.LastModified,
+		)
+		if err != nil {
+			return nil, contextErr(ctx, err)
+		}
+
+		feeds = append(feeds, &feed)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, contextErr(ctx, err)
+	}
+
+	return feeds, nil
+}
+
+// UpdateFetchState records the outcome of a fetch. It deliberately leaves the
+// version untouched so background fetches never conflict with user edits.
+func (fs *FeedService) UpdateFetchState(ctx context.Context, feed *models.Feed) error {
+	if feed.ID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    UPDATE feeds
+    SET next_fetch_at = $1, etag = $2, last_modified = $3
+    WHERE id = $4`
+
+	ctx, cancel := context.WithTimeout(ctx, fs.QueryTimeout)
+	defer cancel()
+
+	args := []any{feed.NextFetchAt, feed.ETag, feed.LastModified, feed.ID}
+
+	result, err := fs.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
@@ -1,12 +1,16 @@
 package pgsql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/models/modelstest"
+	"github.com/lib/pq"
 )
 
 func TestFeedService_Create(t *testing.T) {
@@ -34,7 +38,7 @@ func TestFeedService_Create(t *testing.T) {
 		Language:    "en",
 	}
 
-	err = fs.Create(feed)
+	err = fs.Create(context.Background(), feed)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -77,7 +81,7 @@ func TestFeedService_Create_Error(t *testing.T) {
 		Language:    "en",
 	}
 
-	err = fs.Create(feed)
+	err = fs.Create(context.Background(), feed)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -87,6 +91,77 @@ func TestFeedService_Create_Error(t *testing.T) {
 	}
 }
 
+func TestFeedService_Create_DuplicateURL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO feeds`).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "feeds_url_key"})
+
+	fs := NewFeedService(db)
+
+	err = fs.Create(context.Background(), &models.Feed{Title: "Test Feed", URL: "https://example.com/feed.xml", SiteURL: "https://example.com"})
+	if err != ErrDuplicateURL {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateURL)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFeedService_GetByURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		wantError error
+	}{
+		{"success", nil, nil},
+		{"record not found", sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			exp := mock.ExpectQuery(`SELECT .+ FROM feeds WHERE url = \$1`).
+				WithArgs("https://example.com/feed.xml")
+			if tt.mockError != nil {
+				exp.WillReturnError(tt.mockError)
+			} else {
+				exp.WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "url", "site_url", "language", "created_at", "version"}).
+					AddRow(int64(4), "Test Feed", "", "https://example.com/feed.xml", "https://example.com", "en", time.Now(), int32(1)))
+			}
+
+			fs := NewFeedService(db)
+
+			feed, err := fs.GetByURL(context.Background(), "https://example.com/feed.xml")
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if tt.wantError == nil && feed.ID != 4 {
+				t.Errorf("got ID %d, want 4", feed.ID)
+			}
+			if tt.wantError != nil && feed != nil {
+				t.Error("expected nil feed")
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
 func TestFeedService_Get(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -107,7 +182,7 @@ func TestFeedService_Get(t *testing.T) {
 
 	fs := NewFeedService(db)
 
-	feed, err := fs.Get(expectedID)
+	feed, err := fs.Get(context.Background(), expectedID)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -171,7 +246,7 @@ func TestFeedService_Get_Errors(t *testing.T) {
 
 			fs := NewFeedService(db)
 
-			feed, err := fs.Get(tt.id)
+			feed, err := fs.Get(context.Background(), tt.id)
 
 			if feed != nil {
 				t.Error("expected nil feed")
@@ -187,6 +262,109 @@ func TestFeedService_Get_Errors(t *testing.T) {
 	}
 }
 
+func TestFeedService_GetAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Now()
+
+	rows := sqlmock.NewRows([]string{"count", "id", "title", "description", "url", "site_url", "language", "created_at", "version"}).
+		AddRow(5, int64(4), "Go Blog", "Go news", "https://go.dev/blog/feed.atom", "https://go.dev/blog", "en", createdAt, int32(1)).
+		AddRow(5, int64(2), "Go Weekly", "", "https://golangweekly.com/rss", "https://golangweekly.com", "en", createdAt, int32(2))
+
+	mock.ExpectQuery(`SELECT count\(\*\) OVER\(\), id, .+ FROM feeds .+ ORDER BY title DESC, id ASC LIMIT \$3 OFFSET \$4`).
+		WithArgs("go", "en", 2, 2).
+		WillReturnRows(rows)
+
+	fs := NewFeedService(db)
+
+	filters := models.Filters{
+		Page:         2,
+		PageSize:     2,
+		Sort:         "-title",
+		SortSafelist: []string{"id", "title", "-id", "-title"},
+	}
+
+	feeds, metadata, err := fs.GetAll(context.Background(), "go", "en", filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(feeds) != 2 {
+		t.Fatalf("got %d feeds, want 2", len(feeds))
+	}
+	if feeds[0].ID != 4 || feeds[1].ID != 2 {
+		t.Errorf("got IDs %d and %d, want 4 and 2", feeds[0].ID, feeds[1].ID)
+	}
+
+	want := models.Metadata{CurrentPage: 2, PageSize: 2, FirstPage: 1, LastPage: 3, TotalRecords: 5}
+	if metadata != want {
+		t.Errorf("got metadata %+v, want %+v", metadata, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFeedService_GetAll_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT count\(\*\) OVER\(\)`).
+		WithArgs("", "", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "id", "title", "description", "url", "site_url", "language", "created_at", "version"}))
+
+	fs := NewFeedService(db)
+
+	feeds, metadata, err := fs.GetAll(context.Background(), "", "", models.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if feeds == nil || len(feeds) != 0 {
+		t.Errorf("got %v, want empty non-nil slice", feeds)
+	}
+	if metadata != (models.Metadata{}) {
+		t.Errorf("got metadata %+v, want zero value", metadata)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFeedService_GetAll_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT count\(\*\) OVER\(\)`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	fs := NewFeedService(db)
+
+	feeds, _, err := fs.GetAll(context.Background(), "", "", models.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}})
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+	if feeds != nil {
+		t.Error("expected nil feeds")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestFeedService_Update(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -196,7 +374,7 @@ func TestFeedService_Update(t *testing.T) {
 
 	mock.ExpectQuery(`UPDATE feeds SET .+ WHERE id = \$6 AND version = \$7`).
 		WithArgs("Updated Feed", "Updated description", "https://example.com/updated.xml", "https://example.com/updated", "es", int64(1), int32(1)).
-		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int32(2)))
+		WillReturnRows(sqlmock.NewRows([]string{"version", "etag", "last_modified"}).AddRow(int32(2), "", ""))
 
 	fs := NewFeedService(db)
 
@@ -210,7 +388,7 @@ func TestFeedService_Update(t *testing.T) {
 		Version:     1,
 	}
 
-	err = fs.Update(feed)
+	err = fs.Update(context.Background(), feed)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -265,7 +443,7 @@ func TestFeedService_Update_Errors(t *testing.T) {
 				Version:     tt.feedVersion,
 			}
 
-			err = fs.Update(feed)
+			err = fs.Update(context.Background(), feed)
 
 			if err != tt.wantError {
 				t.Errorf("got error %v, want %v", err, tt.wantError)
@@ -278,6 +456,28 @@ func TestFeedService_Update_Errors(t *testing.T) {
 	}
 }
 
+func TestFeedService_Update_DuplicateURL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE feeds`).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "feeds_url_key"})
+
+	fs := NewFeedService(db)
+
+	err = fs.Update(context.Background(), &models.Feed{ID: 1, Title: "Test Feed", URL: "https://example.com/taken.xml", Version: 1})
+	if err != ErrDuplicateURL {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateURL)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestFeedService_Delete(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -291,7 +491,7 @@ func TestFeedService_Delete(t *testing.T) {
 
 	fs := NewFeedService(db)
 
-	err = fs.Delete(1)
+	err = fs.Delete(context.Background(), 1)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -338,8 +538,137 @@ func TestFeedService_Delete_Errors(t *testing.T) {
 
 			fs := NewFeedService(db)
 
-			err = fs.Delete(tt.id)
+			err = fs.Delete(context.Background(), tt.id)
+
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestFeedService_ClaimDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Now()
+	nextFetchAt := createdAt.Add(10 * time.Minute)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "url", "site_url", "language", "created_at", "version", "next_fetch_at", "etag", "last_modified"}).
+		AddRow(int64(1), "Feed One", "First", "https://one.example.com/feed.xml", "https://one.example.com", "en", createdAt, int32(1), nextFetchAt, `"abc"`, "Mon, 02 Jan 2006 15:04:05 GMT").
+		AddRow(int64(2), "Feed Two", "Second", "https://two.example.com/feed.xml", "https://two.example.com", "", createdAt, int32(3), nextFetchAt, "", "")
+
+	mock.ExpectQuery(`UPDATE feeds SET next_fetch_at = .+ FOR UPDATE SKIP LOCKED`).
+		WithArgs(10, float64(600)).
+		WillReturnRows(rows)
+
+	fs := NewFeedService(db)
+
+	feeds, err := fs.ClaimDue(context.Background(), 10, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(feeds) != 2 {
+		t.Fatalf("got %d feeds, want 2", len(feeds))
+	}
+	if feeds[0].ID != 1 || feeds[1].ID != 2 {
+		t.Errorf("got IDs %d and %d, want 1 and 2", feeds[0].ID, feeds[1].ID)
+	}
+	if feeds[1].Version != 3 {
+		t.Errorf("got Version %d, want 3", feeds[1].Version)
+	}
+	if !feeds[0].NextFetchAt.Equal(nextFetchAt) {
+		t.Errorf("got NextFetchAt %v, want %v", feeds[0].NextFetchAt, nextFetchAt)
+	}
+	if feeds[0].ETag != `"abc"` {
+		t.Errorf("got ETag %q, want %q", feeds[0].ETag, `"abc"`)
+	}
+	if feeds[0].LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("got LastModified %q, want %q", feeds[0].LastModified, "Mon, 02 Jan 2006 15:04:05 GMT")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFeedService_ClaimDue_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE feeds SET next_fetch_at`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	fs := NewFeedService(db)
+
+	feeds, err := fs.ClaimDue(context.Background(), 10, time.Minute)
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+	if feeds != nil {
+		t.Error("expected nil feeds")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFeedService_UpdateFetchState(t *testing.T) {
+	nextFetchAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		id           int64
+		mockError    error
+		rowsAffected int64
+		wantError    error
+	}{
+		{"success", 1, nil, 1, nil},
+		{"invalid id", 0, nil, 0, ErrRecordNotFound},
+		{"record not found", 999, nil, 0, ErrRecordNotFound},
+		{"database error", 1, sqlmock.ErrCancelled, 0, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectExec(`UPDATE feeds SET next_fetch_at = \$1, etag = \$2, last_modified = \$3 WHERE id = \$4`).
+					WithArgs(nextFetchAt, `"abc"`, "Mon, 02 Jan 2006 15:04:05 GMT", tt.id)
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+				}
+			}
+
+			fs := NewFeedService(db)
+
+			feed := &models.Feed{
+				ID:           tt.id,
+				NextFetchAt:  nextFetchAt,
+				ETag:         `"abc"`,
+				LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+			}
 
+			err = fs.UpdateFetchState(context.Background(), feed)
 			if err != tt.wantError {
 				t.Errorf("got error %v, want %v", err, tt.wantError)
 			}
@@ -350,3 +679,65 @@ func TestFeedService_Delete_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestFeedService_Get_Context(t *testing.T) {
+	t.Run("query timeout", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`SELECT .+ FROM feeds WHERE id = \$1`).
+			WithArgs(int64(1)).
+			WillDelayFor(time.Second).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		fs := NewFeedService(db)
+		fs.QueryTimeout = 10 * time.Millisecond
+
+		_, err = fs.Get(context.Background(), 1)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("caller canceled", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		mock.ExpectQuery(`SELECT .+ FROM feeds WHERE id = \$1`).
+			WithArgs(int64(1)).
+			WillDelayFor(time.Second).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		_, err = NewFeedService(db).Get(ctx, 1)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+// TestFeedService_Conformance runs the checks shared with the other storage
+// backends against a real database, whose feeds and everything referencing
+// them are deleted. It is skipped unless RSSAPP_TEST_DB_DSN is set.
+func TestFeedService_Conformance(t *testing.T) {
+	db := newConformanceDB(t)
+
+	modelstest.TestFeedService(t, func(t *testing.T) models.FeedService {
+		if _, err := db.Exec(`TRUNCATE feeds RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("failed to empty feeds: %v", err)
+		}
+		return NewFeedService(db)
+	})
+}
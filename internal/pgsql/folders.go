@@ -0,0 +1,186 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type FolderService struct {
+	db DBTX
+}
+
+func NewFolderService(db DBTX) *FolderService {
+	return &FolderService{db: db}
+}
+
+func (fs *FolderService) Create(folder *models.Folder) error {
+	query := `
+    INSERT INTO folders (user_id, name)
+    VALUES ($1, $2)
+    RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := fs.db.QueryRowContext(ctx, query, folder.UserID, folder.Name).Scan(&folder.ID, &folder.CreatedAt, &folder.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "folders_user_id_name_key"):
+			return ErrDuplicateFolder
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns the folder with its unread count if it belongs to userID.
+// Folders of other users are reported as not found.
+func (fs *FolderService) Get(id int64, userID int64) (*models.Folder, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    SELECT fo.id, fo.user_id, fo.name, fo.created_at, fo.version,
+        (SELECT COALESCE(sum(` + unreadCountExpr + `), 0)::bigint FROM subscriptions s WHERE s.folder_id = fo.id)
+    FROM folders fo
+    WHERE fo.id = $1 AND fo.user_id = $2`
+
+	var folder models.Folder
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := fs.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&folder.ID,
+		&folder.UserID,
+		&folder.Name,
+		&folder.CreatedAt,
+		&folder.Version,
+		&folder.UnreadCount,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &folder, nil
+}
+
+// GetAllForUser returns the user's folders with their unread counts, ordered
+// by name.
+func (fs *FolderService) GetAllForUser(userID int64) ([]*models.Folder, error) {
+	query := `
+    SELECT fo.id, fo.user_id, fo.name, fo.created_at, fo.version,
+        (SELECT COALESCE(sum(` + unreadCountExpr + `), 0)::bigint FROM subscriptions s WHERE s.folder_id = fo.id)
+    FROM folders fo
+    WHERE fo.user_id = $1
+    ORDER BY lower(fo.name), fo.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := fs.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []*models.Folder{}
+
+	for rows.Next() {
+		var folder models.Folder
+
+		err := rows.Scan(
+			&folder.ID,
+			&folder.UserID,
+			&folder.Name,
+			&folder.CreatedAt,
+			&folder.Version,
+			&folder.UnreadCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		folders = append(folders, &folder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+func (fs *FolderService) Update(folder *models.Folder) error {
+	if folder.ID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    UPDATE folders
+    SET name = $1, version = version + 1
+    WHERE id = $2 AND user_id = $3 AND version = $4
+    RETURNING version`
+
+	args := []any{folder.Name, folder.ID, folder.UserID, folder.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := fs.db.QueryRowContext(ctx, query, args...).Scan(&folder.Version)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrEditConflict
+		case isUniqueViolation(err, "folders_user_id_name_key"):
+			return ErrDuplicateFolder
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the folder if it belongs to userID. Its subscriptions are
+// kept and moved out of any folder.
+func (fs *FolderService) Delete(id int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    DELETE FROM folders
+    WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := fs.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package pgsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestFolderService_Create(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		wantError error
+	}{
+		{"success", nil, nil},
+		{"duplicate name", &pq.Error{Code: "23505", Constraint: "folders_user_id_name_key"}, ErrDuplicateFolder},
+		{"database error", sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			exp := mock.ExpectQuery(`INSERT INTO folders`).WithArgs(int64(1), "Tech")
+			if tt.mockError != nil {
+				exp.WillReturnError(tt.mockError)
+			} else {
+				exp.WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(int64(3), time.Now(), int32(1)))
+			}
+
+			fs := NewFolderService(db)
+
+			folder := &models.Folder{UserID: 1, Name: "Tech"}
+
+			err = fs.Create(folder)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError == nil && folder.ID != 3 {
+				t.Errorf("expected ID 3, got %d", folder.ID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestFolderService_Get(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int64
+		mockError error
+		wantError error
+	}{
+		{"success", 3, nil, nil},
+		{"invalid id", 0, nil, ErrRecordNotFound},
+		{"not found or not owned", 3, sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", 3, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectQuery(`SELECT .+ FROM folders fo WHERE fo.id = \$1 AND fo.user_id = \$2`).
+					WithArgs(tt.id, int64(1))
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "created_at", "version", "unread_count"}).
+						AddRow(tt.id, int64(1), "Tech", time.Now(), int32(2), 7))
+				}
+			}
+
+			fs := NewFolderService(db)
+
+			folder, err := fs.Get(tt.id, 1)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if tt.wantError == nil {
+				if folder.Name != "Tech" || folder.UnreadCount != 7 {
+					t.Errorf("got folder %+v", folder)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestFolderService_GetAllForUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "created_at", "version", "unread_count"}).
+		AddRow(int64(4), int64(1), "News", now, int32(1), 0).
+		AddRow(int64(3), int64(1), "Tech", now, int32(1), 12)
+
+	mock.ExpectQuery(`SELECT .+ FROM folders fo WHERE fo.user_id = \$1 ORDER BY lower\(fo.name\), fo.id`).
+		WithArgs(int64(1)).
+		WillReturnRows(rows)
+
+	fs := NewFolderService(db)
+
+	folders, err := fs.GetAllForUser(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(folders) != 2 {
+		t.Fatalf("got %d folders, want 2", len(folders))
+	}
+	if folders[1].Name != "Tech" || folders[1].UnreadCount != 12 {
+		t.Errorf("got folder %+v", folders[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFolderService_Update(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int64
+		mockError error
+		wantError error
+	}{
+		{"success", 3, nil, nil},
+		{"invalid id", 0, nil, ErrRecordNotFound},
+		{"edit conflict", 3, sql.ErrNoRows, ErrEditConflict},
+		{"duplicate name", 3, &pq.Error{Code: "23505", Constraint: "folders_user_id_name_key"}, ErrDuplicateFolder},
+		{"database error", 3, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectQuery(`UPDATE folders SET .+ WHERE id = \$2 AND user_id = \$3 AND version = \$4`).
+					WithArgs("Renamed", tt.id, int64(1), int32(1))
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int32(2)))
+				}
+			}
+
+			fs := NewFolderService(db)
+
+			folder := &models.Folder{ID: tt.id, UserID: 1, Name: "Renamed", Version: 1}
+
+			err = fs.Update(folder)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError == nil && folder.Version != 2 {
+				t.Errorf("expected Version 2, got %d", folder.Version)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestFolderService_Delete(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           int64
+		mockError    error
+		rowsAffected int64
+		wantError    error
+	}{
+		{"success", 3, nil, 1, nil},
+		{"invalid id", 0, nil, 0, ErrRecordNotFound},
+		{"not found or not owned", 3, nil, 0, ErrRecordNotFound},
+		{"database error", 3, sqlmock.ErrCancelled, 0, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectExec(`DELETE FROM folders WHERE id = \$1 AND user_id = \$2`).
+					WithArgs(tt.id, int64(1))
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+				}
+			}
+
+			fs := NewFolderService(db)
+
+			err = fs.Delete(tt.id, 1)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
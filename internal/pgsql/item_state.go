@@ -0,0 +1,99 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+type ItemStateService struct {
+	db DBTX
+}
+
+func NewItemStateService(db DBTX) *ItemStateService {
+	return &ItemStateService{db: db}
+}
+
+// Update only touches items whose feed the user subscribes to. A nil flag in
+// update leaves the stored value alone; a nil read on a new row means the item
+// follows the subscription's read watermark.
+func (ss *ItemStateService) Update(userID int64, itemIDs []int64, update models.ItemStateUpdate) (int64, error) {
+	query := `
+    INSERT INTO user_item_state (user_id, item_id, read, read_at, starred, starred_at)
+    SELECT $1, i.id,
+        $3::boolean, CASE WHEN $3::boolean THEN NOW() END,
+        COALESCE($4::boolean, false), CASE WHEN $4::boolean THEN NOW() END
+    FROM items i
+    INNER JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $1
+    WHERE i.id = ANY($2)
+    ON CONFLICT (user_id, item_id) DO UPDATE
+    SET read = CASE WHEN $3::boolean IS NULL THEN user_item_state.read ELSE EXCLUDED.read END,
+        read_at = CASE WHEN $3::boolean IS NULL THEN user_item_state.read_at ELSE EXCLUDED.read_at END,
+        starred = CASE WHEN $4::boolean IS NULL THEN user_item_state.starred ELSE EXCLUDED.starred END,
+        starred_at = CASE WHEN $4::boolean IS NULL THEN user_item_state.starred_at ELSE EXCLUDED.starred_at END`
+
+	args := []any{userID, pq.Array(itemIDs), nullBool(update.Read), nullBool(update.Starred)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := ss.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MarkSubscriptionRead moves the subscription's read watermark forward to
+// before and drops per-item read overrides it now covers. The watermark never
+// moves backwards, so an older timestamp cannot mark read items unread.
+func (ss *ItemStateService) MarkSubscriptionRead(subscriptionID int64, userID int64, before time.Time) error {
+	if subscriptionID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    WITH sub AS (
+        UPDATE subscriptions
+        SET read_before = GREATEST(read_before, $3)
+        WHERE id = $1 AND user_id = $2
+        RETURNING feed_id
+    ), cleared AS (
+        UPDATE user_item_state st
+        SET read = NULL, read_at = NULL
+        FROM items i, sub
+        WHERE st.user_id = $2
+        AND st.item_id = i.id
+        AND i.feed_id = sub.feed_id
+        AND i.published_at <= $3
+    )
+    SELECT feed_id FROM sub`
+
+	var feedID int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ss.db.QueryRowContext(ctx, query, subscriptionID, userID, before).Scan(&feedID)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
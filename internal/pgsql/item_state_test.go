@@ -0,0 +1,134 @@
+package pgsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestItemStateService_Update(t *testing.T) {
+	read := true
+	starred := false
+
+	tests := []struct {
+		name        string
+		update      models.ItemStateUpdate
+		wantRead    sql.NullBool
+		wantStarred sql.NullBool
+	}{
+		{
+			name:        "read only",
+			update:      models.ItemStateUpdate{Read: &read},
+			wantRead:    sql.NullBool{Bool: true, Valid: true},
+			wantStarred: sql.NullBool{},
+		},
+		{
+			name:        "starred only",
+			update:      models.ItemStateUpdate{Starred: &starred},
+			wantRead:    sql.NullBool{},
+			wantStarred: sql.NullBool{Bool: false, Valid: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(`INSERT INTO user_item_state .+ INNER JOIN subscriptions s .+ ON CONFLICT \(user_id, item_id\) DO UPDATE`).
+				WithArgs(int64(1), pq.Array([]int64{3, 4}), tt.wantRead, tt.wantStarred).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+
+			ss := NewItemStateService(db)
+
+			updated, err := ss.Update(1, []int64{3, 4}, tt.update)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if updated != 2 {
+				t.Errorf("got %d updated, want 2", updated)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestItemStateService_Update_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO user_item_state`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	ss := NewItemStateService(db)
+
+	_, err = ss.Update(1, []int64{3}, models.ItemStateUpdate{})
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemStateService_MarkSubscriptionRead(t *testing.T) {
+	before := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		id        int64
+		mockError error
+		wantError error
+	}{
+		{"success", 9, nil, nil},
+		{"invalid id", 0, nil, ErrRecordNotFound},
+		{"not found or not owned", 9, sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", 9, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectQuery(`WITH sub AS \( UPDATE subscriptions SET read_before = GREATEST\(read_before, \$3\)`).
+					WithArgs(tt.id, int64(1), before)
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnRows(sqlmock.NewRows([]string{"feed_id"}).AddRow(int64(4)))
+				}
+			}
+
+			ss := NewItemStateService(db)
+
+			err = ss.MarkSubscriptionRead(tt.id, 1, before)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
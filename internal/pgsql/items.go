@@ -0,0 +1,288 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type ItemService struct {
+	db DBTX
+}
+
+func NewItemService(db DBTX) *ItemService {
+	return &ItemService{db: db}
+}
+
+// Upsert inserts the item, or refreshes the stored copy when the feed already
+// has an item with the same GUID whose content has changed. Unchanged items
+// are left untouched and item.ID is not populated.
+func (is *ItemService) Upsert(item *models.Item) error {
+	item.ContentHash = item.Hash()
+
+	query := `
+    INSERT INTO items (feed_id, guid, title, link, author, content, summary, published_at, updated_at, content_hash)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (feed_id, guid) DO UPDATE
+    SET title = EXCLUDED.title, link = EXCLUDED.link, author = EXCLUDED.author, content = EXCLUDED.content,
+        summary = EXCLUDED.summary, updated_at = EXCLUDED.updated_at, content_hash = EXCLUDED.content_hash
+    WHERE items.content_hash <> EXCLUDED.content_hash
+    RETURNING id, created_at`
+
+	args := []any{
+		item.FeedID,
+		item.GUID,
+		item.Title,
+		item.Link,
+		item.Author,
+		item.Content,
+		item.Summary,
+		item.PublishedAt,
+		item.UpdatedAt,
+		item.ContentHash,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := is.db.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
+}
+
+func (is *ItemService) Get(id int64) (*models.Item, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    SELECT id, feed_id, guid, title, link, author, content, summary, published_at, updated_at, content_hash, created_at
+    FROM items
+    WHERE id = $1`
+
+	var item models.Item
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := is.db.QueryRowContext(ctx, query, id).Scan(
+		&item.ID,
+		&item.FeedID,
+		&item.GUID,
+		&item.Title,
+		&item.Link,
+		&item.Author,
+		&item.Content,
+		&item.Summary,
+		&item.PublishedAt,
+		&item.UpdatedAt,
+		&item.ContentHash,
+		&item.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+
+// GetAllForFeed returns a page of the feed's items, newest first, starting
+// after filters.After, along with userID's state for each. Content is left
+// empty; clients fetch it per item.
+func (is *ItemService) GetAllForFeed(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	query := `
+    SELECT i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.summary, i.published_at, i.updated_at,
+        COALESCE(st.read, i.published_at <= s.read_before, false), COALESCE(st.starred, false)
+    FROM items i
+    LEFT JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $5
+    LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = $5
+    WHERE i.feed_id = $1
+    AND ($2::bigint = 0 OR (i.published_at, i.id) < ($3::timestamptz, $2::bigint))
+    ORDER BY i.published_at DESC, i.id DESC
+    LIMIT $4`
+
+	// Ask for one extra row to learn whether another page follows.
+	args := []any{feedID, filters.After.ID, filters.After.PublishedAt, filters.PageSize + 1, userID}
+
+	return is.queryPage(query, args, filters.PageSize)
+}
+
+// GetAllForFolder is like GetAllForFeed, but merges the items of every feed
+// userID has subscribed to in the folder into a single timeline.
+func (is *ItemService) GetAllForFolder(folderID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	query := `
+    SELECT i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.summary, i.published_at, i.updated_at,
+        COALESCE(st.read, i.published_at <= s.read_before, false), COALESCE(st.starred, false)
+    FROM items i
+    INNER JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $5
+    LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = $5
+    WHERE s.folder_id = $1
+    AND ($2::bigint = 0 OR (i.published_at, i.id) < ($3::timestamptz, $2::bigint))
+    ORDER BY i.published_at DESC, i.id DESC
+    LIMIT $4`
+
+	// Ask for one extra row to learn whether another page follows.
+	args := []any{folderID, filters.After.ID, filters.After.PublishedAt, filters.PageSize + 1, userID}
+
+	return is.queryPage(query, args, filters.PageSize)
+}
+
+// Search returns a page of the items in userID's subscriptions that match
+// the search, with snippets highlighting the matching words. Snippets are
+// only built for the rows on the page, as ts_headline has to re-parse each
+// document.
+func (is *ItemService) Search(userID int64, search models.ItemSearch, filters models.Filters) ([]*models.SearchResult, models.Metadata, error) {
+	query := fmt.Sprintf(`
+    SELECT m.total, m.id, m.feed_id, m.guid, m.title, m.link, m.author, m.summary, m.published_at, m.updated_at,
+        m.read, m.starred, m.rank,
+        ts_headline('english', regexp_replace(COALESCE(NULLIF(m.content, ''), m.summary, ''), '<[^>]*>', ' ', 'g'), m.query,
+            'StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35, MaxFragments=2')
+    FROM (
+        SELECT count(*) OVER() AS total, i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.summary, i.content,
+            i.published_at, i.updated_at,
+            COALESCE(st.read, i.published_at <= s.read_before, false) AS read,
+            COALESCE(st.starred, false) AS starred,
+            ts_rank(i.search, q.query) AS rank,
+            q.query
+        FROM items i
+        INNER JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $1
+        LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = $1
+        CROSS JOIN websearch_to_tsquery('english', $2) AS q(query)
+        WHERE i.search @@ q.query
+        AND ($3::timestamptz IS NULL OR i.published_at >= $3)
+        AND ($4::timestamptz IS NULL OR i.published_at < $4)
+        ORDER BY %[1]s %[2]s, i.id DESC
+        LIMIT $5 OFFSET $6
+    ) m
+    ORDER BY %[1]s %[2]s, m.id DESC`, filters.SortColumn(), filters.SortDirection())
+
+	args := []any{
+		userID,
+		search.Query,
+		nullTime(search.PublishedAfter),
+		nullTime(search.PublishedBefore),
+		filters.Limit(),
+		filters.Offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := is.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*models.SearchResult{}
+
+	for rows.Next() {
+		var item models.Item
+		var state models.ItemState
+		var result models.SearchResult
+
+		err := rows.Scan(
+			&totalRecords,
+			&item.ID,
+			&item.FeedID,
+			&item.GUID,
+			&item.Title,
+			&item.Link,
+			&item.Author,
+			&item.Summary,
+			&item.PublishedAt,
+			&item.UpdatedAt,
+			&state.Read,
+			&state.Starred,
+			&result.Rank,
+			&result.Headline,
+		)
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		item.State = &state
+		result.Item = &item
+		result.Headline = models.EscapeHeadline(result.Headline)
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return results, metadata, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// queryPage runs a timeline query that selects pageSize+1 items with their
+// state, and trims the extra row into the next cursor.
+func (is *ItemService) queryPage(query string, args []any, pageSize int) ([]*models.Item, models.CursorMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := is.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.CursorMetadata{}, err
+	}
+	defer rows.Close()
+
+	items := []*models.Item{}
+
+	for rows.Next() {
+		var item models.Item
+		var state models.ItemState
+
+		err := rows.Scan(
+			&item.ID,
+			&item.FeedID,
+			&item.GUID,
+			&item.Title,
+			&item.Link,
+			&item.Author,
+			&item.Summary,
+			&item.PublishedAt,
+			&item.UpdatedAt,
+			&state.Read,
+			&state.Starred,
+		)
+		if err != nil {
+			return nil, models.CursorMetadata{}, err
+		}
+
+		item.State = &state
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.CursorMetadata{}, err
+	}
+
+	metadata := models.CursorMetadata{PageSize: pageSize}
+
+	if len(items) > pageSize {
+		items = items[:pageSize]
+		last := items[len(items)-1]
+		metadata.NextCursor = models.Cursor{PublishedAt: last.PublishedAt, ID: last.ID}.Encode()
+	}
+
+	return items, metadata, nil
+}
@@ -0,0 +1,432 @@
+package pgsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func newTestItem() *models.Item {
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return &models.Item{
+		FeedID:      1,
+		GUID:        "item-1",
+		Title:       "Test Item",
+		Link:        "https://example.com/items/1",
+		Author:      "Jane Doe",
+		Content:     "<p>Content</p>",
+		Summary:     "Summary",
+		PublishedAt: published,
+		UpdatedAt:   published,
+	}
+}
+
+func TestItemService_Upsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	item := newTestItem()
+	expectedCreatedAt := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO items .+ ON CONFLICT \(feed_id, guid\) DO UPDATE`).
+		WithArgs(item.FeedID, item.GUID, item.Title, item.Link, item.Author, item.Content, item.Summary, item.PublishedAt, item.UpdatedAt, item.Hash()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(7), expectedCreatedAt))
+
+	is := NewItemService(db)
+
+	err = is.Upsert(item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if item.ID != 7 {
+		t.Errorf("got ID %d, want 7", item.ID)
+	}
+	if !item.CreatedAt.Equal(expectedCreatedAt) {
+		t.Errorf("got CreatedAt %v, want %v", item.CreatedAt, expectedCreatedAt)
+	}
+	if item.ContentHash != item.Hash() {
+		t.Errorf("got ContentHash %q, want %q", item.ContentHash, item.Hash())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemService_Upsert_Unchanged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO items`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	is := NewItemService(db)
+
+	item := newTestItem()
+
+	err = is.Upsert(item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if item.ID != 0 {
+		t.Errorf("got ID %d, want 0 for unchanged item", item.ID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemService_Upsert_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO items`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	is := NewItemService(db)
+
+	err = is.Upsert(newTestItem())
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemService_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	want := newTestItem()
+	want.ID = 7
+	want.ContentHash = want.Hash()
+	want.CreatedAt = time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "feed_id", "guid", "title", "link", "author", "content", "summary", "published_at", "updated_at", "content_hash", "created_at"}).
+		AddRow(want.ID, want.FeedID, want.GUID, want.Title, want.Link, want.Author, want.Content, want.Summary, want.PublishedAt, want.UpdatedAt, want.ContentHash, want.CreatedAt)
+
+	mock.ExpectQuery(`SELECT .+ FROM items WHERE id = \$1`).
+		WithArgs(want.ID).
+		WillReturnRows(rows)
+
+	is := NewItemService(db)
+
+	item, err := is.Get(want.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if item.ID != want.ID {
+		t.Errorf("got ID %d, want %d", item.ID, want.ID)
+	}
+	if item.FeedID != want.FeedID {
+		t.Errorf("got FeedID %d, want %d", item.FeedID, want.FeedID)
+	}
+	if item.GUID != want.GUID {
+		t.Errorf("got GUID %q, want %q", item.GUID, want.GUID)
+	}
+	if item.Title != want.Title {
+		t.Errorf("got Title %q, want %q", item.Title, want.Title)
+	}
+	if item.Content != want.Content {
+		t.Errorf("got Content %q, want %q", item.Content, want.Content)
+	}
+	if !item.PublishedAt.Equal(want.PublishedAt) {
+		t.Errorf("got PublishedAt %v, want %v", item.PublishedAt, want.PublishedAt)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemService_Get_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int64
+		mockError error // nil means no DB call expected
+		wantError error
+	}{
+		{"invalid id zero", 0, nil, ErrRecordNotFound},
+		{"invalid id negative", -1, nil, ErrRecordNotFound},
+		{"record not found", 999, sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", 1, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.mockError != nil {
+				mock.ExpectQuery(`SELECT .+ FROM items WHERE id = \$1`).
+					WithArgs(tt.id).
+					WillReturnError(tt.mockError)
+			}
+
+			is := NewItemService(db)
+
+			item, err := is.Get(tt.id)
+
+			if item != nil {
+				t.Error("expected nil item")
+			}
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestItemService_GetAllForFeed(t *testing.T) {
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"id", "feed_id", "guid", "title", "link", "author", "summary", "published_at", "updated_at", "read", "starred"}
+
+	tests := []struct {
+		name           string
+		after          models.Cursor
+		rows           int
+		wantItems      int
+		wantNextCursor string
+	}{
+		{
+			name:      "last page",
+			rows:      2,
+			wantItems: 2,
+		},
+		{
+			name:           "more pages follow",
+			rows:           3,
+			wantItems:      2,
+			wantNextCursor: models.Cursor{PublishedAt: published.Add(-time.Hour), ID: 9}.Encode(),
+		},
+		{
+			name:      "after cursor",
+			after:     models.Cursor{PublishedAt: published, ID: 42},
+			rows:      1,
+			wantItems: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			rows := sqlmock.NewRows(columns)
+			for i := range tt.rows {
+				id := int64(10 - i)
+				at := published.Add(-time.Duration(i) * time.Hour)
+				rows.AddRow(id, int64(1), "guid", "Title", "https://example.com", "", "Summary", at, at, i > 0, i == 0)
+			}
+
+			mock.ExpectQuery(`SELECT .+ FROM items i .+ WHERE i.feed_id = \$1 .+ ORDER BY i.published_at DESC, i.id DESC LIMIT \$4`).
+				WithArgs(int64(1), tt.after.ID, tt.after.PublishedAt, 3, int64(5)).
+				WillReturnRows(rows)
+
+			is := NewItemService(db)
+
+			items, metadata, err := is.GetAllForFeed(1, 5, models.CursorFilters{After: tt.after, PageSize: 2})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if items[0].State == nil || items[0].State.Read || !items[0].State.Starred {
+				t.Errorf("got first item state %+v, want unread and starred", items[0].State)
+			}
+
+			if len(items) != tt.wantItems {
+				t.Errorf("got %d items, want %d", len(items), tt.wantItems)
+			}
+			if metadata.PageSize != 2 {
+				t.Errorf("got page size %d, want 2", metadata.PageSize)
+			}
+			if metadata.NextCursor != tt.wantNextCursor {
+				t.Errorf("got next cursor %q, want %q", metadata.NextCursor, tt.wantNextCursor)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestItemService_GetAllForFolder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "feed_id", "guid", "title", "link", "author", "summary", "published_at", "updated_at", "read", "starred"}).
+		AddRow(int64(10), int64(1), "a", "From One", "", "", "", published, published, false, false).
+		AddRow(int64(9), int64(2), "b", "From Two", "", "", "", published.Add(-time.Hour), published, true, false).
+		AddRow(int64(8), int64(1), "c", "Older", "", "", "", published.Add(-2*time.Hour), published, false, false)
+
+	mock.ExpectQuery(`SELECT .+ FROM items i INNER JOIN subscriptions s .+ WHERE s.folder_id = \$1 .+ LIMIT \$4`).
+		WithArgs(int64(3), int64(0), time.Time{}, 3, int64(5)).
+		WillReturnRows(rows)
+
+	is := NewItemService(db)
+
+	items, metadata, err := is.GetAllForFolder(3, 5, models.CursorFilters{PageSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].FeedID != 1 || items[1].FeedID != 2 {
+		t.Errorf("got feeds %d and %d, want items merged from feeds 1 and 2", items[0].FeedID, items[1].FeedID)
+	}
+	if want := (models.Cursor{PublishedAt: published.Add(-time.Hour), ID: 9}).Encode(); metadata.NextCursor != want {
+		t.Errorf("got next cursor %q, want %q", metadata.NextCursor, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemService_GetAllForFeed_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT .+ FROM items i`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	is := NewItemService(db)
+
+	items, _, err := is.GetAllForFeed(1, 5, models.CursorFilters{PageSize: 20})
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+	if items != nil {
+		t.Error("expected nil items")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestItemService_Search(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	after := published.AddDate(0, -1, 0)
+
+	rows := sqlmock.NewRows([]string{
+		"total", "id", "feed_id", "guid", "title", "link", "author", "summary", "published_at", "updated_at",
+		"read", "starred", "rank", "headline",
+	}).
+		AddRow(3, int64(10), int64(1), "a", "Generics in Go", "", "", "", published, published, false, true, 0.8, "using <mark>generics</mark>").
+		AddRow(3, int64(7), int64(2), "b", "Go 1.18", "", "", "", published, published, true, false, 0.2, "type parameters, or <mark>generics</mark>")
+
+	mock.ExpectQuery(`SELECT .+ FROM items i INNER JOIN subscriptions s .+ websearch_to_tsquery\('english', \$2\) .+ ORDER BY rank DESC, i.id DESC LIMIT \$5 OFFSET \$6 \) m ORDER BY rank DESC, m.id DESC`).
+		WithArgs(int64(5), "generics", after, nil, 2, 0).
+		WillReturnRows(rows)
+
+	is := NewItemService(db)
+
+	search := models.ItemSearch{Query: "generics", PublishedAfter: after}
+	filters := models.Filters{Page: 1, PageSize: 2, Sort: "-rank", SortSafelist: []string{"-rank"}}
+
+	results, metadata, err := is.Search(5, search, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Title != "Generics in Go" || results[0].Rank != 0.8 || results[0].Headline != "using <mark>generics</mark>" {
+		t.Errorf("got first result %+v", results[0])
+	}
+	if results[0].State == nil || !results[0].State.Starred {
+		t.Errorf("got state %+v, want starred", results[0].State)
+	}
+	if metadata.TotalRecords != 3 || metadata.LastPage != 2 {
+		t.Errorf("got metadata %+v, want 3 records over 2 pages", metadata)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// TestItemService_Search_EscapesHeadline feeds the snippet Postgres builds
+// from a hostile item body, after its complete tags were stripped, through
+// Search: only the highlights may remain markup.
+func TestItemService_Search_EscapesHeadline(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{
+		"total", "id", "feed_id", "guid", "title", "link", "author", "summary", "published_at", "updated_at",
+		"read", "starred", "rank", "headline",
+	}).
+		AddRow(1, int64(10), int64(1), "a", "Hostile", "", "", "", published, published, false, false, 0.8,
+			`Tom &amp; Jerry &lt;3 <mark>generics</mark> <img src=x onerror=alert(1) <mark>generics</mark>`)
+
+	mock.ExpectQuery(`SELECT .+ FROM items i`).WillReturnRows(rows)
+
+	filters := models.Filters{Page: 1, PageSize: 20, Sort: "-rank", SortSafelist: []string{"-rank"}}
+
+	results, _, err := NewItemService(db).Search(5, models.ItemSearch{Query: "generics"}, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `Tom &amp; Jerry &lt;3 <mark>generics</mark> &lt;img src=x onerror=alert(1) <mark>generics</mark>`
+	if len(results) != 1 || results[0].Headline != want {
+		t.Errorf("got headline %q, want %q", results[0].Headline, want)
+	}
+}
@@ -0,0 +1,272 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/grodier/rss-app/migrations"
+)
+
+// migrationLockID is the key of the advisory lock held while migrating, so
+// that replicas starting together apply each migration only once. It is the
+// same key goose uses, so the two also exclude each other.
+const migrationLockID = 5887940537704921958
+
+// Migrator applies migrations and records them in goose's version table, so
+// that databases migrated by either stay in step.
+type Migrator struct {
+	db         *DB
+	migrations []*migrations.Migration
+}
+
+func NewMigrator(db *DB, ms []*migrations.Migration) *Migrator {
+	return &Migrator{db: db, migrations: ms}
+}
+
+// Up applies every pending migration in order and returns those applied.
+func (m *Migrator) Up(ctx context.Context) ([]*migrations.Migration, error) {
+	return m.To(ctx, m.latest())
+}
+
+// Down rolls back the most recently applied migration, returning nil when
+// none are applied.
+func (m *Migrator) Down(ctx context.Context) (*migrations.Migration, error) {
+	var rolledBack *migrations.Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range slices.Backward(m.migrations) {
+			if _, ok := applied[migration.Version]; ok {
+				if err := m.run(ctx, conn, migration, false); err != nil {
+					return err
+				}
+				rolledBack = migration
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// To applies or rolls back migrations until version is the latest applied,
+// returning the migrations run. Version 0 rolls back everything.
+func (m *Migrator) To(ctx context.Context, version int64) ([]*migrations.Migration, error) {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mi *migrations.Migration) bool { return mi.Version == version }) {
+		return nil, fmt.Errorf("no migration with version %d", version)
+	}
+
+	ran := []*migrations.Migration{}
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range slices.Backward(m.migrations) {
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.run(ctx, conn, migration, false); err != nil {
+					return err
+				}
+				ran = append(ran, migration)
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.run(ctx, conn, migration, true); err != nil {
+					return err
+				}
+				ran = append(ran, migration)
+			}
+		}
+
+		return nil
+	})
+
+	return ran, err
+}
+
+// Status reports every migration and when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]*migrations.Status, error) {
+	var statuses []*migrations.Status
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			statuses = append(statuses, &migrations.Status{Migration: migration, AppliedAt: applied[migration.Version]})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+func (m *Migrator) latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// locked runs fn on a single connection holding the migration lock, after
+// making sure the version table exists.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// The lock belongs to the session, so release it even if ctx has
+		// been cancelled before the connection goes back to the pool.
+		conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ensureVersionTable creates goose's version table, including the version 0
+// row goose inserts, unless it already exists.
+func ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	var exists bool
+
+	err := conn.QueryRowContext(ctx, `SELECT to_regclass('goose_db_version') IS NOT NULL`).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    CREATE TABLE goose_db_version (
+        id serial PRIMARY KEY,
+        version_id bigint NOT NULL,
+        is_applied boolean NOT NULL,
+        tstamp timestamp DEFAULT now()
+    )`
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, true)`); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// appliedVersions returns when each applied migration was applied. Older
+// versions of goose recorded rollbacks as rows with is_applied false rather
+// than deleting them, so only the latest row for each version counts.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `
+    SELECT version_id, is_applied, tstamp
+    FROM goose_db_version
+    WHERE version_id > 0
+    ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	seen := make(map[int64]bool)
+
+	for rows.Next() {
+		var (
+			version   int64
+			isApplied bool
+			tstamp    sql.NullTime
+		)
+
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+
+		if isApplied {
+			applied[version] = tstamp.Time
+		}
+	}
+
+	return applied, rows.Err()
+}
+
+// execer is satisfied by both connections and transactions.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// run applies or rolls back a single migration and records the result,
+// inside one transaction unless the migration opts out.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration *migrations.Migration, up bool) error {
+	statements := migration.Down
+	record := `DELETE FROM goose_db_version WHERE version_id = $1`
+
+	if up {
+		statements = migration.Up
+		record = `INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, true)`
+	}
+
+	exec := func(db execer) error {
+		for _, stmt := range statements {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("%s: %w", migration.Name, err)
+			}
+		}
+
+		_, err := db.ExecContext(ctx, record, migration.Version)
+		return err
+	}
+
+	if migration.NoTransaction {
+		return exec(conn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := exec(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
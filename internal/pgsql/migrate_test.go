@@ -0,0 +1,217 @@
+package pgsql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/migrations"
+)
+
+func testMigrations() []*migrations.Migration {
+	return []*migrations.Migration{
+		{Version: 1, Name: "00001_one.sql", Up: []string{"CREATE TABLE one ();"}, Down: []string{"DROP TABLE one;"}},
+		{Version: 2, Name: "00002_two.sql", Up: []string{"CREATE TABLE two ();"}, Down: []string{"DROP TABLE two;"}},
+		{Version: 3, Name: "00003_three.sql", Up: []string{"CREATE INDEX CONCURRENTLY three_idx ON two ();"}, Down: []string{"DROP INDEX three_idx;"}, NoTransaction: true},
+	}
+}
+
+func expectLock(mock sqlmock.Sqlmock, tableExists bool) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectQuery(`SELECT to_regclass\('goose_db_version'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tableExists))
+
+	if !tableExists {
+		mock.ExpectBegin()
+		mock.ExpectExec(`CREATE TABLE goose_db_version`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO goose_db_version \(version_id, is_applied\) VALUES \(0, true\)`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	expectLock(mock, false)
+
+	mock.ExpectQuery(`SELECT version_id, is_applied, tstamp FROM goose_db_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied", "tstamp"}).
+			// Version 2 was rolled back by an older goose that kept the row.
+			AddRow(2, false, time.Now()).
+			AddRow(2, true, time.Now()).
+			AddRow(1, true, time.Now()))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE two`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO goose_db_version`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(`CREATE INDEX CONCURRENTLY three_idx`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO goose_db_version`).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	expectUnlock(mock)
+
+	ran, err := NewMigrator(db, testMigrations()).Up(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ran) != 2 || ran[0].Version != 2 || ran[1].Version != 3 {
+		t.Errorf("got migrations %v, want versions 2 and 3", ran)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	expectLock(mock, true)
+
+	mock.ExpectQuery(`SELECT version_id, is_applied, tstamp FROM goose_db_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied", "tstamp"}).
+			AddRow(2, true, time.Now()).
+			AddRow(1, true, time.Now()))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DROP TABLE two`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM goose_db_version WHERE version_id = \$1`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expectUnlock(mock)
+
+	rolledBack, err := NewMigrator(db, testMigrations()).Down(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rolledBack == nil || rolledBack.Version != 2 {
+		t.Errorf("got %v, want version 2 rolled back", rolledBack)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_To(t *testing.T) {
+	t.Run("down to version", func(t *testing.T) {
+		db, mock := newMockDB(t)
+
+		expectLock(mock, true)
+
+		mock.ExpectQuery(`SELECT version_id, is_applied, tstamp FROM goose_db_version`).
+			WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied", "tstamp"}).
+				AddRow(3, true, time.Now()).
+				AddRow(2, true, time.Now()).
+				AddRow(1, true, time.Now()))
+
+		mock.ExpectExec(`DROP INDEX three_idx`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM goose_db_version`).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DROP TABLE two`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM goose_db_version`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		expectUnlock(mock)
+
+		ran, err := NewMigrator(db, testMigrations()).To(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(ran) != 2 || ran[0].Version != 3 || ran[1].Version != 2 {
+			t.Errorf("got migrations %v, want versions 3 and 2", ran)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		db, _ := newMockDB(t)
+
+		_, err := NewMigrator(db, testMigrations()).To(context.Background(), 9)
+		if err == nil {
+			t.Error("expected an error for an unknown version")
+		}
+	})
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	expectLock(mock, true)
+
+	mock.ExpectQuery(`SELECT version_id, is_applied, tstamp FROM goose_db_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied", "tstamp"}))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE one`).WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	expectUnlock(mock)
+
+	ran, err := NewMigrator(db, testMigrations()).Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "00001_one.sql") {
+		t.Errorf("got error %v, want one naming the failed migration", err)
+	}
+
+	if len(ran) != 0 {
+		t.Errorf("got %d migrations run, want 0", len(ran))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	appliedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	expectLock(mock, true)
+
+	mock.ExpectQuery(`SELECT version_id, is_applied, tstamp FROM goose_db_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "is_applied", "tstamp"}).
+			AddRow(1, true, appliedAt))
+
+	expectUnlock(mock)
+
+	statuses, err := NewMigrator(db, testMigrations()).Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statuses) != 3 {
+		t.Fatalf("got %d statuses, want 3", len(statuses))
+	}
+
+	if !statuses[0].Applied() || !statuses[0].AppliedAt.Equal(appliedAt) {
+		t.Errorf("got applied at %v, want %v", statuses[0].AppliedAt, appliedAt)
+	}
+
+	if statuses[1].Applied() || statuses[2].Applied() {
+		t.Error("expected later migrations to be pending")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package pgsql
+
+import (
+	"context"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+type PermissionService struct {
+	db DBTX
+}
+
+func NewPermissionService(db DBTX) *PermissionService {
+	return &PermissionService{db: db}
+}
+
+func (ps *PermissionService) GetAllForUser(userID int64) (models.Permissions, error) {
+	query := `
+    SELECT permissions.code
+    FROM permissions
+    INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+    WHERE users_permissions.user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ps.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions models.Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (ps *PermissionService) AddForUser(userID int64, codes ...string) error {
+	query := `
+    INSERT INTO users_permissions
+    SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+    ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := ps.db.ExecContext(ctx, query, userID, pq.Array(codes))
+	return err
+}
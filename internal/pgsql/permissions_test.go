@@ -0,0 +1,89 @@
+package pgsql
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestPermissionService_GetAllForUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT permissions.code FROM permissions .+ WHERE users_permissions.user_id = \$1`).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("feeds:read").AddRow("admin"))
+
+	ps := NewPermissionService(db)
+
+	permissions, err := ps.GetAllForUser(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := models.Permissions{"feeds:read", "admin"}
+	if !slices.Equal(permissions, want) {
+		t.Errorf("got permissions %v, want %v", permissions, want)
+	}
+	if permissions.Include(models.PermissionFeedsWrite) {
+		t.Error("expected feeds:write not to be included")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPermissionService_GetAllForUser_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT permissions.code`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	ps := NewPermissionService(db)
+
+	permissions, err := ps.GetAllForUser(3)
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+	if permissions != nil {
+		t.Error("expected nil permissions")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPermissionService_AddForUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO users_permissions SELECT \$1, permissions.id FROM permissions WHERE permissions.code = ANY\(\$2\)`).
+		WithArgs(int64(3), pq.Array([]string{"feeds:read", "feeds:write"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	ps := NewPermissionService(db)
+
+	err = ps.AddForUser(3, "feeds:read", "feeds:write")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
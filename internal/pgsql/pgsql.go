@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"math/rand/v2"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
 )
 
 // DBTX abstracts query methods shared by *sql.DB and *sql.Tx.
@@ -20,14 +23,53 @@ type DBTX interface {
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-// Verify DB implements DBTX at compile time.
-var _ DBTX = (*DB)(nil)
+// Verify DB and transactions implement DBTX at compile time.
+var (
+	_ DBTX = (*DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
 
+// The errors are shared with the other storage backends.
 var (
-	ErrRecordNotFound = errors.New("record not found")
-	ErrEditConflict   = errors.New("edit conflict")
+	ErrRecordNotFound = models.ErrRecordNotFound
+	ErrEditConflict   = models.ErrEditConflict
+	ErrDuplicateEmail = models.ErrDuplicateEmail
+	ErrDuplicateURL   = models.ErrDuplicateURL
+
+	ErrDuplicateSubscription = models.ErrDuplicateSubscription
+	ErrDuplicateFolder       = models.ErrDuplicateFolder
 )
 
+// defaultQueryTimeout bounds queries when a service's QueryTimeout isn't
+// configured.
+const defaultQueryTimeout = 3 * time.Second
+
+// contextErr returns the context's error in place of err when the query failed
+// because ctx ended. Postgres reports a cancelled query as an ordinary server
+// error, which would otherwise hide that the client went away or the query
+// ran out of time.
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// isSerializationFailure reports whether err is postgres aborting a
+// transaction that conflicted with a concurrent one. Such transactions can
+// succeed if run again.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "40001"
+}
+
+// isUniqueViolation reports whether err is postgres rejecting a write because
+// it would break the named unique constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
 type DB struct {
 	dsn string
 	db  *sql.DB
@@ -71,6 +113,104 @@ func (pg *DB) Close() error {
 	return nil
 }
 
+const (
+	// maxTxAttempts bounds how many times WithTx runs a transaction that
+	// keeps failing with serialization errors.
+	maxTxAttempts = 3
+
+	// txRetryDelay is the base delay before retrying a transaction. It grows
+	// with each attempt, plus jitter so that conflicting transactions don't
+	// retry in lockstep.
+	txRetryDelay = 20 * time.Millisecond
+)
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back if it returns an error or panics. Services are bound to the
+// transaction by passing tx to their constructors, e.g. NewFeedService(tx).
+//
+// opts sets the isolation level, or the database default when nil. A
+// transaction that fails with a serialization error, as can happen under
+// repeatable read or serializable isolation, is retried from the start, so fn
+// must be safe to run more than once.
+func (pg *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx DBTX) error) error {
+	var err error
+
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = pg.runTx(ctx, opts, fn)
+		if !isSerializationFailure(err) || attempt == maxTxAttempts {
+			break
+		}
+
+		delay := time.Duration(attempt)*txRetryDelay + rand.N(txRetryDelay)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return err
+}
+
+func (pg *DB) runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx DBTX) error) error {
+	tx, err := pg.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stats returns the connection pool statistics.
+func (pg *DB) Stats() sql.DBStats {
+	return pg.db.Stats()
+}
+
+// RegisterMetrics exposes the connection pool statistics on r. They are read
+// from the pool each time the metrics are collected.
+func (pg *DB) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.", func() float64 {
+		return float64(pg.Stats().MaxOpenConnections)
+	})
+	r.NewGaugeFunc("db_open_connections", "Number of established connections, both in use and idle.", func() float64 {
+		return float64(pg.Stats().OpenConnections)
+	})
+	r.NewGaugeFunc("db_in_use_connections", "Number of connections currently in use.", func() float64 {
+		return float64(pg.Stats().InUse)
+	})
+	r.NewGaugeFunc("db_idle_connections", "Number of idle connections.", func() float64 {
+		return float64(pg.Stats().Idle)
+	})
+	r.NewCounterFunc("db_wait_count_total", "Number of connections waited for.", func() float64 {
+		return float64(pg.Stats().WaitCount)
+	})
+	r.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for new connections.", func() float64 {
+		return pg.Stats().WaitDuration.Seconds()
+	})
+	r.NewCounterFunc("db_max_idle_closed_total", "Number of connections closed due to the idle connection limit.", func() float64 {
+		return float64(pg.Stats().MaxIdleClosed)
+	})
+	r.NewCounterFunc("db_max_idle_time_closed_total", "Number of connections closed due to the maximum idle time.", func() float64 {
+		return float64(pg.Stats().MaxIdleTimeClosed)
+	})
+	r.NewCounterFunc("db_max_lifetime_closed_total", "Number of connections closed due to the maximum connection lifetime.", func() float64 {
+		return float64(pg.Stats().MaxLifetimeClosed)
+	})
+}
+
 // DBTX interface implementation
 
 func (pg *DB) Exec(query string, args ...any) (sql.Result, error) {
@@ -0,0 +1,254 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/models/modelstest"
+	"github.com/grodier/rss-app/migrations"
+	"github.com/lib/pq"
+)
+
+func newMockDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &DB{db: db}, mock
+}
+
+// newConformanceDB opens and migrates the database named by
+// RSSAPP_TEST_DB_DSN for the checks shared with the other storage backends,
+// skipping the test when it isn't set.
+func newConformanceDB(t *testing.T) *DB {
+	t.Helper()
+
+	dsn := os.Getenv("RSSAPP_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("RSSAPP_TEST_DB_DSN not set")
+	}
+
+	db := NewDB(dsn)
+	if err := db.Open(); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ms, err := migrations.Parse(migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to parse migrations: %v", err)
+	}
+
+	if _, err := NewMigrator(db, ms).Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return db
+}
+
+// TestServices_Conformance runs the checks shared with the other storage
+// backends against a real database, whose users, feeds and everything
+// referencing them are deleted. It is skipped unless RSSAPP_TEST_DB_DSN is
+// set.
+func TestServices_Conformance(t *testing.T) {
+	db := newConformanceDB(t)
+
+	modelstest.TestServices(t, func(t *testing.T) *models.Services {
+		if _, err := db.Exec(`TRUNCATE users, feeds RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("failed to empty users and feeds: %v", err)
+		}
+
+		return &models.Services{
+			Feeds:         NewFeedService(db),
+			Items:         NewItemService(db),
+			Users:         NewUserService(db),
+			Tokens:        NewTokenService(db),
+			Permissions:   NewPermissionService(db),
+			Subscriptions: NewSubscriptionService(db),
+			ItemStates:    NewItemStateService(db),
+			Folders:       NewFolderService(db),
+		}
+	})
+}
+
+func TestDB_WithTx_Commit(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM feeds WHERE id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		return NewFeedService(tx).Delete(context.Background(), 1)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDB_WithTx_RollbackOnError(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM feeds WHERE id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		return NewFeedService(tx).Delete(context.Background(), 1)
+	})
+	if err != ErrRecordNotFound {
+		t.Errorf("got error %v, want %v", err, ErrRecordNotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDB_WithTx_RollbackOnPanic(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic to be re-raised")
+			}
+		}()
+
+		db.WithTx(context.Background(), nil, func(tx DBTX) error {
+			panic("boom")
+		})
+	}()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDB_WithTx_RetriesSerializationFailures(t *testing.T) {
+	serializationFailure := &pq.Error{Code: "40001"}
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantAttempts int
+		wantError    error
+	}{
+		{"succeeds after retry", 1, 2, nil},
+		{"gives up", maxTxAttempts, maxTxAttempts, serializationFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+
+			for range tt.failures {
+				mock.ExpectBegin()
+				mock.ExpectCommit().WillReturnError(serializationFailure)
+			}
+			if tt.failures < maxTxAttempts {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			}
+
+			attempts := 0
+			err := db.WithTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx DBTX) error {
+				attempts++
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDB_WithTx_DoesNotRetryOtherErrors(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	attempts := 0
+	wantErr := errors.New("invalid feed")
+
+	err := db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		attempts++
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+	if attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDB_WithTx_ServicesShareTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO feeds`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(7, time.Now(), 1))
+	mock.ExpectQuery(`INSERT INTO folders`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(3, time.Now(), 1))
+	mock.ExpectRollback()
+
+	wantErr := errors.New("subscription failed")
+
+	err := db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		feed := &models.Feed{Title: "Feed", URL: "https://example.com/feed", SiteURL: "https://example.com"}
+		if err := NewFeedService(tx).Create(context.Background(), feed); err != nil {
+			return err
+		}
+
+		folder := &models.Folder{UserID: 1, Name: "News"}
+		if err := NewFolderService(tx).Create(folder); err != nil {
+			return err
+		}
+
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package pgsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestSubscriptionService_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO subscriptions`).
+		WithArgs(int64(1), int64(4), "My Title", int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(int64(9), createdAt, int32(1)))
+
+	ss := NewSubscriptionService(db)
+
+	subscription := &models.Subscription{UserID: 1, FeedID: 4, Title: "My Title", FolderID: 3}
+
+	err = ss.Create(subscription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if subscription.ID != 9 {
+		t.Errorf("expected ID 9, got %d", subscription.ID)
+	}
+	if !subscription.CreatedAt.Equal(createdAt) {
+		t.Errorf("expected CreatedAt %v, got %v", createdAt, subscription.CreatedAt)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSubscriptionService_Create_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		wantError error
+	}{
+		{"duplicate subscription", &pq.Error{Code: "23505", Constraint: "subscriptions_user_id_feed_id_key"}, ErrDuplicateSubscription},
+		{"database error", sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(`INSERT INTO subscriptions`).
+				WillReturnError(tt.mockError)
+
+			ss := NewSubscriptionService(db)
+
+			err = ss.Create(&models.Subscription{UserID: 1, FeedID: 4})
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_GetAllForUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "user_id", "feed_id", "title", "folder_id", "created_at", "version",
+		"id", "title", "description", "url", "site_url", "language", "created_at", "version",
+		"unread_count",
+	}).
+		AddRow(int64(9), int64(1), int64(4), "", int64(0), now, int32(1),
+			int64(4), "Feed Four", "", "https://four.example.com/feed.xml", "https://four.example.com", "en", now, int32(2),
+			12).
+		AddRow(int64(8), int64(1), int64(2), "Renamed", int64(3), now, int32(1),
+			int64(2), "Feed Two", "", "https://two.example.com/feed.xml", "https://two.example.com", "", now, int32(1),
+			0)
+
+	mock.ExpectQuery(`SELECT .+ FROM subscriptions s INNER JOIN feeds f ON f.id = s.feed_id WHERE s.user_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(rows)
+
+	ss := NewSubscriptionService(db)
+
+	subscriptions, err := ss.GetAllForUser(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(subscriptions) != 2 {
+		t.Fatalf("got %d subscriptions, want 2", len(subscriptions))
+	}
+	if subscriptions[0].Feed == nil || subscriptions[0].Feed.Title != "Feed Four" {
+		t.Errorf("got feed %+v, want Feed Four", subscriptions[0].Feed)
+	}
+	if subscriptions[0].UnreadCount != 12 {
+		t.Errorf("got unread count %d, want 12", subscriptions[0].UnreadCount)
+	}
+	if subscriptions[1].Title != "Renamed" || subscriptions[1].FolderID != 3 {
+		t.Errorf("got title %q and folder %d", subscriptions[1].Title, subscriptions[1].FolderID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSubscriptionService_Get(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int64
+		mockError error
+		wantError error
+	}{
+		{"success", 9, nil, nil},
+		{"invalid id", 0, nil, ErrRecordNotFound},
+		{"not found or not owned", 9, sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", 9, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectQuery(`SELECT .+ FROM subscriptions WHERE id = \$1 AND user_id = \$2`).
+					WithArgs(tt.id, int64(1))
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "feed_id", "title", "folder_id", "created_at", "version"}).
+						AddRow(tt.id, int64(1), int64(4), "", int64(3), time.Now(), int32(1)))
+				}
+			}
+
+			ss := NewSubscriptionService(db)
+
+			subscription, err := ss.Get(tt.id, 1)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError == nil && subscription.FolderID != 3 {
+				t.Errorf("got folder %d, want 3", subscription.FolderID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_Update(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int64
+		mockError error
+		wantError error
+	}{
+		{"success", 9, nil, nil},
+		{"invalid id", 0, nil, ErrRecordNotFound},
+		{"edit conflict", 9, sql.ErrNoRows, ErrEditConflict},
+		{"database error", 9, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectQuery(`UPDATE subscriptions SET .+ WHERE id = \$3 AND user_id = \$4 AND version = \$5`).
+					WithArgs("Renamed", int64(0), tt.id, int64(1), int32(1))
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int32(2)))
+				}
+			}
+
+			ss := NewSubscriptionService(db)
+
+			subscription := &models.Subscription{ID: tt.id, UserID: 1, Title: "Renamed", Version: 1}
+
+			err = ss.Update(subscription)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+			if tt.wantError == nil && subscription.Version != 2 {
+				t.Errorf("expected Version 2, got %d", subscription.Version)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestSubscriptionService_Delete(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           int64
+		mockError    error
+		rowsAffected int64
+		wantError    error
+	}{
+		{"success", 9, nil, 1, nil},
+		{"invalid id", 0, nil, 0, ErrRecordNotFound},
+		{"not found or not owned", 9, nil, 0, ErrRecordNotFound},
+		{"database error", 9, sqlmock.ErrCancelled, 0, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			if tt.id >= 1 {
+				exp := mock.ExpectExec(`DELETE FROM subscriptions WHERE id = \$1 AND user_id = \$2`).
+					WithArgs(tt.id, int64(1))
+				if tt.mockError != nil {
+					exp.WillReturnError(tt.mockError)
+				} else {
+					exp.WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+				}
+			}
+
+			ss := NewSubscriptionService(db)
+
+			err = ss.Delete(tt.id, 1)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
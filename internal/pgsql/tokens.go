@@ -0,0 +1,42 @@
+package pgsql
+
+import (
+	"context"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type TokenService struct {
+	db DBTX
+}
+
+func NewTokenService(db DBTX) *TokenService {
+	return &TokenService{db: db}
+}
+
+func (ts *TokenService) Create(token *models.Token) error {
+	query := `
+    INSERT INTO tokens (hash, user_id, expiry, scope)
+    VALUES ($1, $2, $3, $4)`
+
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := ts.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (ts *TokenService) DeleteAllForUser(scope string, userID int64) error {
+	query := `
+    DELETE FROM tokens
+    WHERE scope = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := ts.db.ExecContext(ctx, query, scope, userID)
+	return err
+}
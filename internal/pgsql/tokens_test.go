@@ -0,0 +1,79 @@
+package pgsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestTokenService_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	token := models.GenerateToken(3, time.Hour, models.ScopeAuthentication)
+
+	mock.ExpectExec(`INSERT INTO tokens`).
+		WithArgs(token.Hash, int64(3), token.Expiry, models.ScopeAuthentication).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ts := NewTokenService(db)
+
+	err = ts.Create(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTokenService_Create_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO tokens`).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	ts := NewTokenService(db)
+
+	err = ts.Create(models.GenerateToken(3, time.Hour, models.ScopeAuthentication))
+	if err != sqlmock.ErrCancelled {
+		t.Errorf("got error %v, want %v", err, sqlmock.ErrCancelled)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTokenService_DeleteAllForUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM tokens WHERE scope = \$1 AND user_id = \$2`).
+		WithArgs(models.ScopeAuthentication, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	ts := NewTokenService(db)
+
+	err = ts.DeleteAllForUser(models.ScopeAuthentication, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package pgsql
+
+import (
+	"context"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// Transactor implements models.Transactor with DB.WithTx.
+type Transactor struct {
+	db *DB
+
+	// QueryTimeout is used as the QueryTimeout of the feed services bound to
+	// each transaction.
+	QueryTimeout time.Duration
+}
+
+func NewTransactor(db *DB) *Transactor {
+	return &Transactor{db: db, QueryTimeout: defaultQueryTimeout}
+}
+
+func (t *Transactor) WithTx(ctx context.Context, fn func(tx *models.Services) error) error {
+	return t.db.WithTx(ctx, nil, func(tx DBTX) error {
+		feeds := NewFeedService(tx)
+		feeds.QueryTimeout = t.QueryTimeout
+
+		return fn(&models.Services{
+			Feeds:         feeds,
+			Items:         NewItemService(tx),
+			Users:         NewUserService(tx),
+			Tokens:        NewTokenService(tx),
+			Permissions:   NewPermissionService(tx),
+			Subscriptions: NewSubscriptionService(tx),
+			ItemStates:    NewItemStateService(tx),
+			Folders:       NewFolderService(tx),
+		})
+	})
+}
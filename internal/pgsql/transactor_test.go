@@ -0,0 +1,65 @@
+package pgsql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestTransactor_WithTx(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(7, time.Now(), 1))
+	mock.ExpectExec(`INSERT INTO users_permissions`).
+		WithArgs(int64(7), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := NewTransactor(db).WithTx(context.Background(), func(tx *models.Services) error {
+		user := &models.User{Email: "alice@example.com", Password: models.Password{Hash: []byte("hash")}}
+		if err := tx.Users.Create(user); err != nil {
+			return err
+		}
+
+		return tx.Permissions.AddForUser(user.ID, models.DefaultPermissions...)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTransactor_WithTx_Rollback(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+	mock.ExpectRollback()
+
+	err := NewTransactor(db).WithTx(context.Background(), func(tx *models.Services) error {
+		user := &models.User{Email: "alice@example.com", Password: models.Password{Hash: []byte("hash")}}
+		if err := tx.Users.Create(user); err != nil {
+			return err
+		}
+
+		t.Error("expected the transaction to stop at the duplicate email")
+		return nil
+	})
+	if err != ErrDuplicateEmail {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateEmail)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
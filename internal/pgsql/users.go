@@ -0,0 +1,110 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type UserService struct {
+	db DBTX
+}
+
+func NewUserService(db DBTX) *UserService {
+	return &UserService{db: db}
+}
+
+func (us *UserService) Create(user *models.User) error {
+	query := `
+    INSERT INTO users (email, password_hash)
+    VALUES ($1, $2)
+    RETURNING id, created_at, version`
+
+	args := []any{user.Email, user.Password.Hash}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := us.db.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "users_email_key"):
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (us *UserService) GetByEmail(email string) (*models.User, error) {
+	query := `
+    SELECT id, created_at, email, password_hash, version
+    FROM users
+    WHERE email = $1`
+
+	var user models.User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := us.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// GetForToken returns the user owning an unexpired token with the given scope.
+func (us *UserService) GetForToken(scope, tokenPlaintext string) (*models.User, error) {
+	query := `
+    SELECT users.id, users.created_at, users.email, users.password_hash, users.version
+    FROM users
+    INNER JOIN tokens
+    ON users.id = tokens.user_id
+    WHERE tokens.hash = $1
+    AND tokens.scope = $2
+    AND tokens.expiry > $3`
+
+	args := []any{models.HashToken(tokenPlaintext), scope, time.Now()}
+
+	var user models.User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := us.db.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,211 @@
+package pgsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestUserService_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Now()
+	hash := []byte("hash")
+
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("user@example.com", hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "version"}).AddRow(int64(1), createdAt, int32(1)))
+
+	us := NewUserService(db)
+
+	user := &models.User{Email: "user@example.com", Password: models.Password{Hash: hash}}
+
+	err = us.Create(user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.ID != 1 {
+		t.Errorf("expected ID 1, got %d", user.ID)
+	}
+	if !user.CreatedAt.Equal(createdAt) {
+		t.Errorf("expected CreatedAt %v, got %v", createdAt, user.CreatedAt)
+	}
+	if user.Version != 1 {
+		t.Errorf("expected Version 1, got %d", user.Version)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUserService_Create_Errors(t *testing.T) {
+	otherViolation := &pq.Error{Code: "23505", Constraint: "users_pkey"}
+
+	tests := []struct {
+		name      string
+		mockError error
+		wantError error
+	}{
+		{"duplicate email", &pq.Error{Code: "23505", Constraint: "users_email_key"}, ErrDuplicateEmail},
+		{"other constraint", otherViolation, otherViolation},
+		{"database error", sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(`INSERT INTO users`).
+				WillReturnError(tt.mockError)
+
+			us := NewUserService(db)
+
+			err = us.Create(&models.User{Email: "user@example.com", Password: models.Password{Hash: []byte("hash")}})
+
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestUserService_GetByEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`SELECT .+ FROM users WHERE email = \$1`).
+		WithArgs("user@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "email", "password_hash", "version"}).
+			AddRow(int64(3), createdAt, "user@example.com", []byte("hash"), int32(1)))
+
+	us := NewUserService(db)
+
+	user, err := us.GetByEmail("user@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.ID != 3 {
+		t.Errorf("expected ID 3, got %d", user.ID)
+	}
+	if string(user.Password.Hash) != "hash" {
+		t.Errorf("expected password hash %q, got %q", "hash", user.Password.Hash)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUserService_GetByEmail_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		wantError error
+	}{
+		{"record not found", sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(`SELECT .+ FROM users WHERE email = \$1`).
+				WillReturnError(tt.mockError)
+
+			us := NewUserService(db)
+
+			user, err := us.GetByEmail("user@example.com")
+
+			if user != nil {
+				t.Error("expected nil user")
+			}
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestUserService_GetForToken(t *testing.T) {
+	const plaintext = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	tests := []struct {
+		name      string
+		mockError error
+		wantError error
+	}{
+		{"success", nil, nil},
+		{"unknown or expired token", sql.ErrNoRows, ErrRecordNotFound},
+		{"database error", sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			exp := mock.ExpectQuery(`SELECT .+ FROM users INNER JOIN tokens .+ WHERE tokens.hash = \$1 AND tokens.scope = \$2 AND tokens.expiry > \$3`).
+				WithArgs(models.HashToken(plaintext), models.ScopeAuthentication, sqlmock.AnyArg())
+			if tt.mockError != nil {
+				exp.WillReturnError(tt.mockError)
+			} else {
+				exp.WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "email", "password_hash", "version"}).
+					AddRow(int64(3), time.Now(), "user@example.com", []byte("hash"), int32(1)))
+			}
+
+			us := NewUserService(db)
+
+			user, err := us.GetForToken(models.ScopeAuthentication, plaintext)
+			if err != tt.wantError {
+				t.Errorf("got error %v, want %v", err, tt.wantError)
+			}
+
+			if tt.wantError == nil && user.ID != 3 {
+				t.Errorf("expected ID 3, got %d", user.ID)
+			}
+			if tt.wantError != nil && user != nil {
+				t.Error("expected nil user")
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// mockFeedService is a mock implementation of models.FeedService for testing.
+// Only the methods used by the poller are configurable.
+type mockFeedService struct {
+	claimDueFn         func(limit int, lease time.Duration) ([]*models.Feed, error)
+	updateFetchStateFn func(feed *models.Feed) error
+}
+
+func (m *mockFeedService) Create(ctx context.Context, feed *models.Feed) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockFeedService) Get(ctx context.Context, id int64) (*models.Feed, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFeedService) GetByURL(ctx context.Context, url string) (*models.Feed, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFeedService) GetAll(ctx context.Context, title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+	return nil, models.Metadata{}, errors.New("not implemented")
+}
+
+func (m *mockFeedService) Update(ctx context.Context, feed *models.Feed) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockFeedService) Delete(ctx context.Context, id int64) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockFeedService) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Feed, error) {
+	if m.claimDueFn != nil {
+		return m.claimDueFn(limit, lease)
+	}
+	return []*models.Feed{}, nil
+}
+
+func (m *mockFeedService) UpdateFetchState(ctx context.Context, feed *models.Feed) error {
+	if m.updateFetchStateFn != nil {
+		return m.updateFetchStateFn(feed)
+	}
+	return nil
+}
+
+// mockItemService records upserted items. It is safe for concurrent use since
+// the poller stores items from several workers at once.
+type mockItemService struct {
+	mu       sync.Mutex
+	items    []*models.Item
+	upsertFn func(item *models.Item) error
+}
+
+func (m *mockItemService) Upsert(item *models.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.upsertFn != nil {
+		if err := m.upsertFn(item); err != nil {
+			return err
+		}
+	}
+
+	m.items = append(m.items, item)
+	item.ID = int64(len(m.items))
+	return nil
+}
+
+func (m *mockItemService) Get(id int64) (*models.Item, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockItemService) GetAllForFeed(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	return nil, models.CursorMetadata{}, errors.New("not implemented")
+}
+
+func (m *mockItemService) GetAllForFolder(folderID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	return nil, models.CursorMetadata{}, errors.New("not implemented")
+}
+
+func (m *mockItemService) Search(userID int64, search models.ItemSearch, filters models.Filters) ([]*models.SearchResult, models.Metadata, error) {
+	return nil, models.Metadata{}, errors.New("not implemented")
+}
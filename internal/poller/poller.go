@@ -0,0 +1,231 @@
+package poller
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/grodier/rss-app/internal/feedparser"
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/validator"
+)
+
+// lease is how long a claimed feed is hidden from other pollers while it is
+// being fetched. Feeds whose fetch is interrupted become due again after it.
+const lease = 10 * time.Minute
+
+type Poller struct {
+	Interval time.Duration
+	Workers  int
+	Jitter   time.Duration
+
+	FeedService models.FeedService
+	ItemService models.ItemService
+	Fetcher     *fetcher.Fetcher
+
+	logger  *slog.Logger
+	metrics *pollerMetrics
+}
+
+type pollerMetrics struct {
+	fetches     *metrics.Counter
+	itemsStored *metrics.Counter
+}
+
+func NewPoller(logger *slog.Logger) *Poller {
+	return &Poller{
+		Interval: 30 * time.Minute,
+		Workers:  4,
+		logger:   logger,
+	}
+}
+
+// RegisterMetrics records the poller's fetches and stored items on r.
+func (p *Poller) RegisterMetrics(r *metrics.Registry) {
+	p.metrics = &pollerMetrics{
+		fetches:     r.NewCounter("poller_fetches_total", "Number of feed fetches by result.", "result"),
+		itemsStored: r.NewCounter("poller_items_stored_total", "Number of new or changed items stored."),
+	}
+}
+
+// Run refreshes due feeds until ctx is cancelled, then waits for in-flight
+// fetches to finish before returning.
+func (p *Poller) Run(ctx context.Context) {
+	p.logger.Info("starting poller", "interval", p.Interval, "workers", p.Workers, "jitter", p.Jitter)
+
+	ticker := time.NewTicker(min(p.Interval, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		p.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("stopped poller")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll claims due feeds in batches and refreshes them on a bounded pool of
+// workers until no due feeds remain.
+func (p *Poller) poll(ctx context.Context) {
+	batchSize := p.Workers * 4
+
+	for ctx.Err() == nil {
+		feeds, err := p.FeedService.ClaimDue(ctx, batchSize, lease)
+		if err != nil {
+			p.logger.Error("failed to claim due feeds", "error", err)
+			return
+		}
+
+		if len(feeds) == 0 {
+			return
+		}
+
+		jobs := make(chan *models.Feed)
+		var wg sync.WaitGroup
+
+		for range min(p.Workers, len(feeds)) {
+			wg.Go(func() {
+				for feed := range jobs {
+					p.refresh(ctx, feed)
+				}
+			})
+		}
+
+	dispatch:
+		for _, feed := range feeds {
+			select {
+			case jobs <- feed:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+
+		close(jobs)
+		wg.Wait()
+
+		if len(feeds) < batchSize {
+			return
+		}
+	}
+}
+
+func (p *Poller) refresh(ctx context.Context, feed *models.Feed) {
+	res, err := p.Fetcher.FetchConditional(ctx, feed.URL, feed.ETag, feed.LastModified)
+	switch {
+	case err != nil:
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Warn("failed to fetch feed", "feed_id", feed.ID, "url", feed.URL, "error", err)
+		p.countFetch("error")
+	case res.NotModified:
+		p.logger.Debug("feed not modified", "feed_id", feed.ID)
+		feed.ETag = res.ETag
+		feed.LastModified = res.LastModified
+		p.countFetch("not_modified")
+	default:
+		// Items that failed to store would never be fetched again once the
+		// upstream answers 304, so the old validators are kept and the next
+		// poll does a full GET.
+		if failed := p.storeItems(feed, res.Result.Entries); failed == 0 {
+			feed.ETag = res.ETag
+			feed.LastModified = res.LastModified
+		}
+		p.countFetch("ok")
+	}
+
+	feed.NextFetchAt = p.nextFetchAt()
+
+	// Record the outcome even when shutting down, so the feed isn't fetched
+	// again as soon as its lease runs out.
+	err = p.FeedService.UpdateFetchState(context.WithoutCancel(ctx), feed)
+	if err != nil {
+		p.logger.Error("failed to update feed fetch state", "feed_id", feed.ID, "error", err)
+	}
+}
+
+// storeItems upserts the feed's valid entries and returns how many of them
+// could not be stored.
+func (p *Poller) storeItems(feed *models.Feed, entries []*feedparser.Entry) int {
+	stored, failed := 0, 0
+
+	for _, entry := range entries {
+		item := newItem(feed.ID, entry)
+
+		v := validator.NewValidator()
+		if models.ValidateItem(v, item); !v.Valid() {
+			p.logger.Debug("skipping invalid item", "feed_id", feed.ID, "errors", v.Errors)
+			continue
+		}
+
+		err := p.ItemService.Upsert(item)
+		if err != nil {
+			p.logger.Error("failed to store item", "feed_id", feed.ID, "guid", item.GUID, "error", err)
+			failed++
+			continue
+		}
+
+		if item.ID != 0 {
+			stored++
+		}
+	}
+
+	p.logger.Info("refreshed feed", "feed_id", feed.ID, "entries", len(entries), "stored", stored, "failed", failed)
+
+	if p.metrics != nil {
+		p.metrics.itemsStored.Add(float64(stored))
+	}
+
+	return failed
+}
+
+func (p *Poller) countFetch(result string) {
+	if p.metrics != nil {
+		p.metrics.fetches.Inc(result)
+	}
+}
+
+func (p *Poller) nextFetchAt() time.Time {
+	next := time.Now().Add(p.Interval)
+	if p.Jitter > 0 {
+		next = next.Add(rand.N(p.Jitter))
+	}
+	return next
+}
+
+func newItem(feedID int64, entry *feedparser.Entry) *models.Item {
+	item := &models.Item{
+		FeedID:      feedID,
+		GUID:        entry.GUID,
+		Title:       entry.Title,
+		Link:        entry.Link,
+		Author:      entry.Author,
+		Content:     entry.Content,
+		Summary:     entry.Summary,
+		PublishedAt: entry.Published,
+		UpdatedAt:   entry.Updated,
+	}
+
+	// Entries without an id or link are identified by their content instead.
+	if item.GUID == "" {
+		item.GUID = item.Hash()
+	}
+
+	if item.PublishedAt.IsZero() {
+		item.PublishedAt = time.Now()
+	}
+
+	if item.UpdatedAt.IsZero() {
+		item.UpdatedAt = item.PublishedAt
+	}
+
+	return item
+}
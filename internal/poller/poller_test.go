@@ -0,0 +1,306 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/feedparser"
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/models"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0">
+	<channel>
+		<title>Upstream Feed</title>
+		<link>https://upstream.example.com/</link>
+		<description>Served by httptest</description>
+		<item>
+			<title>First</title>
+			<guid>first</guid>
+			<pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate>
+		</item>
+		<item>
+			<title>Second</title>
+			<guid>second</guid>
+		</item>
+	</channel>
+</rss>`
+
+func newTestPoller(feeds *mockFeedService, items *mockItemService) *Poller {
+	p := NewPoller(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	p.Interval = time.Hour
+	p.Jitter = time.Minute
+	p.Workers = 2
+	p.FeedService = feeds
+	p.ItemService = items
+	p.Fetcher = fetcher.NewFetcher()
+	// Upstreams are httptest servers on the loopback interface.
+	p.Fetcher.AllowPrivateAddresses = true
+	return p
+}
+
+func TestPoller_Poll(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(testFeed))
+	}))
+	defer upstream.Close()
+
+	var (
+		mu      sync.Mutex
+		claims  int
+		updated = map[int64]time.Time{}
+	)
+
+	feeds := &mockFeedService{
+		claimDueFn: func(limit int, l time.Duration) ([]*models.Feed, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			claims++
+			if limit != 8 {
+				t.Errorf("got limit %d, want 8", limit)
+			}
+			if l != lease {
+				t.Errorf("got lease %v, want %v", l, lease)
+			}
+			if claims > 1 {
+				return []*models.Feed{}, nil
+			}
+			return []*models.Feed{
+				{ID: 1, URL: upstream.URL + "/feed"},
+				{ID: 2, URL: upstream.URL + "/broken"},
+				{ID: 3, URL: upstream.URL + "/other"},
+			}, nil
+		},
+		updateFetchStateFn: func(feed *models.Feed) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			updated[feed.ID] = feed.NextFetchAt
+			return nil
+		},
+	}
+	items := &mockItemService{}
+
+	p := newTestPoller(feeds, items)
+
+	registry := metrics.NewRegistry()
+	p.RegisterMetrics(registry)
+
+	start := time.Now()
+	p.poll(context.Background())
+
+	if claims != 1 {
+		t.Errorf("got %d claims, want 1 for a partial batch", claims)
+	}
+
+	if len(items.items) != 4 {
+		t.Errorf("got %d stored items, want 4", len(items.items))
+	}
+
+	for _, id := range []int64{1, 2, 3} {
+		next, ok := updated[id]
+		if !ok {
+			t.Errorf("feed %d: fetch state was not updated", id)
+			continue
+		}
+		if next.Before(start.Add(p.Interval)) || next.After(time.Now().Add(p.Interval+p.Jitter)) {
+			t.Errorf("feed %d: got next fetch %v outside of interval and jitter", id, next)
+		}
+	}
+
+	var sb strings.Builder
+	registry.WriteTo(&sb)
+
+	for _, want := range []string{
+		`poller_fetches_total{result="ok"} 2`,
+		`poller_fetches_total{result="error"} 1`,
+		`poller_items_stored_total 4`,
+	} {
+		if !strings.Contains(sb.String(), want+"\n") {
+			t.Errorf("expected metrics to contain %q, got\n%s", want, sb.String())
+		}
+	}
+}
+
+func TestPoller_Poll_ConditionalGet(t *testing.T) {
+	const etag = `"v1"`
+
+	var gotIfNoneMatch string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfNoneMatch = r.Header.Get("If-None-Match")
+		if gotIfNoneMatch == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(testFeed))
+	}))
+	defer upstream.Close()
+
+	var stored *models.Feed
+
+	newFeeds := func(feed *models.Feed) *mockFeedService {
+		claimed := false
+		return &mockFeedService{
+			claimDueFn: func(limit int, lease time.Duration) ([]*models.Feed, error) {
+				if claimed {
+					return []*models.Feed{}, nil
+				}
+				claimed = true
+				return []*models.Feed{feed}, nil
+			},
+			updateFetchStateFn: func(feed *models.Feed) error {
+				stored = feed
+				return nil
+			},
+		}
+	}
+
+	t.Run("first fetch stores validators and items", func(t *testing.T) {
+		items := &mockItemService{}
+		p := newTestPoller(newFeeds(&models.Feed{ID: 1, URL: upstream.URL}), items)
+
+		p.poll(context.Background())
+
+		if gotIfNoneMatch != "" {
+			t.Errorf("got If-None-Match %q, want none", gotIfNoneMatch)
+		}
+		if len(items.items) != 2 {
+			t.Errorf("got %d stored items, want 2", len(items.items))
+		}
+		if stored == nil || stored.ETag != etag {
+			t.Fatalf("expected ETag %q to be persisted", etag)
+		}
+	})
+
+	t.Run("failed upsert keeps previous validators", func(t *testing.T) {
+		items := &mockItemService{
+			upsertFn: func(item *models.Item) error {
+				if item.GUID == "second" {
+					return errors.New("database connection failed")
+				}
+				return nil
+			},
+		}
+		p := newTestPoller(newFeeds(&models.Feed{ID: 1, URL: upstream.URL}), items)
+
+		stored = nil
+		p.poll(context.Background())
+
+		if stored == nil {
+			t.Fatal("expected fetch state to be updated")
+		}
+		if stored.ETag != "" {
+			t.Fatalf("got ETag %q, want none after a failed upsert", stored.ETag)
+		}
+
+		// The next poll fetches the whole feed again and stores what failed.
+		items.upsertFn = nil
+		p.FeedService = newFeeds(stored)
+		p.poll(context.Background())
+
+		if gotIfNoneMatch != "" {
+			t.Errorf("got If-None-Match %q, want none", gotIfNoneMatch)
+		}
+		if len(items.items) != 3 {
+			t.Errorf("got %d stored items, want 3", len(items.items))
+		}
+		if stored.ETag != etag {
+			t.Errorf("got ETag %q, want %q", stored.ETag, etag)
+		}
+	})
+
+	t.Run("not modified leaves items untouched", func(t *testing.T) {
+		items := &mockItemService{}
+		p := newTestPoller(newFeeds(&models.Feed{ID: 1, URL: upstream.URL, ETag: etag}), items)
+
+		stored = nil
+		p.poll(context.Background())
+
+		if gotIfNoneMatch != etag {
+			t.Errorf("got If-None-Match %q, want %q", gotIfNoneMatch, etag)
+		}
+		if len(items.items) != 0 {
+			t.Errorf("got %d stored items, want 0", len(items.items))
+		}
+		if stored == nil {
+			t.Fatal("expected fetch state to be updated")
+		}
+		if stored.ETag != etag {
+			t.Errorf("got ETag %q, want %q", stored.ETag, etag)
+		}
+		if stored.NextFetchAt.IsZero() {
+			t.Error("expected next fetch to be scheduled")
+		}
+	})
+}
+
+func TestPoller_Run_StopsOnCancel(t *testing.T) {
+	p := newTestPoller(&mockFeedService{}, &mockItemService{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poller did not stop after context cancellation")
+	}
+}
+
+func TestNewItem(t *testing.T) {
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("copies entry fields", func(t *testing.T) {
+		item := newItem(5, &feedparser.Entry{
+			GUID:      "guid-1",
+			Title:     "Title",
+			Link:      "https://example.com/1",
+			Published: published,
+		})
+
+		if item.FeedID != 5 {
+			t.Errorf("got FeedID %d, want 5", item.FeedID)
+		}
+		if item.GUID != "guid-1" {
+			t.Errorf("got GUID %q, want %q", item.GUID, "guid-1")
+		}
+		if !item.UpdatedAt.Equal(published) {
+			t.Errorf("got UpdatedAt %v, want published fallback %v", item.UpdatedAt, published)
+		}
+	})
+
+	t.Run("derives missing guid and dates", func(t *testing.T) {
+		item := newItem(5, &feedparser.Entry{Title: "No identifiers"})
+
+		if item.GUID == "" {
+			t.Error("expected GUID to be derived from content")
+		}
+		if item.PublishedAt.IsZero() {
+			t.Error("expected PublishedAt to default to the fetch time")
+		}
+	})
+}
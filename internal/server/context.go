@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type contextKey string
+
+const (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("request_id")
+	accessLogContextKey = contextKey("access_log")
+)
+
+func (s *Server) contextSetUser(r *http.Request, user *models.User) *http.Request {
+	// The access log runs outside authenticate and never sees this request,
+	// so the user is also recorded on its entry.
+	if entry, ok := r.Context().Value(accessLogContextKey).(*accessLogEntry); ok {
+		entry.user = user
+	}
+
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser panics when no user is set, since authenticate runs on every
+// route and a missing value means the middleware chain is misconfigured.
+func (s *Server) contextGetUser(r *http.Request) *models.User {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return user
+}
+
+func (s *Server) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID returns "" when no ID is set, so that errors can still
+// be logged from handlers tested outside the middleware chain.
+func (s *Server) contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
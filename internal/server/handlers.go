@@ -1,11 +1,16 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"time"
 
 	"github.com/grodier/rss-app/internal/models"
-	"github.com/grodier/rss-app/internal/pgsql"
+	"github.com/grodier/rss-app/internal/opml"
 	"github.com/grodier/rss-app/internal/validator"
 )
 
@@ -24,12 +29,16 @@ func (s *Server) handleHealthcheck(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleCreateFeed only requires a url. The feed is fetched and its title,
+// description, site URL and language are discovered from the document, with
+// any of those fields given in the request body taking precedence.
 func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Title       string `json:"title"`
 		Description string `json:"description"`
 		URL         string `json:"url"`
 		SiteURL     string `json:"site_url"`
+		Language    string `json:"language"`
 	}
 
 	err := s.readJSON(w, r, &input)
@@ -38,23 +47,55 @@ func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	feed := &models.Feed{
-		Title:       input.Title,
-		Description: input.Description,
-		URL:         input.URL,
-		SiteURL:     input.SiteURL,
+	v := validator.NewValidator()
+
+	if models.ValidateFeedURL(v, input.URL); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
-	v := validator.NewValidator()
+	fetchCtx, cancel := context.WithTimeout(r.Context(), fetchTimeout)
+	defer cancel()
+
+	res, err := s.FeedFetcher.Fetch(fetchCtx, input.URL)
+	if err != nil {
+		s.fetchFailedResponse(w, r, err)
+		return
+	}
+
+	feed := res.Result.Feed
+	feed.URL = input.URL
+
+	if input.Title != "" {
+		feed.Title = input.Title
+	}
+
+	if input.Description != "" {
+		feed.Description = input.Description
+	}
+
+	if input.SiteURL != "" {
+		feed.SiteURL = input.SiteURL
+	}
+
+	if input.Language != "" {
+		feed.Language = input.Language
+	}
 
 	if models.ValidateFeed(v, feed); !v.Valid() {
 		s.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = s.FeedService.Create(feed)
+	err = s.FeedService.Create(r.Context(), feed)
 	if err != nil {
-		s.serverErrorResponse(w, r, err)
+		switch {
+		case err == models.ErrDuplicateURL:
+			v.AddError("url", "a feed with this url already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -67,6 +108,122 @@ func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleDiscoverFeeds lists the feeds offered by a web page. The page and its
+// candidate feeds share a single fetch deadline.
+func (s *Server) handleDiscoverFeeds(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL string `json:"url"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.NewValidator()
+
+	if models.ValidateFeedURL(v, input.URL); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), fetchTimeout)
+	defer cancel()
+
+	candidates, err := s.FeedFetcher.Discover(ctx, input.URL)
+	if err != nil {
+		s.fetchFailedResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"feeds": candidates}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleListFeeds(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title    string
+		Language string
+		models.Filters
+	}
+
+	v := validator.NewValidator()
+
+	qs := r.URL.Query()
+
+	input.Title = s.readString(qs, "title", "")
+	input.Language = s.readString(qs, "language", "")
+
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = s.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "title", "created_at", "-id", "-title", "-created_at"}
+
+	if models.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feeds, metadata, err := s.FeedService.GetAll(r.Context(), input.Title, input.Language, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"feeds": feeds, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleSearchItems searches the items in the user's subscriptions, most
+// relevant first unless sorted by publication date.
+func (s *Server) handleSearchItems(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		models.ItemSearch
+		models.Filters
+	}
+
+	v := validator.NewValidator()
+
+	qs := r.URL.Query()
+
+	input.Query = s.readString(qs, "q", "")
+	input.PublishedAfter = s.readTime(qs, "published_after", v)
+	input.PublishedBefore = s.readTime(qs, "published_before", v)
+
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = s.readString(qs, "sort", "-rank")
+	input.Filters.SortSafelist = []string{"-rank", "published_at", "-published_at"}
+
+	models.ValidateItemSearch(v, input.ItemSearch)
+	models.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	results, metadata, err := s.ItemService.Search(user.ID, input.ItemSearch, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
 func (s *Server) handleShowFeed(w http.ResponseWriter, r *http.Request) {
 	id, err := s.readIDParam(r)
 	if err != nil {
@@ -74,10 +231,10 @@ func (s *Server) handleShowFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	feed, err := s.FeedService.Get(id)
+	feed, err := s.FeedService.Get(r.Context(), id)
 	if err != nil {
 		switch {
-		case err == pgsql.ErrRecordNotFound:
+		case err == models.ErrRecordNotFound:
 			s.notFoundResponse(w, r)
 		default:
 			s.serverErrorResponse(w, r, err)
@@ -98,10 +255,10 @@ func (s *Server) handleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	feed, err := s.FeedService.Get(id)
+	feed, err := s.FeedService.Get(r.Context(), id)
 	if err != nil {
 		switch {
-		case err == pgsql.ErrRecordNotFound:
+		case err == models.ErrRecordNotFound:
 			s.notFoundResponse(w, r)
 		default:
 			s.serverErrorResponse(w, r, err)
@@ -150,11 +307,14 @@ func (s *Server) handleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.FeedService.Update(feed)
+	err = s.FeedService.Update(r.Context(), feed)
 	if err != nil {
 		switch {
-		case err == pgsql.ErrEditConflict:
+		case err == models.ErrEditConflict:
 			s.editConflictResponse(w, r)
+		case err == models.ErrDuplicateURL:
+			v.AddError("url", "a feed with this url already exists")
+			s.failedValidationResponse(w, r, v.Errors)
 		default:
 			s.serverErrorResponse(w, r, err)
 		}
@@ -174,10 +334,10 @@ func (s *Server) handleDeleteFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.FeedService.Delete(id)
+	err = s.FeedService.Delete(r.Context(), id)
 	if err != nil {
 		switch {
-		case err == pgsql.ErrRecordNotFound:
+		case err == models.ErrRecordNotFound:
 			s.notFoundResponse(w, r)
 		default:
 			s.serverErrorResponse(w, r, err)
@@ -190,3 +350,890 @@ func (s *Server) handleDeleteFeed(w http.ResponseWriter, r *http.Request) {
 		s.serverErrorResponse(w, r, err)
 	}
 }
+
+func (s *Server) handleListFeedItems(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.NewValidator()
+
+	qs := r.URL.Query()
+
+	var filters models.CursorFilters
+
+	filters.After, err = models.DecodeCursor(s.readString(qs, "cursor", ""))
+	if err != nil {
+		v.AddError("cursor", "must be a cursor returned by a previous request")
+	}
+
+	filters.PageSize = s.readInt(qs, "page_size", 20, v)
+
+	if models.ValidateCursorFilters(v, filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Look the feed up first so an unknown feed is a 404 rather than an
+	// empty list.
+	_, err = s.FeedService.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	items, metadata, err := s.ItemService.GetAllForFeed(id, user.ID, filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"items": items, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleShowItem(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	item, err := s.ItemService.Get(id)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleRegisterUser(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.NewValidator()
+
+	models.ValidateEmail(v, input.Email)
+	models.ValidatePasswordPlaintext(v, input.Password)
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := &models.User{
+		Email: input.Email,
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// A user without permissions couldn't do anything, nor register again.
+	err = s.Transactor.WithTx(r.Context(), func(tx *models.Services) error {
+		err := tx.Users.Create(user)
+		if err != nil {
+			return err
+		}
+
+		return tx.Permissions.AddForUser(user.ID, models.DefaultPermissions...)
+	})
+	if err != nil {
+		switch {
+		case err == models.ErrDuplicateEmail:
+			v.AddError("email", "a user with this email address already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// dummyPassword is checked when no user has the given email, so that logging
+// in with an unknown address costs the same bcrypt work as a wrong password
+// and response times don't reveal which emails are registered. It uses the
+// cost Password.Set hashes with.
+var dummyPassword = models.Password{Hash: []byte("$2a$12$Ro0zp58EtDK2xehdY5kN4u6rm6jqNf9QUvEtOSQUWOKhiCScspOcS")}
+
+func (s *Server) handleCreateAuthenticationToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.NewValidator()
+
+	models.ValidateEmail(v, input.Email)
+	models.ValidatePasswordPlaintext(v, input.Password)
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := s.UserService.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			dummyPassword.Matches(input.Password)
+			s.invalidCredentialsResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		s.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	token := models.GenerateToken(user.ID, 24*time.Hour, models.ScopeAuthentication)
+
+	err = s.TokenService.Create(token)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleCreateSubscription subscribes the user to the feed at url. Feeds
+// already in the catalog are reused; unknown ones are fetched and added first.
+func (s *Server) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL      string `json:"url"`
+		Title    string `json:"title"`
+		FolderID int64  `json:"folder_id"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	subscription := &models.Subscription{
+		UserID:   user.ID,
+		Title:    input.Title,
+		FolderID: input.FolderID,
+	}
+
+	v := validator.NewValidator()
+
+	models.ValidateFeedURL(v, input.URL)
+	models.ValidateSubscription(v, subscription)
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.checkFolder(v, subscription)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feed, err := s.FeedService.GetByURL(r.Context(), input.URL)
+	if err == models.ErrRecordNotFound {
+		fetchCtx, cancel := context.WithTimeout(r.Context(), fetchTimeout)
+		defer cancel()
+
+		res, fetchErr := s.FeedFetcher.Fetch(fetchCtx, input.URL)
+		if fetchErr != nil {
+			s.fetchFailedResponse(w, r, fetchErr)
+			return
+		}
+
+		feed = res.Result.Feed
+		feed.URL = input.URL
+
+		if models.ValidateFeed(v, feed); !v.Valid() {
+			s.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = s.FeedService.Create(r.Context(), feed)
+		if err == models.ErrDuplicateURL {
+			// Another request added the feed since it was looked up.
+			feed, err = s.FeedService.GetByURL(r.Context(), input.URL)
+		}
+	}
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subscription.FeedID = feed.ID
+	subscription.Feed = feed
+
+	err = s.SubscriptionService.Create(subscription)
+	if err != nil {
+		switch {
+		case err == models.ErrDuplicateSubscription:
+			v.AddError("url", "you are already subscribed to this feed")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"subscription": subscription}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	user := s.contextGetUser(r)
+
+	subscriptions, err := s.SubscriptionService.GetAllForUser(user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"subscriptions": subscriptions}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleUpdateSubscription renames the subscription or moves it to another
+// folder. A folder_id of 0 moves it out of any folder.
+func (s *Server) handleUpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	subscription, err := s.SubscriptionService.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Title    *string `json:"title"`
+		FolderID *int64  `json:"folder_id"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		subscription.Title = *input.Title
+	}
+
+	if input.FolderID != nil {
+		subscription.FolderID = *input.FolderID
+	}
+
+	v := validator.NewValidator()
+
+	if models.ValidateSubscription(v, subscription); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The current folder is already known to be the user's own.
+	if input.FolderID != nil {
+		err = s.checkFolder(v, subscription)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.SubscriptionService.Update(subscription)
+	if err != nil {
+		switch {
+		case err == models.ErrEditConflict:
+			s.editConflictResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"subscription": subscription}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkFolder records a validation error if the subscription is being put in
+// a folder that doesn't belong to its user.
+func (s *Server) checkFolder(v *validator.Validator, subscription *models.Subscription) error {
+	if subscription.FolderID == 0 {
+		return nil
+	}
+
+	_, err := s.FolderService.Get(subscription.FolderID, subscription.UserID)
+	switch {
+	case err == models.ErrRecordNotFound:
+		v.AddError("folder_id", "must be one of your folders")
+	case err != nil:
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	err = s.SubscriptionService.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "subscription successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleUpdateItemState(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Read    *bool `json:"read"`
+		Starred *bool `json:"starred"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	update := models.ItemStateUpdate{Read: input.Read, Starred: input.Starred}
+
+	v := validator.NewValidator()
+
+	if models.ValidateItemStateUpdate(v, update); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	updated, err := s.ItemStateService.Update(user.ID, []int64{id}, update)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Items that don't exist or aren't in one of the user's subscriptions
+	// are not updated.
+	if updated == 0 {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "item state successfully updated"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleBulkUpdateItemState(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ItemIDs []int64 `json:"item_ids"`
+		Read    *bool   `json:"read"`
+		Starred *bool   `json:"starred"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	update := models.ItemStateUpdate{Read: input.Read, Starred: input.Starred}
+
+	v := validator.NewValidator()
+
+	models.ValidateItemIDs(v, input.ItemIDs)
+	models.ValidateItemStateUpdate(v, update)
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	updated, err := s.ItemStateService.Update(user.ID, input.ItemIDs, update)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"updated": updated}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleMarkSubscriptionRead marks every item in the subscription published
+// at or before the given time as read. It defaults to now, and the body may
+// be left out entirely.
+func (s *Server) handleMarkSubscriptionRead(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Before *time.Time `json:"before"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil && err != errEmptyBody {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	before := time.Now()
+	if input.Before != nil {
+		before = *input.Before
+	}
+
+	user := s.contextGetUser(r)
+
+	err = s.ItemStateService.MarkSubscriptionRead(id, user.ID, before)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "subscription successfully marked as read"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleCreateFolder(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	folder := &models.Folder{
+		UserID: user.ID,
+		Name:   input.Name,
+	}
+
+	v := validator.NewValidator()
+
+	if models.ValidateFolder(v, folder); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.FolderService.Create(folder)
+	if err != nil {
+		switch {
+		case err == models.ErrDuplicateFolder:
+			v.AddError("name", "a folder with this name already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/folders/%d", folder.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"folder": folder}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleListFolders(w http.ResponseWriter, r *http.Request) {
+	user := s.contextGetUser(r)
+
+	folders, err := s.FolderService.GetAllForUser(user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"folders": folders}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleShowFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	folder, err := s.FolderService.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"folder": folder}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *Server) handleUpdateFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	folder, err := s.FolderService.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		folder.Name = *input.Name
+	}
+
+	v := validator.NewValidator()
+
+	if models.ValidateFolder(v, folder); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.FolderService.Update(folder)
+	if err != nil {
+		switch {
+		case err == models.ErrEditConflict:
+			s.editConflictResponse(w, r)
+		case err == models.ErrDuplicateFolder:
+			v.AddError("name", "a folder with this name already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"folder": folder}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleDeleteFolder removes the folder. Its subscriptions are kept and moved
+// out of any folder.
+func (s *Server) handleDeleteFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	err = s.FolderService.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "folder successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleListFolderItems returns the items of every feed in the folder as a
+// single timeline, newest first, along with the folder's unread count.
+func (s *Server) handleListFolderItems(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.NewValidator()
+
+	qs := r.URL.Query()
+
+	var filters models.CursorFilters
+
+	filters.After, err = models.DecodeCursor(s.readString(qs, "cursor", ""))
+	if err != nil {
+		v.AddError("cursor", "must be a cursor returned by a previous request")
+	}
+
+	filters.PageSize = s.readInt(qs, "page_size", 20, v)
+
+	if models.ValidateCursorFilters(v, filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	folder, err := s.FolderService.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case err == models.ErrRecordNotFound:
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	items, metadata, err := s.ItemService.GetAllForFolder(folder.ID, user.ID, filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"folder": folder, "items": items, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleImportOPML imports an OPML file sent either as the request body or as
+// the "file" field of a multipart form. Every feed outline is reported on
+// separately, so one bad entry doesn't fail the whole import. Feeds new to the
+// catalog are fetched and verified first, as when subscribing to one feed.
+func (s *Server) handleImportOPML(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 5_242_880)
+
+	var body io.Reader = r.Body
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			s.badRequestResponse(w, r, errors.New(`body must contain an OPML file in the "file" field`))
+			return
+		}
+		defer file.Close()
+
+		body = file
+	}
+
+	doc, err := opml.Parse(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, opml.ErrInvalidDocument):
+			s.badRequestResponse(w, r, errors.New("body must be an OPML document"))
+		case errors.As(err, &maxBytesError):
+			s.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit))
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	importer := &opml.Importer{
+		FeedService:  s.FeedService,
+		FeedFetcher:  s.FeedFetcher,
+		FetchTimeout: fetchTimeout,
+		Transactor:   s.Transactor,
+	}
+
+	results, err := importer.Import(r.Context(), doc, user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	summary := map[opml.Status]int{
+		opml.StatusCreated: 0,
+		opml.StatusExists:  0,
+		opml.StatusInvalid: 0,
+	}
+	for _, result := range results {
+		summary[result.Status]++
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"results": results, "summary": summary}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleExportOPML returns the user's subscriptions as an OPML file, nested by
+// folder, for importing into another reader.
+func (s *Server) handleExportOPML(w http.ResponseWriter, r *http.Request) {
+	user := s.contextGetUser(r)
+
+	folders, err := s.FolderService.GetAllForUser(user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subscriptions, err := s.SubscriptionService.GetAllForUser(user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	doc := opml.Export("rss-app subscriptions", folders, subscriptions)
+
+	headers := make(http.Header)
+	headers.Set("Content-Type", "text/x-opml; charset=utf-8")
+	headers.Set("Content-Disposition", `attachment; filename="subscriptions.opml"`)
+
+	// The document is streamed, so a failure part way through can't change
+	// the response any more.
+	err = s.writeXML(w, http.StatusOK, doc, headers)
+	if err != nil {
+		s.logError(r, err)
+	}
+}
@@ -1,17 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/grodier/rss-app/internal/feedparser"
+	"github.com/grodier/rss-app/internal/fetcher"
 	"github.com/grodier/rss-app/internal/models"
-	"github.com/grodier/rss-app/internal/pgsql"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // validFeedBody is a shared test fixture for valid feed creation requests
@@ -22,11 +30,25 @@ var validFeedBody = `{
 	"site_url": "https://test.com/"
 }`
 
+// testToken is a well-formed authentication token. The default mockUserService
+// resolves it to testUser.
+const testToken = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+var testUser = &models.User{ID: 1, Email: "test@example.com"}
+
 // testServerOptions configures optional dependencies for test server
 type testServerOptions struct {
-	feedService models.FeedService
-	version     string
-	env         string
+	feedService   models.FeedService
+	itemService   models.ItemService
+	userService   models.UserService
+	tokenService  models.TokenService
+	permissions   models.PermissionService
+	subscriptions models.SubscriptionService
+	itemStates    models.ItemStateService
+	folders       models.FolderService
+	feedFetcher   FeedFetcher
+	version       string
+	env           string
 }
 
 // newTestServer creates a Server instance configured for testing.
@@ -35,13 +57,42 @@ func newTestServer(opts *testServerOptions) *Server {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	s := &Server{
-		logger: logger,
+		logger:       logger,
+		UserService:  &mockUserService{},
+		TokenService: &mockTokenService{},
+		FeedFetcher:  &mockFeedFetcher{},
+
+		PermissionService: &mockPermissionService{},
 	}
 
 	if opts != nil {
 		if opts.feedService != nil {
 			s.FeedService = opts.feedService
 		}
+		if opts.itemService != nil {
+			s.ItemService = opts.itemService
+		}
+		if opts.userService != nil {
+			s.UserService = opts.userService
+		}
+		if opts.tokenService != nil {
+			s.TokenService = opts.tokenService
+		}
+		if opts.permissions != nil {
+			s.PermissionService = opts.permissions
+		}
+		if opts.subscriptions != nil {
+			s.SubscriptionService = opts.subscriptions
+		}
+		if opts.itemStates != nil {
+			s.ItemStateService = opts.itemStates
+		}
+		if opts.folders != nil {
+			s.FolderService = opts.folders
+		}
+		if opts.feedFetcher != nil {
+			s.FeedFetcher = opts.feedFetcher
+		}
 		if opts.version != "" {
 			s.Version = opts.version
 		}
@@ -50,6 +101,8 @@ func newTestServer(opts *testServerOptions) *Server {
 		}
 	}
 
+	s.Transactor = &mockTransactor{s: s}
+
 	return s
 }
 
@@ -115,6 +168,7 @@ func TestHandleCreateFeed_Success(t *testing.T) {
 	})
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(validFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -165,6 +219,36 @@ func TestHandleCreateFeed_Success(t *testing.T) {
 	}
 }
 
+func TestHandleCreateFeed_EmptyDescription(t *testing.T) {
+	var created *models.Feed
+
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			createFn: func(feed *models.Feed) error {
+				created = feed
+				feed.ID = 1
+				return nil
+			},
+		},
+	})
+
+	// The default fetcher discovers no description either.
+	body := `{"title": "Test Site", "url": "https://test.com/rss.xml", "site_url": "https://test.com/"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.handleCreateFeed(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if created == nil || created.Description != "" {
+		t.Errorf("got created feed %+v, want it stored without a description", created)
+	}
+}
+
 func TestHandleCreateFeed_JSONParsingErrors(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -184,6 +268,7 @@ func TestHandleCreateFeed_JSONParsingErrors(t *testing.T) {
 			s := newTestServer(nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
@@ -222,16 +307,21 @@ func TestHandleCreateFeed_ValidationErrors(t *testing.T) {
 			body:       `{"title": "` + strings.Repeat("a", 501) + `", "description": "Description for a test feed", "url": "https://test.com/rss.xml", "site_url": "https://test.com/"}`,
 			wantErrors: map[string]string{"title": "must not be more than 500 bytes long"},
 		},
-		{
-			name:       "missing description",
-			body:       `{"title": "Test Site", "url": "https://test.com/rss.xml", "site_url": "https://test.com/"}`,
-			wantErrors: map[string]string{"description": "must be provided"},
-		},
 		{
 			name:       "missing url",
 			body:       `{"title": "Test Site", "description": "Description for a test feed", "site_url": "https://test.com/"}`,
 			wantErrors: map[string]string{"url": "must be provided"},
 		},
+		{
+			name:       "relative url",
+			body:       `{"url": "/rss.xml"}`,
+			wantErrors: map[string]string{"url": "must be an absolute http or https URL"},
+		},
+		{
+			name:       "unsupported url scheme",
+			body:       `{"url": "ftp://test.com/rss.xml"}`,
+			wantErrors: map[string]string{"url": "must be an absolute http or https URL"},
+		},
 		{
 			name:       "missing site_url",
 			body:       `{"title": "Test Site", "description": "Description for a test feed", "url": "https://test.com/rss.xml"}`,
@@ -239,8 +329,8 @@ func TestHandleCreateFeed_ValidationErrors(t *testing.T) {
 		},
 		{
 			name:       "multiple validation failures",
-			body:       `{"title": "", "description": "", "url": "", "site_url": ""}`,
-			wantErrors: map[string]string{"title": "must be provided", "description": "must be provided", "url": "must be provided", "site_url": "must be provided"},
+			body:       `{"title": "", "description": "", "url": "https://test.com/rss.xml", "site_url": ""}`,
+			wantErrors: map[string]string{"title": "must be provided", "site_url": "must be provided"},
 		},
 	}
 
@@ -249,6 +339,7 @@ func TestHandleCreateFeed_ValidationErrors(t *testing.T) {
 			s := newTestServer(nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
@@ -278,6 +369,179 @@ func TestHandleCreateFeed_ValidationErrors(t *testing.T) {
 	}
 }
 
+func TestHandleCreateFeed_DiscoversMetadata(t *testing.T) {
+	var created *models.Feed
+
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			createFn: func(feed *models.Feed) error {
+				created = feed
+				feed.ID = 1
+				return nil
+			},
+		},
+		feedFetcher: &mockFeedFetcher{
+			fetchFn: func(ctx context.Context, url string) (*fetcher.Response, error) {
+				if url != "https://test.com/rss.xml" {
+					t.Errorf("got fetch url %q, want %q", url, "https://test.com/rss.xml")
+				}
+				return &fetcher.Response{
+					URL: url,
+					Result: &feedparser.Result{
+						Feed: &models.Feed{
+							Title:       "Discovered Title",
+							Description: "Discovered description",
+							SiteURL:     "https://test.com/",
+							Language:    "en",
+						},
+					},
+				}, nil
+			},
+		},
+	})
+
+	tests := []struct {
+		name            string
+		body            string
+		wantTitle       string
+		wantDescription string
+		wantLanguage    string
+	}{
+		{
+			name:            "url only",
+			body:            `{"url": "https://test.com/rss.xml"}`,
+			wantTitle:       "Discovered Title",
+			wantDescription: "Discovered description",
+			wantLanguage:    "en",
+		},
+		{
+			name:            "explicit fields override discovered ones",
+			body:            `{"url": "https://test.com/rss.xml", "title": "My Title", "language": "fr"}`,
+			wantTitle:       "My Title",
+			wantDescription: "Discovered description",
+			wantLanguage:    "fr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created = nil
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.handleCreateFeed(rr, req)
+
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("got status %d, want %d", rr.Code, http.StatusCreated)
+			}
+
+			if created.URL != "https://test.com/rss.xml" {
+				t.Errorf("got url %q, want %q", created.URL, "https://test.com/rss.xml")
+			}
+			if created.Title != tt.wantTitle {
+				t.Errorf("got title %q, want %q", created.Title, tt.wantTitle)
+			}
+			if created.Description != tt.wantDescription {
+				t.Errorf("got description %q, want %q", created.Description, tt.wantDescription)
+			}
+			if created.SiteURL != "https://test.com/" {
+				t.Errorf("got site_url %q, want %q", created.SiteURL, "https://test.com/")
+			}
+			if created.Language != tt.wantLanguage {
+				t.Errorf("got language %q, want %q", created.Language, tt.wantLanguage)
+			}
+		})
+	}
+}
+
+func TestHandleCreateFeed_FetchErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fetchErr  error
+		wantError string
+	}{
+		{"not a feed", feedparser.ErrUnknownFormat, "does not point to a supported feed format"},
+		{"upstream status", &fetcher.StatusError{StatusCode: http.StatusNotFound}, "could not be fetched (status 404)"},
+		{"network error", errors.New("connection refused"), "could not be fetched"},
+		{"private address", fmt.Errorf("dial 127.0.0.1: %w", fetcher.ErrForbiddenAddress), "must not point to a private or local network address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				feedService: &mockFeedService{
+					createFn: func(feed *models.Feed) error {
+						t.Error("feed should not be created when fetching fails")
+						return nil
+					},
+				},
+				feedFetcher: &mockFeedFetcher{
+					fetchFn: func(ctx context.Context, url string) (*fetcher.Response, error) {
+						return nil, tt.fetchErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(`{"url": "https://test.com/rss.xml"}`))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.handleCreateFeed(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Error["url"] != tt.wantError {
+				t.Errorf("got url error %q, want %q", resp.Error["url"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleCreateFeed_FetchTimeout(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			createFn: func(feed *models.Feed) error {
+				t.Error("feed should not be created when fetching times out")
+				return nil
+			},
+		},
+		feedFetcher: &mockFeedFetcher{
+			fetchFn: func(ctx context.Context, url string) (*fetcher.Response, error) {
+				deadline, ok := ctx.Deadline()
+				if !ok {
+					t.Fatal("fetch has no deadline")
+				}
+				if remaining := time.Until(deadline); remaining >= writeTimeout {
+					t.Errorf("got %v to fetch, want less than the %v write timeout", remaining, writeTimeout)
+				}
+				return nil, fmt.Errorf("get %q: %w", url, context.DeadlineExceeded)
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(`{"url": "https://test.com/rss.xml"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.handleCreateFeed(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusGatewayTimeout)
+	}
+}
+
 func TestHandleCreateFeed_ServiceError(t *testing.T) {
 	s := newTestServer(&testServerOptions{
 		feedService: &mockFeedService{
@@ -288,6 +552,7 @@ func TestHandleCreateFeed_ServiceError(t *testing.T) {
 	})
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(validFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -309,6 +574,199 @@ func TestHandleCreateFeed_ServiceError(t *testing.T) {
 	}
 }
 
+func TestHandleListFeeds_Success(t *testing.T) {
+	var gotTitle, gotLanguage string
+	var gotFilters models.Filters
+
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getAllFn: func(title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+				gotTitle, gotLanguage, gotFilters = title, language, filters
+				return []*models.Feed{
+					{ID: 3, Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog"},
+					{ID: 1, Title: "Go Weekly", URL: "https://golangweekly.com/rss", SiteURL: "https://golangweekly.com"},
+				}, models.Metadata{
+					CurrentPage:  2,
+					PageSize:     2,
+					FirstPage:    1,
+					LastPage:     3,
+					TotalRecords: 6,
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds?title=go&language=en&sort=-title&page=2&page_size=2", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if gotTitle != "go" || gotLanguage != "en" {
+		t.Errorf("got title %q and language %q, want %q and %q", gotTitle, gotLanguage, "go", "en")
+	}
+	if gotFilters.Page != 2 || gotFilters.PageSize != 2 || gotFilters.Sort != "-title" {
+		t.Errorf("got filters %+v, want page 2, page_size 2, sort -title", gotFilters)
+	}
+
+	var envelope struct {
+		Feeds []struct {
+			ID    int64  `json:"id"`
+			Title string `json:"title"`
+		} `json:"feeds"`
+		Metadata struct {
+			CurrentPage  int `json:"current_page"`
+			LastPage     int `json:"last_page"`
+			TotalRecords int `json:"total_records"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Feeds) != 2 {
+		t.Fatalf("got %d feeds, want 2", len(envelope.Feeds))
+	}
+	if envelope.Feeds[0].ID != 3 || envelope.Feeds[0].Title != "Go Blog" {
+		t.Errorf("got first feed %+v", envelope.Feeds[0])
+	}
+	if envelope.Metadata.CurrentPage != 2 || envelope.Metadata.LastPage != 3 || envelope.Metadata.TotalRecords != 6 {
+		t.Errorf("got metadata %+v", envelope.Metadata)
+	}
+}
+
+func TestHandleListFeeds_Defaults(t *testing.T) {
+	var gotFilters models.Filters
+
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getAllFn: func(title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+				gotFilters = filters
+				return []*models.Feed{}, models.Metadata{}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if gotFilters.Page != 1 || gotFilters.PageSize != 20 || gotFilters.Sort != "id" {
+		t.Errorf("got filters %+v, want page 1, page_size 20, sort id", gotFilters)
+	}
+
+	if got := strings.TrimSpace(rr.Body.String()); got != `{"feeds":[],"metadata":{}}` {
+		t.Errorf("got body %s", got)
+	}
+}
+
+func TestHandleListFeeds_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantErrors map[string]string
+	}{
+		{
+			name:       "non-integer page",
+			query:      "page=abc",
+			wantErrors: map[string]string{"page": "must be an integer value"},
+		},
+		{
+			name:       "zero page",
+			query:      "page=0",
+			wantErrors: map[string]string{"page": "must be greater than zero"},
+		},
+		{
+			name:       "page size too large",
+			query:      "page_size=101",
+			wantErrors: map[string]string{"page_size": "must be a maximum of 100"},
+		},
+		{
+			name:       "unknown sort",
+			query:      "sort=url",
+			wantErrors: map[string]string{"sort": "invalid sort value"},
+		},
+		{
+			name:  "multiple errors",
+			query: "page=-1&page_size=x&sort=-version",
+			wantErrors: map[string]string{
+				"page":      "must be greater than zero",
+				"page_size": "must be an integer value",
+				"sort":      "invalid sort value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				feedService: &mockFeedService{
+					getAllFn: func(title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+						t.Error("GetAll should not be called for invalid input")
+						return nil, models.Metadata{}, nil
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/feeds?"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			if len(resp.Error) != len(tt.wantErrors) {
+				t.Errorf("got %d errors, want %d: %v", len(resp.Error), len(tt.wantErrors), resp.Error)
+			}
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListFeeds_ServiceError(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getAllFn: func(title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+				return nil, models.Metadata{}, errors.New("database connection failed")
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
 func TestHandleShowFeed_Success(t *testing.T) {
 	expectedFeed := &models.Feed{
 		ID:          1,
@@ -331,6 +789,7 @@ func TestHandleShowFeed_Success(t *testing.T) {
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	rr := httptest.NewRecorder()
 
 	s.router().ServeHTTP(rr, req)
@@ -392,6 +851,7 @@ func TestHandleShowFeed_InvalidID(t *testing.T) {
 			s := newTestServer(nil)
 
 			req := httptest.NewRequest(http.MethodGet, "/v1/feeds/"+tt.id, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			rr := httptest.NewRecorder()
 
 			s.router().ServeHTTP(rr, req)
@@ -407,12 +867,13 @@ func TestHandleShowFeed_NotFound(t *testing.T) {
 	s := newTestServer(&testServerOptions{
 		feedService: &mockFeedService{
 			getFn: func(id int64) (*models.Feed, error) {
-				return nil, pgsql.ErrRecordNotFound
+				return nil, models.ErrRecordNotFound
 			},
 		},
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/999", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	rr := httptest.NewRecorder()
 
 	s.router().ServeHTTP(rr, req)
@@ -432,6 +893,7 @@ func TestHandleShowFeed_ServiceError(t *testing.T) {
 	})
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	rr := httptest.NewRecorder()
 
 	s.router().ServeHTTP(rr, req)
@@ -441,14 +903,74 @@ func TestHandleShowFeed_ServiceError(t *testing.T) {
 	}
 }
 
-// validUpdateFeedBody is a shared test fixture for valid partial feed update requests
-var validUpdateFeedBody = `{
-	"title": "Updated Title",
-	"description": "Updated description"
-}`
+func TestHandleShowFeed_RequestContext(t *testing.T) {
+	feeds := &mockFeedService{
+		getFn: func(id int64) (*models.Feed, error) {
+			return &models.Feed{ID: id}, nil
+		},
+	}
+	s := newTestServer(&testServerOptions{feedService: feeds})
 
-func TestHandleUpdateFeed_Success(t *testing.T) {
-	existingFeed := &models.Feed{
+	type ctxKey struct{}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "marker"))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if feeds.getCtx == nil || feeds.getCtx.Value(ctxKey{}) != "marker" {
+		t.Error("expected the request context to be passed to the feed service")
+	}
+}
+
+func TestHandleShowFeed_ContextErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   bool
+	}{
+		{"client canceled", context.Canceled, statusClientClosedRequest, false},
+		{"query timed out", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				feedService: &mockFeedService{
+					getFn: func(id int64) (*models.Feed, error) {
+						return nil, tt.err
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1", nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+
+			if gotBody := rr.Body.Len() > 0; gotBody != tt.wantBody {
+				t.Errorf("got response body %t, want %t", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+// validUpdateFeedBody is a shared test fixture for valid partial feed update requests
+var validUpdateFeedBody = `{
+	"title": "Updated Title",
+	"description": "Updated description"
+}`
+
+func TestHandleUpdateFeed_Success(t *testing.T) {
+	existingFeed := &models.Feed{
 		ID:          1,
 		Title:       "Original Title",
 		Description: "Original description",
@@ -487,7 +1009,8 @@ func TestHandleUpdateFeed_Success(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -525,6 +1048,43 @@ func TestHandleUpdateFeed_Success(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateFeed_EmptyDescription(t *testing.T) {
+	var updated *models.Feed
+
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getFn: func(id int64) (*models.Feed, error) {
+				return &models.Feed{
+					ID:          1,
+					Title:       "Original Title",
+					Description: "Original description",
+					URL:         "https://example.com/feed.xml",
+					SiteURL:     "https://example.com",
+					Version:     1,
+				}, nil
+			},
+			updateFn: func(feed *models.Feed) error {
+				updated = feed
+				return nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(`{"description": ""}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if updated == nil || updated.Description != "" {
+		t.Errorf("got updated feed %+v, want the description cleared", updated)
+	}
+}
+
 func TestHandleUpdateFeed_InvalidID(t *testing.T) {
 	tests := []struct {
 		name string
@@ -539,7 +1099,8 @@ func TestHandleUpdateFeed_InvalidID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			s := newTestServer(nil)
 
-			req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/"+tt.id, strings.NewReader(validUpdateFeedBody))
+			req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/"+tt.id, strings.NewReader(validUpdateFeedBody))
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
@@ -556,12 +1117,13 @@ func TestHandleUpdateFeed_NotFound(t *testing.T) {
 	s := newTestServer(&testServerOptions{
 		feedService: &mockFeedService{
 			getFn: func(id int64) (*models.Feed, error) {
-				return nil, pgsql.ErrRecordNotFound
+				return nil, models.ErrRecordNotFound
 			},
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/999", strings.NewReader(validUpdateFeedBody))
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/999", strings.NewReader(validUpdateFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -605,7 +1167,8 @@ func TestHandleUpdateFeed_JSONParsingErrors(t *testing.T) {
 				},
 			})
 
-			req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/1", strings.NewReader(tt.body))
+			req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
@@ -652,11 +1215,6 @@ func TestHandleUpdateFeed_ValidationErrors(t *testing.T) {
 			body:       `{"title": "` + strings.Repeat("a", 501) + `"}`,
 			wantErrors: map[string]string{"title": "must not be more than 500 bytes long"},
 		},
-		{
-			name:       "empty description",
-			body:       `{"description": ""}`,
-			wantErrors: map[string]string{"description": "must be provided"},
-		},
 		{
 			name:       "empty url",
 			body:       `{"url": ""}`,
@@ -680,7 +1238,8 @@ func TestHandleUpdateFeed_ValidationErrors(t *testing.T) {
 				},
 			})
 
-			req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/1", strings.NewReader(tt.body))
+			req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
@@ -719,7 +1278,8 @@ func TestHandleUpdateFeed_GetServiceError(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -763,7 +1323,8 @@ func TestHandleUpdateFeed_UpdateServiceError(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -802,12 +1363,13 @@ func TestHandleUpdateFeed_EditConflict(t *testing.T) {
 				return &feed, nil
 			},
 			updateFn: func(feed *models.Feed) error {
-				return pgsql.ErrEditConflict
+				return models.ErrEditConflict
 			},
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodPatch, "/v1/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(validUpdateFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -829,6 +1391,40 @@ func TestHandleUpdateFeed_EditConflict(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateFeed_DuplicateURL(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getFn: func(id int64) (*models.Feed, error) {
+				return &models.Feed{ID: 1, Title: "Feed", URL: "https://example.com/feed.xml", SiteURL: "https://example.com", Version: 1}, nil
+			},
+			updateFn: func(feed *models.Feed) error {
+				return models.ErrDuplicateURL
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/admin/feeds/1", strings.NewReader(`{"url": "https://example.com/other.xml"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	var resp struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if want := "a feed with this url already exists"; resp.Error["url"] != want {
+		t.Errorf("got url error %q, want %q", resp.Error["url"], want)
+	}
+}
+
 func TestHandleDeleteFeed_Success(t *testing.T) {
 	s := newTestServer(&testServerOptions{
 		feedService: &mockFeedService{
@@ -841,7 +1437,8 @@ func TestHandleDeleteFeed_Success(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodDelete, "/v1/feeds/1", nil)
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/feeds/1", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	rr := httptest.NewRecorder()
 
 	s.router().ServeHTTP(rr, req)
@@ -880,7 +1477,8 @@ func TestHandleDeleteFeed_InvalidID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			s := newTestServer(nil)
 
-			req := httptest.NewRequest(http.MethodDelete, "/v1/feeds/"+tt.id, nil)
+			req := httptest.NewRequest(http.MethodDelete, "/v1/admin/feeds/"+tt.id, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
 			rr := httptest.NewRecorder()
 
 			s.router().ServeHTTP(rr, req)
@@ -896,12 +1494,13 @@ func TestHandleDeleteFeed_NotFound(t *testing.T) {
 	s := newTestServer(&testServerOptions{
 		feedService: &mockFeedService{
 			deleteFn: func(id int64) error {
-				return pgsql.ErrRecordNotFound
+				return models.ErrRecordNotFound
 			},
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodDelete, "/v1/feeds/999", nil)
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/feeds/999", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	rr := httptest.NewRecorder()
 
 	s.router().ServeHTTP(rr, req)
@@ -920,7 +1519,8 @@ func TestHandleDeleteFeed_ServiceError(t *testing.T) {
 		},
 	})
 
-	req := httptest.NewRequest(http.MethodDelete, "/v1/feeds/1", nil)
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/feeds/1", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
 	rr := httptest.NewRecorder()
 
 	s.router().ServeHTTP(rr, req)
@@ -940,3 +1540,2236 @@ func TestHandleDeleteFeed_ServiceError(t *testing.T) {
 		t.Errorf("got error %q, want %q", resp.Error, wantError)
 	}
 }
+
+func TestHandleDiscoverFeeds_Success(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedFetcher: &mockFeedFetcher{
+			discoverFn: func(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error) {
+				if pageURL != "https://test.com/" {
+					t.Errorf("got page url %q, want %q", pageURL, "https://test.com/")
+				}
+				return []*fetcher.Candidate{
+					{URL: "https://test.com/rss.xml", Title: "Test Site", Type: "application/rss+xml"},
+					{URL: "https://test.com/atom.xml", Title: "Test Site (Atom)"},
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/feeds/discover", strings.NewReader(`{"url": "https://test.com/"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var envelope struct {
+		Feeds []struct {
+			URL   string `json:"url"`
+			Title string `json:"title"`
+			Type  string `json:"type"`
+		} `json:"feeds"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Feeds) != 2 {
+		t.Fatalf("got %d feeds, want 2", len(envelope.Feeds))
+	}
+	if envelope.Feeds[0].URL != "https://test.com/rss.xml" || envelope.Feeds[0].Title != "Test Site" || envelope.Feeds[0].Type != "application/rss+xml" {
+		t.Errorf("got first feed %+v", envelope.Feeds[0])
+	}
+	if envelope.Feeds[1].URL != "https://test.com/atom.xml" {
+		t.Errorf("got second feed url %q, want %q", envelope.Feeds[1].URL, "https://test.com/atom.xml")
+	}
+}
+
+func TestHandleDiscoverFeeds_NoneFound(t *testing.T) {
+	s := newTestServer(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/feeds/discover", strings.NewReader(`{"url": "https://test.com/"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if got := strings.TrimSpace(rr.Body.String()); got != `{"feeds":[]}` {
+		t.Errorf("got body %s, want empty feeds list", got)
+	}
+}
+
+func TestHandleDiscoverFeeds_Timeout(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedFetcher: &mockFeedFetcher{
+			discoverFn: func(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error) {
+				deadline, ok := ctx.Deadline()
+				if !ok {
+					t.Fatal("discovery has no deadline")
+				}
+				if remaining := time.Until(deadline); remaining >= writeTimeout {
+					t.Errorf("got %v to discover, want less than the %v write timeout", remaining, writeTimeout)
+				}
+				return nil, context.DeadlineExceeded
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/feeds/discover", strings.NewReader(`{"url": "https://test.com/"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.handleDiscoverFeeds(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusGatewayTimeout)
+	}
+}
+
+func TestHandleDiscoverFeeds_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		discoverFn func(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error)
+		wantStatus int
+		wantErrors map[string]string
+	}{
+		{
+			name:       "missing url",
+			body:       `{}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"url": "must be provided"},
+		},
+		{
+			name:       "invalid url",
+			body:       `{"url": "not a url"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"url": "must be an absolute http or https URL"},
+		},
+		{
+			name: "page unavailable",
+			body: `{"url": "https://test.com/"}`,
+			discoverFn: func(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error) {
+				return nil, &fetcher.StatusError{StatusCode: http.StatusForbidden}
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"url": "could not be fetched (status 403)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				feedFetcher: &mockFeedFetcher{discoverFn: tt.discoverFn},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/feeds/discover", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListFeedItems_Success(t *testing.T) {
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	after := models.Cursor{PublishedAt: published, ID: 7}
+
+	var gotFilters models.CursorFilters
+
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getFn: func(id int64) (*models.Feed, error) {
+				return &models.Feed{ID: id}, nil
+			},
+		},
+		itemService: &mockItemService{
+			getAllForFeedFn: func(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+				if feedID != 1 {
+					t.Errorf("got feed id %d, want 1", feedID)
+				}
+				if userID != testUser.ID {
+					t.Errorf("got user id %d, want %d", userID, testUser.ID)
+				}
+				gotFilters = filters
+				return []*models.Item{
+					{ID: 6, FeedID: 1, GUID: "six", Title: "Six", PublishedAt: published.Add(-time.Hour)},
+					{ID: 5, FeedID: 1, GUID: "five", Title: "Five", PublishedAt: published.Add(-2 * time.Hour)},
+				}, models.CursorMetadata{PageSize: 2, NextCursor: "next"}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1/items?page_size=2&cursor="+after.Encode(), nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if gotFilters.PageSize != 2 || gotFilters.After != after {
+		t.Errorf("got filters %+v, want page size 2 after %+v", gotFilters, after)
+	}
+
+	var envelope struct {
+		Items []struct {
+			ID    int64  `json:"id"`
+			Title string `json:"title"`
+		} `json:"items"`
+		Metadata struct {
+			PageSize   int    `json:"page_size"`
+			NextCursor string `json:"next_cursor"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Items) != 2 || envelope.Items[0].ID != 6 {
+		t.Errorf("got items %+v", envelope.Items)
+	}
+	if envelope.Metadata.NextCursor != "next" {
+		t.Errorf("got next cursor %q, want %q", envelope.Metadata.NextCursor, "next")
+	}
+}
+
+func TestHandleListFeedItems_FeedNotFound(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getFn: func(id int64) (*models.Feed, error) {
+				return nil, models.ErrRecordNotFound
+			},
+		},
+		itemService: &mockItemService{
+			getAllForFeedFn: func(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+				t.Error("GetAllForFeed should not be called for a missing feed")
+				return nil, models.CursorMetadata{}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/999/items", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleListFeedItems_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantErrors map[string]string
+	}{
+		{
+			name:       "malformed cursor",
+			query:      "cursor=not-a-cursor",
+			wantErrors: map[string]string{"cursor": "must be a cursor returned by a previous request"},
+		},
+		{
+			name:       "non-integer page size",
+			query:      "page_size=abc",
+			wantErrors: map[string]string{"page_size": "must be an integer value"},
+		},
+		{
+			name:       "page size too large",
+			query:      "page_size=500",
+			wantErrors: map[string]string{"page_size": "must be a maximum of 100"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1/items?"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListFeedItems_ServiceError(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getFn: func(id int64) (*models.Feed, error) {
+				return &models.Feed{ID: id}, nil
+			},
+		},
+		itemService: &mockItemService{
+			getAllForFeedFn: func(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+				return nil, models.CursorMetadata{}, errors.New("database connection failed")
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/feeds/1/items", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestHandleShowItem_Success(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		itemService: &mockItemService{
+			getFn: func(id int64) (*models.Item, error) {
+				if id != 3 {
+					t.Errorf("unexpected id: got %d, want 3", id)
+				}
+				return &models.Item{
+					ID:      3,
+					FeedID:  1,
+					GUID:    "three",
+					Title:   "Three",
+					Content: "<p>Full content</p>",
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items/3", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var envelope struct {
+		Item struct {
+			ID      int64  `json:"id"`
+			Content string `json:"content"`
+		} `json:"item"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if envelope.Item.ID != 3 {
+		t.Errorf("got id %d, want 3", envelope.Item.ID)
+	}
+	if envelope.Item.Content != "<p>Full content</p>" {
+		t.Errorf("got content %q, want %q", envelope.Item.Content, "<p>Full content</p>")
+	}
+}
+
+func TestHandleShowItem_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		serviceErr error
+		wantStatus int
+	}{
+		{"invalid id", "abc", nil, http.StatusNotFound},
+		{"not found", "999", models.ErrRecordNotFound, http.StatusNotFound},
+		{"service error", "1", errors.New("database connection failed"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				itemService: &mockItemService{
+					getFn: func(id int64) (*models.Item, error) {
+						return nil, tt.serviceErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/items/"+tt.id, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandleRegisterUser_Success(t *testing.T) {
+	var created *models.User
+	var granted []string
+
+	s := newTestServer(&testServerOptions{
+		userService: &mockUserService{
+			createFn: func(user *models.User) error {
+				created = user
+				user.ID = 7
+				user.CreatedAt = time.Now()
+				user.Version = 1
+				return nil
+			},
+		},
+		permissions: &mockPermissionService{
+			addForUserFn: func(userID int64, codes ...string) error {
+				if userID != 7 {
+					t.Errorf("got user id %d, want 7", userID)
+				}
+				granted = codes
+				return nil
+			},
+		},
+	})
+
+	body := `{"email": "alice@example.com", "password": "pa55word1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	if created == nil {
+		t.Fatal("expected user to be created")
+	}
+	if !slices.Equal(granted, models.DefaultPermissions) {
+		t.Errorf("got granted permissions %v, want %v", granted, models.DefaultPermissions)
+	}
+	if ok, err := created.Password.Matches("pa55word1234"); err != nil || !ok {
+		t.Errorf("stored hash does not match the password (ok=%v, err=%v)", ok, err)
+	}
+
+	var envelope struct {
+		User map[string]any `json:"user"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if envelope.User["id"] != float64(7) {
+		t.Errorf("got id %v, want 7", envelope.User["id"])
+	}
+	if envelope.User["email"] != "alice@example.com" {
+		t.Errorf("got email %v, want %q", envelope.User["email"], "alice@example.com")
+	}
+	for _, key := range []string{"password", "password_hash", "Password"} {
+		if _, ok := envelope.User[key]; ok {
+			t.Errorf("response must not include %q", key)
+		}
+	}
+}
+
+func TestHandleRegisterUser_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErrors map[string]string
+	}{
+		{
+			name:       "missing email",
+			body:       `{"password": "pa55word1234"}`,
+			wantErrors: map[string]string{"email": "must be provided"},
+		},
+		{
+			name:       "invalid email",
+			body:       `{"email": "not-an-email", "password": "pa55word1234"}`,
+			wantErrors: map[string]string{"email": "must be a valid email address"},
+		},
+		{
+			name:       "missing password",
+			body:       `{"email": "alice@example.com"}`,
+			wantErrors: map[string]string{"password": "must be provided"},
+		},
+		{
+			name:       "short password",
+			body:       `{"email": "alice@example.com", "password": "short"}`,
+			wantErrors: map[string]string{"password": "must be at least 8 bytes long"},
+		},
+		{
+			name:       "long password",
+			body:       `{"email": "alice@example.com", "password": "` + strings.Repeat("a", 73) + `"}`,
+			wantErrors: map[string]string{"password": "must not be more than 72 bytes long"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				userService: &mockUserService{
+					createFn: func(user *models.User) error {
+						t.Error("Create should not be called for invalid input")
+						return nil
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleRegisterUser_DuplicateEmail(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		userService: &mockUserService{
+			createFn: func(user *models.User) error {
+				return models.ErrDuplicateEmail
+			},
+		},
+	})
+
+	body := `{"email": "alice@example.com", "password": "pa55word1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	var resp struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	want := "a user with this email address already exists"
+	if resp.Error["email"] != want {
+		t.Errorf("got error %q, want %q", resp.Error["email"], want)
+	}
+}
+
+func TestHandleRegisterUser_ServiceError(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		userService: &mockUserService{
+			createFn: func(user *models.User) error {
+				return errors.New("database connection failed")
+			},
+		},
+	})
+
+	body := `{"email": "alice@example.com", "password": "pa55word1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestHandleRegisterUser_PermissionsError(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		userService: &mockUserService{
+			createFn: func(user *models.User) error {
+				user.ID = 7
+				return nil
+			},
+		},
+		permissions: &mockPermissionService{
+			addForUserFn: func(userID int64, codes ...string) error {
+				return errors.New("database connection failed")
+			},
+		},
+	})
+
+	body := `{"email": "alice@example.com", "password": "pa55word1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+
+	// The user must be rolled back along with the permissions.
+	tx := s.Transactor.(*mockTransactor)
+	if tx.rollbacks != 1 || tx.commits != 0 {
+		t.Errorf("got %d rollbacks and %d commits, want the user created in a transaction that was rolled back", tx.rollbacks, tx.commits)
+	}
+}
+
+func TestHandleCreateAuthenticationToken_Success(t *testing.T) {
+	user := &models.User{ID: 5, Email: "alice@example.com"}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+
+	var stored *models.Token
+
+	s := newTestServer(&testServerOptions{
+		userService: &mockUserService{
+			getByEmailFn: func(email string) (*models.User, error) {
+				if email != "alice@example.com" {
+					t.Errorf("got email %q, want %q", email, "alice@example.com")
+				}
+				return user, nil
+			},
+		},
+		tokenService: &mockTokenService{
+			createFn: func(token *models.Token) error {
+				stored = token
+				return nil
+			},
+		},
+	})
+
+	body := `{"email": "alice@example.com", "password": "pa55word1234"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/tokens/authentication", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	if stored == nil {
+		t.Fatal("expected token to be stored")
+	}
+	if stored.UserID != 5 || stored.Scope != models.ScopeAuthentication {
+		t.Errorf("got stored token for user %d with scope %q", stored.UserID, stored.Scope)
+	}
+
+	var envelope struct {
+		Token struct {
+			Token  string    `json:"token"`
+			Expiry time.Time `json:"expiry"`
+		} `json:"authentication_token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Token.Token) != 26 {
+		t.Errorf("got token %q, want 26 characters", envelope.Token.Token)
+	}
+	if string(models.HashToken(envelope.Token.Token)) != string(stored.Hash) {
+		t.Error("stored hash does not match the returned token")
+	}
+	if time.Until(envelope.Token.Expiry) < 23*time.Hour {
+		t.Errorf("got expiry %v, want about 24 hours from now", envelope.Token.Expiry)
+	}
+}
+
+func TestHandleCreateAuthenticationToken_InvalidCredentials(t *testing.T) {
+	user := &models.User{ID: 5, Email: "alice@example.com"}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		getByEmailFn func(email string) (*models.User, error)
+	}{
+		{
+			name: "unknown email",
+			body: `{"email": "bob@example.com", "password": "pa55word1234"}`,
+			getByEmailFn: func(email string) (*models.User, error) {
+				return nil, models.ErrRecordNotFound
+			},
+		},
+		{
+			name: "wrong password",
+			body: `{"email": "alice@example.com", "password": "wrongpassword"}`,
+			getByEmailFn: func(email string) (*models.User, error) {
+				return user, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				userService: &mockUserService{getByEmailFn: tt.getByEmailFn},
+				tokenService: &mockTokenService{
+					createFn: func(token *models.Token) error {
+						t.Error("Create should not be called for invalid credentials")
+						return nil
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/tokens/authentication", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusUnauthorized)
+			}
+
+			var resp struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.Error != "invalid authentication credentials" {
+				t.Errorf("got error %q, want %q", resp.Error, "invalid authentication credentials")
+			}
+		})
+	}
+}
+
+func TestDummyPassword(t *testing.T) {
+	var password models.Password
+	if err := password.Set("pa55word1234"); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+
+	// Unknown emails must pay the same bcrypt cost as real accounts.
+	want, _ := bcrypt.Cost(password.Hash)
+	got, err := bcrypt.Cost(dummyPassword.Hash)
+	if err != nil || got != want {
+		t.Errorf("got cost %d (%v), want %d", got, err, want)
+	}
+
+	if match, err := dummyPassword.Matches("pa55word1234"); match || err != nil {
+		t.Errorf("got match %v and error %v, want a well-formed hash that doesn't match", match, err)
+	}
+}
+
+func TestHandleCreateAuthenticationToken_ValidationErrors(t *testing.T) {
+	s := newTestServer(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tokens/authentication", strings.NewReader(`{"email": "nope"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	var resp struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if resp.Error["email"] != "must be a valid email address" {
+		t.Errorf("got email error %q", resp.Error["email"])
+	}
+	if resp.Error["password"] != "must be provided" {
+		t.Errorf("got password error %q", resp.Error["password"])
+	}
+}
+
+func TestHandleCreateFeed_DuplicateURL(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			createFn: func(feed *models.Feed) error {
+				return models.ErrDuplicateURL
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/feeds", strings.NewReader(validFeedBody))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	var resp struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if resp.Error["url"] != "a feed with this url already exists" {
+		t.Errorf("got url error %q", resp.Error["url"])
+	}
+}
+
+func TestHandleCreateSubscription(t *testing.T) {
+	const feedURL = "https://test.com/rss.xml"
+
+	existing := &models.Feed{ID: 4, Title: "Known Feed", URL: feedURL, SiteURL: "https://test.com/"}
+
+	tests := []struct {
+		name        string
+		getByURLFn  func(calls int) (*models.Feed, error)
+		createFn    func(feed *models.Feed) error
+		wantFetch   bool
+		wantCreate  bool
+		wantFeedID  int64
+		wantLookups int
+	}{
+		{
+			name: "known feed is reused",
+			getByURLFn: func(calls int) (*models.Feed, error) {
+				return existing, nil
+			},
+			wantFeedID:  4,
+			wantLookups: 1,
+		},
+		{
+			name: "unknown feed is fetched and added",
+			getByURLFn: func(calls int) (*models.Feed, error) {
+				return nil, models.ErrRecordNotFound
+			},
+			createFn: func(feed *models.Feed) error {
+				feed.ID = 12
+				return nil
+			},
+			wantFetch:   true,
+			wantCreate:  true,
+			wantFeedID:  12,
+			wantLookups: 1,
+		},
+		{
+			name: "feed added concurrently is reused",
+			getByURLFn: func(calls int) (*models.Feed, error) {
+				if calls == 1 {
+					return nil, models.ErrRecordNotFound
+				}
+				return existing, nil
+			},
+			createFn: func(feed *models.Feed) error {
+				return models.ErrDuplicateURL
+			},
+			wantFetch:   true,
+			wantCreate:  true,
+			wantFeedID:  4,
+			wantLookups: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups int
+			var fetched, created bool
+			var subscribed *models.Subscription
+
+			s := newTestServer(&testServerOptions{
+				feedService: &mockFeedService{
+					getByURLFn: func(url string) (*models.Feed, error) {
+						lookups++
+						if url != feedURL {
+							t.Errorf("got url %q, want %q", url, feedURL)
+						}
+						return tt.getByURLFn(lookups)
+					},
+					createFn: func(feed *models.Feed) error {
+						created = true
+						return tt.createFn(feed)
+					},
+				},
+				feedFetcher: &mockFeedFetcher{
+					fetchFn: func(ctx context.Context, url string) (*fetcher.Response, error) {
+						fetched = true
+						return &fetcher.Response{
+							URL: url,
+							Result: &feedparser.Result{Feed: &models.Feed{
+								Title:   "Fetched Feed",
+								SiteURL: "https://test.com/",
+							}},
+						}, nil
+					},
+				},
+				subscriptions: &mockSubscriptionService{
+					createFn: func(subscription *models.Subscription) error {
+						subscribed = subscription
+						subscription.ID = 30
+						return nil
+					},
+				},
+				folders: &mockFolderService{
+					getFn: func(id int64, userID int64) (*models.Folder, error) {
+						return &models.Folder{ID: id, UserID: userID, Name: "Tech"}, nil
+					},
+				},
+			})
+
+			body := `{"url": "` + feedURL + `", "title": "My Name", "folder_id": 7}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/subscriptions", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusCreated, rr.Body.String())
+			}
+
+			if fetched != tt.wantFetch {
+				t.Errorf("got fetched %v, want %v", fetched, tt.wantFetch)
+			}
+			if created != tt.wantCreate {
+				t.Errorf("got created %v, want %v", created, tt.wantCreate)
+			}
+			if lookups != tt.wantLookups {
+				t.Errorf("got %d lookups, want %d", lookups, tt.wantLookups)
+			}
+
+			if subscribed == nil {
+				t.Fatal("expected subscription to be created")
+			}
+			if subscribed.UserID != testUser.ID || subscribed.FeedID != tt.wantFeedID {
+				t.Errorf("got user %d and feed %d, want %d and %d", subscribed.UserID, subscribed.FeedID, testUser.ID, tt.wantFeedID)
+			}
+			if subscribed.Title != "My Name" || subscribed.FolderID != 7 {
+				t.Errorf("got title %q and folder %d", subscribed.Title, subscribed.FolderID)
+			}
+
+			var envelope struct {
+				Subscription struct {
+					ID     int64  `json:"id"`
+					FeedID int64  `json:"feed_id"`
+					Title  string `json:"title"`
+					Feed   struct {
+						URL string `json:"url"`
+					} `json:"feed"`
+				} `json:"subscription"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			if envelope.Subscription.ID != 30 || envelope.Subscription.FeedID != tt.wantFeedID {
+				t.Errorf("got subscription %+v", envelope.Subscription)
+			}
+			if envelope.Subscription.Feed.URL != feedURL {
+				t.Errorf("got feed url %q, want %q", envelope.Subscription.Feed.URL, feedURL)
+			}
+		})
+	}
+}
+
+func TestHandleCreateSubscription_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		fetchFn    func(ctx context.Context, url string) (*fetcher.Response, error)
+		createFn   func(subscription *models.Subscription) error
+		wantStatus int
+		wantErrors map[string]string
+	}{
+		{
+			name:       "invalid url",
+			body:       `{"url": "nope"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"url": "must be an absolute http or https URL"},
+		},
+		{
+			name:       "negative folder",
+			body:       `{"url": "https://test.com/rss.xml", "folder_id": -1}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"folder_id": "must not be negative"},
+		},
+		{
+			name:       "folder not owned",
+			body:       `{"url": "https://test.com/rss.xml", "folder_id": 9}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"folder_id": "must be one of your folders"},
+		},
+		{
+			name: "feed cannot be fetched",
+			body: `{"url": "https://test.com/rss.xml"}`,
+			fetchFn: func(ctx context.Context, url string) (*fetcher.Response, error) {
+				return nil, feedparser.ErrUnknownFormat
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"url": "does not point to a supported feed format"},
+		},
+		{
+			name: "already subscribed",
+			body: `{"url": "https://test.com/rss.xml"}`,
+			createFn: func(subscription *models.Subscription) error {
+				return models.ErrDuplicateSubscription
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"url": "you are already subscribed to this feed"},
+		},
+		{
+			name: "service error",
+			body: `{"url": "https://test.com/rss.xml"}`,
+			createFn: func(subscription *models.Subscription) error {
+				return errors.New("database connection failed")
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				feedService: &mockFeedService{
+					getByURLFn: func(url string) (*models.Feed, error) {
+						if tt.fetchFn != nil {
+							return nil, models.ErrRecordNotFound
+						}
+						return &models.Feed{ID: 4, URL: url}, nil
+					},
+				},
+				feedFetcher:   &mockFeedFetcher{fetchFn: tt.fetchFn},
+				subscriptions: &mockSubscriptionService{createFn: tt.createFn},
+				folders: &mockFolderService{
+					getFn: func(id int64, userID int64) (*models.Folder, error) {
+						return nil, models.ErrRecordNotFound
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/subscriptions", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+
+			if tt.wantErrors == nil {
+				return
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListSubscriptions(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		subscriptions: &mockSubscriptionService{
+			getAllForUserFn: func(userID int64) ([]*models.Subscription, error) {
+				if userID != testUser.ID {
+					t.Errorf("got user id %d, want %d", userID, testUser.ID)
+				}
+				return []*models.Subscription{
+					{ID: 1, FeedID: 4, Feed: &models.Feed{ID: 4, Title: "Feed Four"}, UnreadCount: 12},
+					{ID: 2, FeedID: 5, Title: "Renamed", FolderID: 7, Feed: &models.Feed{ID: 5, Title: "Feed Five"}},
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscriptions", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var envelope struct {
+		Subscriptions []struct {
+			ID       int64  `json:"id"`
+			Title    string `json:"title"`
+			FolderID int64  `json:"folder_id"`
+			Feed     struct {
+				Title string `json:"title"`
+			} `json:"feed"`
+			UnreadCount int `json:"unread_count"`
+		} `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Subscriptions) != 2 {
+		t.Fatalf("got %d subscriptions, want 2", len(envelope.Subscriptions))
+	}
+	if envelope.Subscriptions[0].Feed.Title != "Feed Four" {
+		t.Errorf("got feed title %q, want %q", envelope.Subscriptions[0].Feed.Title, "Feed Four")
+	}
+	if envelope.Subscriptions[0].UnreadCount != 12 {
+		t.Errorf("got unread count %d, want 12", envelope.Subscriptions[0].UnreadCount)
+	}
+	if envelope.Subscriptions[1].Title != "Renamed" || envelope.Subscriptions[1].FolderID != 7 {
+		t.Errorf("got subscription %+v", envelope.Subscriptions[1])
+	}
+}
+
+func TestHandleDeleteSubscription(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		deleteErr  error
+		wantStatus int
+	}{
+		{"success", "3", nil, http.StatusOK},
+		{"invalid id", "abc", nil, http.StatusNotFound},
+		{"not found or not owned", "3", models.ErrRecordNotFound, http.StatusNotFound},
+		{"service error", "3", errors.New("database connection failed"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				subscriptions: &mockSubscriptionService{
+					deleteFn: func(id int64, userID int64) error {
+						if id != 3 || userID != testUser.ID {
+							t.Errorf("got id %d and user %d, want 3 and %d", id, userID, testUser.ID)
+						}
+						return tt.deleteErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/subscriptions/"+tt.id, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateItemState(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		updated    int64
+		updateErr  error
+		wantRead   *bool
+		wantStar   *bool
+		wantStatus int
+	}{
+		{
+			name:       "mark read",
+			id:         "3",
+			body:       `{"read": true}`,
+			updated:    1,
+			wantRead:   &yes,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unstar",
+			id:         "3",
+			body:       `{"starred": false}`,
+			updated:    1,
+			wantStar:   &no,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no changes",
+			id:         "3",
+			body:       `{}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid id",
+			id:         "abc",
+			body:       `{"read": true}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "item not in a subscription",
+			id:         "3",
+			body:       `{"read": true}`,
+			updated:    0,
+			wantRead:   &yes,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "service error",
+			id:         "3",
+			body:       `{"read": true}`,
+			updateErr:  errors.New("database connection failed"),
+			wantRead:   &yes,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				itemStates: &mockItemStateService{
+					updateFn: func(userID int64, itemIDs []int64, update models.ItemStateUpdate) (int64, error) {
+						if userID != testUser.ID || !slices.Equal(itemIDs, []int64{3}) {
+							t.Errorf("got user %d and items %v, want %d and [3]", userID, itemIDs, testUser.ID)
+						}
+						if !equalBoolPtr(update.Read, tt.wantRead) || !equalBoolPtr(update.Starred, tt.wantStar) {
+							t.Errorf("got update %+v", update)
+						}
+						return tt.updated, tt.updateErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/items/"+tt.id+"/state", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandleBulkUpdateItemState(t *testing.T) {
+	var gotIDs []int64
+
+	s := newTestServer(&testServerOptions{
+		itemStates: &mockItemStateService{
+			updateFn: func(userID int64, itemIDs []int64, update models.ItemStateUpdate) (int64, error) {
+				gotIDs = itemIDs
+				if update.Read == nil || *update.Read || update.Starred != nil {
+					t.Errorf("got update %+v, want read=false only", update)
+				}
+				return 2, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/items/state", strings.NewReader(`{"item_ids": [4, 5, 6], "read": false}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if !slices.Equal(gotIDs, []int64{4, 5, 6}) {
+		t.Errorf("got item ids %v, want [4 5 6]", gotIDs)
+	}
+
+	var envelope struct {
+		Updated int64 `json:"updated"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if envelope.Updated != 2 {
+		t.Errorf("got updated %d, want 2", envelope.Updated)
+	}
+}
+
+func TestHandleBulkUpdateItemState_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErrors map[string]string
+	}{
+		{
+			name:       "no items",
+			body:       `{"item_ids": [], "read": true}`,
+			wantErrors: map[string]string{"item_ids": "must contain at least 1 item"},
+		},
+		{
+			name:       "duplicate items",
+			body:       `{"item_ids": [1, 1], "read": true}`,
+			wantErrors: map[string]string{"item_ids": "must not contain duplicate values"},
+		},
+		{
+			name:       "invalid id",
+			body:       `{"item_ids": [0], "starred": true}`,
+			wantErrors: map[string]string{"item_ids": "must only contain positive ids"},
+		},
+		{
+			name:       "no changes",
+			body:       `{"item_ids": [1]}`,
+			wantErrors: map[string]string{"state": "must change read or starred"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/items/state", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleMarkSubscriptionRead(t *testing.T) {
+	before := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		body       string
+		markErr    error
+		wantBefore func(got time.Time) bool
+		wantStatus int
+	}{
+		{
+			name:       "explicit timestamp",
+			body:       `{"before": "2024-01-02T03:04:05Z"}`,
+			wantBefore: func(got time.Time) bool { return got.Equal(before) },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "defaults to now",
+			body:       `{}`,
+			wantBefore: func(got time.Time) bool { return time.Since(got) < time.Minute },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no body",
+			body:       "",
+			wantBefore: func(got time.Time) bool { return time.Since(got) < time.Minute },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "malformed body",
+			body:       `{"before":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not found or not owned",
+			body:       `{}`,
+			markErr:    models.ErrRecordNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "malformed timestamp",
+			body:       `{"before": "yesterday"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				itemStates: &mockItemStateService{
+					markSubscriptionReadFn: func(subscriptionID int64, userID int64, got time.Time) error {
+						if subscriptionID != 9 || userID != testUser.ID {
+							t.Errorf("got subscription %d and user %d, want 9 and %d", subscriptionID, userID, testUser.ID)
+						}
+						if tt.wantBefore != nil && !tt.wantBefore(got) {
+							t.Errorf("got before %v", got)
+						}
+						return tt.markErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/subscriptions/9/read", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func equalBoolPtr(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestHandleUpdateSubscription(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		body         string
+		getErr       error
+		updateErr    error
+		wantStatus   int
+		wantFolderID int64
+		wantErrors   map[string]string
+	}{
+		{
+			name:         "move to folder",
+			id:           "3",
+			body:         `{"folder_id": 7}`,
+			wantStatus:   http.StatusOK,
+			wantFolderID: 7,
+		},
+		{
+			name:         "move out of folder",
+			id:           "3",
+			body:         `{"folder_id": 0}`,
+			wantStatus:   http.StatusOK,
+			wantFolderID: 0,
+		},
+		{
+			name:       "invalid id",
+			id:         "abc",
+			body:       `{}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "not found or not owned",
+			id:         "3",
+			body:       `{}`,
+			getErr:     models.ErrRecordNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "folder not owned",
+			id:         "3",
+			body:       `{"folder_id": 9}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"folder_id": "must be one of your folders"},
+		},
+		{
+			name:       "edit conflict",
+			id:         "3",
+			body:       `{"title": "Renamed"}`,
+			updateErr:  models.ErrEditConflict,
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *models.Subscription
+
+			s := newTestServer(&testServerOptions{
+				subscriptions: &mockSubscriptionService{
+					getFn: func(id int64, userID int64) (*models.Subscription, error) {
+						if tt.getErr != nil {
+							return nil, tt.getErr
+						}
+						return &models.Subscription{ID: id, UserID: userID, FeedID: 4, FolderID: 2, Version: 1}, nil
+					},
+					updateFn: func(subscription *models.Subscription) error {
+						updated = subscription
+						return tt.updateErr
+					},
+				},
+				folders: &mockFolderService{
+					getFn: func(id int64, userID int64) (*models.Folder, error) {
+						if id != 7 {
+							return nil, models.ErrRecordNotFound
+						}
+						return &models.Folder{ID: id, UserID: userID}, nil
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/subscriptions/"+tt.id, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusOK && updated.FolderID != tt.wantFolderID {
+				t.Errorf("got folder %d, want %d", updated.FolderID, tt.wantFolderID)
+			}
+
+			if tt.wantErrors == nil {
+				return
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleCreateFolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		createErr  error
+		wantStatus int
+		wantErrors map[string]string
+	}{
+		{
+			name:       "success",
+			body:       `{"name": "Tech"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing name",
+			body:       `{}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"name": "must be provided"},
+		},
+		{
+			name:       "duplicate name",
+			body:       `{"name": "Tech"}`,
+			createErr:  models.ErrDuplicateFolder,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantErrors: map[string]string{"name": "a folder with this name already exists"},
+		},
+		{
+			name:       "service error",
+			body:       `{"name": "Tech"}`,
+			createErr:  errors.New("database connection failed"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				folders: &mockFolderService{
+					createFn: func(folder *models.Folder) error {
+						if folder.UserID != testUser.ID {
+							t.Errorf("got user id %d, want %d", folder.UserID, testUser.ID)
+						}
+						folder.ID = 5
+						return tt.createErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/folders", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusCreated {
+				if location := rr.Header().Get("Location"); location != "/v1/folders/5" {
+					t.Errorf("got Location %q, want %q", location, "/v1/folders/5")
+				}
+			}
+
+			if tt.wantErrors == nil {
+				return
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListFolders(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		folders: &mockFolderService{
+			getAllForUserFn: func(userID int64) ([]*models.Folder, error) {
+				if userID != testUser.ID {
+					t.Errorf("got user id %d, want %d", userID, testUser.ID)
+				}
+				return []*models.Folder{
+					{ID: 1, Name: "News"},
+					{ID: 2, Name: "Tech", UnreadCount: 12},
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/folders", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var envelope struct {
+		Folders []struct {
+			ID          int64  `json:"id"`
+			Name        string `json:"name"`
+			UnreadCount int    `json:"unread_count"`
+		} `json:"folders"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Folders) != 2 {
+		t.Fatalf("got %d folders, want 2", len(envelope.Folders))
+	}
+	if envelope.Folders[1].Name != "Tech" || envelope.Folders[1].UnreadCount != 12 {
+		t.Errorf("got folder %+v", envelope.Folders[1])
+	}
+}
+
+func TestHandleUpdateFolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		getErr     error
+		updateErr  error
+		wantStatus int
+	}{
+		{"success", "3", `{"name": "Renamed"}`, nil, nil, http.StatusOK},
+		{"invalid id", "abc", `{}`, nil, nil, http.StatusNotFound},
+		{"not found or not owned", "3", `{}`, models.ErrRecordNotFound, nil, http.StatusNotFound},
+		{"empty name", "3", `{"name": ""}`, nil, nil, http.StatusUnprocessableEntity},
+		{"duplicate name", "3", `{"name": "News"}`, nil, models.ErrDuplicateFolder, http.StatusUnprocessableEntity},
+		{"edit conflict", "3", `{"name": "Renamed"}`, nil, models.ErrEditConflict, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				folders: &mockFolderService{
+					getFn: func(id int64, userID int64) (*models.Folder, error) {
+						if tt.getErr != nil {
+							return nil, tt.getErr
+						}
+						return &models.Folder{ID: id, UserID: userID, Name: "Tech", Version: 1}, nil
+					},
+					updateFn: func(folder *models.Folder) error {
+						if folder.Name != "Renamed" && folder.Name != "News" {
+							t.Errorf("got name %q", folder.Name)
+						}
+						return tt.updateErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/folders/"+tt.id, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleDeleteFolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		deleteErr  error
+		wantStatus int
+	}{
+		{"success", "3", nil, http.StatusOK},
+		{"invalid id", "abc", nil, http.StatusNotFound},
+		{"not found or not owned", "3", models.ErrRecordNotFound, http.StatusNotFound},
+		{"service error", "3", errors.New("database connection failed"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{
+				folders: &mockFolderService{
+					deleteFn: func(id int64, userID int64) error {
+						if id != 3 || userID != testUser.ID {
+							t.Errorf("got id %d and user %d, want 3 and %d", id, userID, testUser.ID)
+						}
+						return tt.deleteErr
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/folders/"+tt.id, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandleListFolderItems(t *testing.T) {
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	s := newTestServer(&testServerOptions{
+		folders: &mockFolderService{
+			getFn: func(id int64, userID int64) (*models.Folder, error) {
+				if id != 3 {
+					return nil, models.ErrRecordNotFound
+				}
+				return &models.Folder{ID: id, UserID: userID, Name: "Tech", UnreadCount: 5}, nil
+			},
+		},
+		itemService: &mockItemService{
+			getAllForFolderFn: func(folderID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+				if folderID != 3 || userID != testUser.ID {
+					t.Errorf("got folder %d and user %d, want 3 and %d", folderID, userID, testUser.ID)
+				}
+				return []*models.Item{
+					{ID: 6, FeedID: 1, Title: "Six", PublishedAt: published},
+					{ID: 5, FeedID: 2, Title: "Five", PublishedAt: published.Add(-time.Hour)},
+				}, models.CursorMetadata{PageSize: filters.PageSize, NextCursor: "next"}, nil
+			},
+		},
+	})
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/folders/3/items?page_size=2", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := httptest.NewRecorder()
+
+		s.router().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+		}
+
+		var envelope struct {
+			Folder struct {
+				UnreadCount int `json:"unread_count"`
+			} `json:"folder"`
+			Items []struct {
+				ID     int64 `json:"id"`
+				FeedID int64 `json:"feed_id"`
+			} `json:"items"`
+			Metadata struct {
+				PageSize   int    `json:"page_size"`
+				NextCursor string `json:"next_cursor"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+
+		if envelope.Folder.UnreadCount != 5 {
+			t.Errorf("got unread count %d, want 5", envelope.Folder.UnreadCount)
+		}
+		if len(envelope.Items) != 2 || envelope.Items[1].FeedID != 2 {
+			t.Errorf("got items %+v", envelope.Items)
+		}
+		if envelope.Metadata.PageSize != 2 || envelope.Metadata.NextCursor != "next" {
+			t.Errorf("got metadata %+v", envelope.Metadata)
+		}
+	})
+
+	t.Run("folder not found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/folders/999/items", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := httptest.NewRecorder()
+
+		s.router().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+}
+
+const testOPML = `<?xml version="1.0"?>
+<opml version="2.0">
+	<body>
+		<outline text="Tech">
+			<outline text="Feed One" xmlUrl="https://one.example.com/feed"/>
+		</outline>
+		<outline text="Broken" xmlUrl="not a url"/>
+	</body>
+</opml>`
+
+func TestHandleImportOPML(t *testing.T) {
+	newServer := func(subscribed *[]*models.Subscription) *Server {
+		return newTestServer(&testServerOptions{
+			feedService: &mockFeedService{
+				getByURLFn: func(url string) (*models.Feed, error) {
+					return nil, models.ErrRecordNotFound
+				},
+				createFn: func(feed *models.Feed) error {
+					feed.ID = 4
+					return nil
+				},
+			},
+			folders: &mockFolderService{
+				getAllForUserFn: func(userID int64) ([]*models.Folder, error) {
+					return []*models.Folder{}, nil
+				},
+			},
+			subscriptions: &mockSubscriptionService{
+				createFn: func(subscription *models.Subscription) error {
+					*subscribed = append(*subscribed, subscription)
+					return nil
+				},
+			},
+		})
+	}
+
+	multipartBody := func() (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("file", "feeds.opml")
+		fw.Write([]byte(testOPML))
+		mw.Close()
+		return &buf, mw.FormDataContentType()
+	}
+
+	tests := []struct {
+		name string
+		body func() (*bytes.Buffer, string)
+	}{
+		{"raw body", func() (*bytes.Buffer, string) { return bytes.NewBufferString(testOPML), "text/x-opml" }},
+		{"multipart form", multipartBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subscribed []*models.Subscription
+			s := newServer(&subscribed)
+
+			body, contentType := tt.body()
+			req := httptest.NewRequest(http.MethodPost, "/v1/opml", body)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", contentType)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+
+			var envelope struct {
+				Results []struct {
+					URL    string            `json:"url"`
+					Folder string            `json:"folder"`
+					Status string            `json:"status"`
+					Errors map[string]string `json:"errors"`
+				} `json:"results"`
+				Summary map[string]int `json:"summary"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			if len(envelope.Results) != 2 {
+				t.Fatalf("got %d results, want 2", len(envelope.Results))
+			}
+			if envelope.Results[0].Status != "created" || envelope.Results[0].Folder != "Tech" {
+				t.Errorf("got first result %+v", envelope.Results[0])
+			}
+			if envelope.Results[1].Status != "invalid" || envelope.Results[1].Errors["url"] == "" {
+				t.Errorf("got second result %+v", envelope.Results[1])
+			}
+			if envelope.Summary["created"] != 1 || envelope.Summary["exists"] != 0 || envelope.Summary["invalid"] != 1 {
+				t.Errorf("got summary %v", envelope.Summary)
+			}
+
+			if len(subscribed) != 1 || subscribed[0].UserID != testUser.ID || subscribed[0].FolderID == 0 {
+				t.Errorf("got subscriptions %+v, want one in a folder for the test user", subscribed)
+			}
+		})
+	}
+}
+
+func TestHandleImportOPML_FetchFailed(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getByURLFn: func(url string) (*models.Feed, error) {
+				return nil, models.ErrRecordNotFound
+			},
+			createFn: func(feed *models.Feed) error {
+				t.Errorf("created feed %q that could not be fetched", feed.URL)
+				return nil
+			},
+		},
+		feedFetcher: &mockFeedFetcher{
+			fetchFn: func(ctx context.Context, url string) (*fetcher.Response, error) {
+				return nil, feedparser.ErrUnknownFormat
+			},
+		},
+		folders: &mockFolderService{
+			getAllForUserFn: func(userID int64) ([]*models.Folder, error) {
+				return []*models.Folder{}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/opml", strings.NewReader(testOPML))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "text/x-opml")
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var envelope struct {
+		Results []struct {
+			Status string            `json:"status"`
+			Errors map[string]string `json:"errors"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(envelope.Results))
+	}
+	if got := envelope.Results[0]; got.Status != "invalid" || got.Errors["url"] != "does not point to a supported feed format" {
+		t.Errorf("got first result %+v, want it invalid with the fetch error", got)
+	}
+}
+
+func TestHandleImportOPML_BadRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+	}{
+		{"not opml", `<rss version="2.0"></rss>`, "application/xml"},
+		{"empty body", "", "application/xml"},
+		{"multipart without file", "--x--\r\n", "multipart/form-data; boundary=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/opml", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestHandleExportOPML(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		folders: &mockFolderService{
+			getAllForUserFn: func(userID int64) ([]*models.Folder, error) {
+				return []*models.Folder{{ID: 2, Name: "Tech & Science"}}, nil
+			},
+		},
+		subscriptions: &mockSubscriptionService{
+			getAllForUserFn: func(userID int64) ([]*models.Subscription, error) {
+				if userID != testUser.ID {
+					t.Errorf("got user id %d, want %d", userID, testUser.ID)
+				}
+				return []*models.Subscription{
+					{FeedID: 4, FolderID: 2, Feed: &models.Feed{Title: "Feed Four", URL: "https://four.example.com/feed", SiteURL: "https://four.example.com/"}},
+				}, nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/opml", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if got := rr.Header().Get("Content-Type"); got != "text/x-opml; charset=utf-8" {
+		t.Errorf("got Content-Type %q", got)
+	}
+	if got := rr.Header().Get("Content-Disposition"); !strings.Contains(got, `filename="subscriptions.opml"`) {
+		t.Errorf("got Content-Disposition %q", got)
+	}
+
+	body := rr.Body.String()
+
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<opml version="2.0">`,
+		`<outline text="Tech &amp; Science" title="Tech &amp; Science">`,
+		`xmlUrl="https://four.example.com/feed" htmlUrl="https://four.example.com/"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestHandleExportOPML_ServiceError(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		folders: &mockFolderService{},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/opml", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q, want errors to stay JSON", got)
+	}
+}
+
+func TestHandleSearchItems(t *testing.T) {
+	var gotSearch models.ItemSearch
+	var gotFilters models.Filters
+
+	s := newTestServer(&testServerOptions{
+		itemService: &mockItemService{
+			searchFn: func(userID int64, search models.ItemSearch, filters models.Filters) ([]*models.SearchResult, models.Metadata, error) {
+				if userID != testUser.ID {
+					t.Errorf("got user id %d, want %d", userID, testUser.ID)
+				}
+				gotSearch = search
+				gotFilters = filters
+				return []*models.SearchResult{
+					{Item: &models.Item{ID: 10, Title: "Generics in Go"}, Rank: 0.8, Headline: "using <mark>generics</mark>"},
+				}, models.CalculateMetadata(1, filters.Page, filters.PageSize), nil
+			},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, `/v1/search?q=%22type+parameters%22+-java&published_after=2024-01-01&published_before=2024-02-01T00:00:00Z&sort=-published_at&page_size=5`, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rr := httptest.NewRecorder()
+
+	s.router().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	if gotSearch.Query != `"type parameters" -java` {
+		t.Errorf("got query %q", gotSearch.Query)
+	}
+	if !gotSearch.PublishedAfter.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotSearch.PublishedBefore.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got range %v to %v", gotSearch.PublishedAfter, gotSearch.PublishedBefore)
+	}
+	if gotFilters.Sort != "-published_at" || gotFilters.PageSize != 5 {
+		t.Errorf("got filters %+v", gotFilters)
+	}
+
+	var envelope struct {
+		Results []struct {
+			ID       int64   `json:"id"`
+			Title    string  `json:"title"`
+			Rank     float64 `json:"rank"`
+			Headline string  `json:"headline"`
+		} `json:"results"`
+		Metadata struct {
+			TotalRecords int `json:"total_records"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	if len(envelope.Results) != 1 || envelope.Results[0].ID != 10 || envelope.Results[0].Headline != "using <mark>generics</mark>" {
+		t.Errorf("got results %+v", envelope.Results)
+	}
+	if envelope.Metadata.TotalRecords != 1 {
+		t.Errorf("got total records %d, want 1", envelope.Metadata.TotalRecords)
+	}
+}
+
+func TestHandleSearchItems_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantErrors map[string]string
+	}{
+		{"missing query", "", map[string]string{"q": "must be provided"}},
+		{"bad date", "q=go&published_after=last+week", map[string]string{"published_after": "must be an RFC 3339 timestamp or a YYYY-MM-DD date"}},
+		{"empty range", "q=go&published_after=2024-02-01&published_before=2024-01-01", map[string]string{"published_before": "must be later than published_after"}},
+		{"bad sort", "q=go&sort=title", map[string]string{"sort": "invalid sort value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&testServerOptions{itemService: &mockItemService{}})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/search?"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+
+			s.router().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var resp struct {
+				Error map[string]string `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			for field, wantMsg := range tt.wantErrors {
+				if resp.Error[field] != wantMsg {
+					t.Errorf("field %q: got %q, want %q", field, resp.Error[field], wantMsg)
+				}
+			}
+		})
+	}
+}
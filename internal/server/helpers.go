@@ -1,15 +1,21 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/validator"
 )
 
 func (s *Server) readIDParam(r *http.Request) (int64, error) {
@@ -48,6 +54,41 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, data envelope, hea
 	return nil
 }
 
+// writeXML is the XML counterpart to writeJSON. The document is encoded
+// straight to the response rather than buffered, so once the status has been
+// sent an encoding or write error can only be logged by the caller. The
+// Content-Type defaults to application/xml, since formats built on XML
+// usually have their own and set it through headers.
+func (s *Server) writeXML(w http.ResponseWriter, status int, data any, headers http.Header) error {
+	for key, values := range headers {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/xml")
+	}
+	w.WriteHeader(status)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// errEmptyBody is returned by readJSON when the request has no body, for
+// handlers whose input is entirely optional.
+var errEmptyBody = errors.New("body must not be empty")
+
 // From let's go further book. See for further explanation on different potential errors
 func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
@@ -76,7 +117,7 @@ func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, dst any) error
 			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
 
 		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
+			return errEmptyBody
 
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
@@ -101,18 +142,83 @@ func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, dst any) error
 	return nil
 }
 
+func (s *Server) readString(qs url.Values, key string, defaultValue string) string {
+	str := qs.Get(key)
+
+	if str == "" {
+		return defaultValue
+	}
+
+	return str
+}
+
+func (s *Server) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	str := qs.Get(key)
+
+	if str == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(str)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+// readTime reads an RFC 3339 timestamp or a plain date, which is taken as
+// midnight UTC.
+func (s *Server) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	str := qs.Get(key)
+
+	if str == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t
+		}
+	}
+
+	v.AddError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	return time.Time{}
+}
+
+// fetchFailedResponse reports a feed URL that could not be fetched: as a 504
+// when the upstream server ran out of time, and otherwise as a validation
+// error on the url field.
+func (s *Server) fetchFailedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		message := "the feed took too long to respond, please try again later"
+		s.errorResponse(w, r, http.StatusGatewayTimeout, message)
+		return
+	}
+
+	s.failedValidationResponse(w, r, map[string]string{"url": fetcher.ErrorMessage(err)})
+}
+
 func (s *Server) logError(r *http.Request, err error) {
 	var (
 		method = r.Method
 		uri    = r.URL.RequestURI()
 	)
 
-	s.logger.Error(err.Error(), "method", method, "uri", uri)
+	s.logger.Error(err.Error(), "request_id", s.contextGetRequestID(r), "method", method, "uri", uri)
 }
 
+// errorResponse writes message as a JSON error. Server errors also carry the
+// request ID, so that users reporting a problem can point at the matching
+// log lines.
 func (s *Server) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := envelope{"error": message}
 
+	if id := s.contextGetRequestID(r); id != "" && status >= http.StatusInternalServerError {
+		env["request_id"] = id
+	}
+
 	err := s.writeJSON(w, status, env, nil)
 	if err != nil {
 		s.logError(r, err)
@@ -120,10 +226,28 @@ func (s *Server) errorResponse(w http.ResponseWriter, r *http.Request, status in
 	}
 }
 
+// statusClientClosedRequest is the non-standard status nginx uses for
+// requests abandoned by the client. Nobody reads the response, but it marks
+// the request in the access log.
+const statusClientClosedRequest = 499
+
+// serverErrorResponse reports an unexpected error. Errors caused by the
+// request's context ending are told apart: a client that went away is only
+// logged, and a query that ran out of time is a 503 the client can retry.
 func (s *Server) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	s.logError(r, err)
-	message := "the server encountered a problem and could not process your request"
-	s.errorResponse(w, r, http.StatusInternalServerError, message)
+	switch {
+	case errors.Is(err, context.Canceled):
+		s.logger.Info("request canceled by client", "request_id", s.contextGetRequestID(r), "method", r.Method, "uri", r.URL.RequestURI())
+		w.WriteHeader(statusClientClosedRequest)
+	case errors.Is(err, context.DeadlineExceeded):
+		s.logError(r, err)
+		message := "the server took too long to process your request, please try again later"
+		s.errorResponse(w, r, http.StatusServiceUnavailable, message)
+	default:
+		s.logError(r, err)
+		message := "the server encountered a problem and could not process your request"
+		s.errorResponse(w, r, http.StatusInternalServerError, message)
+	}
 }
 
 func (s *Server) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
@@ -140,6 +264,11 @@ func (s *Server) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request
 	s.errorResponse(w, r, http.StatusMethodNotAllowed, message)
 }
 
+func (s *Server) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	message := "rate limit exceeded"
+	s.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
 func (s *Server) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
 	s.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
@@ -148,3 +277,27 @@ func (s *Server) editConflictResponse(w http.ResponseWriter, r *http.Request) {
 	message := "unable to update the record due to an edit conflict, please try again"
 	s.errorResponse(w, r, http.StatusConflict, message)
 }
+
+func (s *Server) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (s *Server) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (s *Server) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "you must be authenticated to access this resource"
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (s *Server) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
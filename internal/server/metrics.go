@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grodier/rss-app/internal/metrics"
+)
+
+// httpMetrics are the request metrics recorded by the instrument middleware.
+type httpMetrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+	inFlight *metrics.Gauge
+}
+
+func newHTTPMetrics(r *metrics.Registry) *httpMetrics {
+	return &httpMetrics{
+		requests: r.NewCounter("http_requests_total", "Number of HTTP requests served.", "method", "route", "status"),
+		duration: r.NewHistogram("http_request_duration_seconds", "Time taken to serve HTTP requests.", metrics.DefaultBuckets, "method", "route", "status"),
+		inFlight: r.NewGauge("http_requests_in_flight", "Number of HTTP requests being served."),
+	}
+}
+
+// instrument records the count and latency of requests. Requests are labelled
+// with the route pattern they matched rather than their path, so that IDs in
+// URLs don't create a series each.
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		s.httpMetrics.inFlight.Inc()
+		defer s.httpMetrics.inFlight.Dec()
+
+		rec := newStatusRecorder(w)
+
+		defer func() {
+			method := methodLabel(r.Method)
+			route := routePattern(r)
+			status := strconv.Itoa(rec.status)
+
+			s.httpMetrics.requests.Inc(method, route, status)
+			s.httpMetrics.duration.Observe(time.Since(start).Seconds(), method, route, status)
+		}()
+
+		rec.serve(next, r)
+	})
+}
+
+// methodLabel returns the label for a request method, collapsing anything but the
+// standard methods into "OTHER", as clients can send any token as a method.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
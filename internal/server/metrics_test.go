@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestInstrument(t *testing.T) {
+	s := newTestServer(&testServerOptions{
+		feedService: &mockFeedService{
+			getFn: func(id int64) (*models.Feed, error) {
+				return &models.Feed{ID: id, URL: "https://example.com/feed"}, nil
+			},
+		},
+	})
+	s.Metrics = metrics.NewRegistry()
+
+	router := s.router()
+
+	for _, path := range []string{"/v1/feeds/1", "/v1/feeds/2", "/v1/healthcheck", "/nowhere"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var sb strings.Builder
+	s.Metrics.WriteTo(&sb)
+	got := sb.String()
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/v1/feeds/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/v1/healthcheck",status="200"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/v1/feeds/{id}",status="200"} 2`,
+		`http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("expected metrics to contain %q, got\n%s", want, got)
+		}
+	}
+
+	if strings.Contains(got, "/v1/feeds/1") {
+		t.Error("expected raw paths not to be used as labels")
+	}
+}
+
+func TestInstrument_NonStandardMethods(t *testing.T) {
+	s := newTestServer(nil)
+	s.Metrics = metrics.NewRegistry()
+
+	router := s.router()
+
+	for _, method := range []string{"PROPFIND", "BREW", "get"} {
+		req := httptest.NewRequest(method, "/v1/healthcheck", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var sb strings.Builder
+	s.Metrics.WriteTo(&sb)
+	got := sb.String()
+
+	if want := `http_requests_total{method="OTHER",route="unmatched",status="405"} 3`; !strings.Contains(got, want+"\n") {
+		t.Errorf("expected metrics to contain %q, got\n%s", want, got)
+	}
+
+	for _, method := range []string{"PROPFIND", "BREW", "get"} {
+		if strings.Contains(got, `method="`+method+`"`) {
+			t.Errorf("expected method %q not to be used as a label", method)
+		}
+	}
+}
@@ -1,10 +1,106 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/validator"
 )
 
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients.
+const maxRequestIDLength = 128
+
+// requestID tags each request with an ID, taken from X-Request-ID when the
+// client or a proxy in front of us sent a usable one, and echoes it back.
+func (s *Server) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		r = s.contextSetRequestID(r, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID rejects empty and overlong IDs, and any containing spaces or
+// control characters that could forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// accessLogEntry collects details for the access log that are only known
+// further down the middleware chain.
+type accessLogEntry struct {
+	user *models.User
+}
+
+// logRequest writes one access log line for each request once it has been
+// served.
+func (s *Server) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		entry := &accessLogEntry{}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogContextKey, entry))
+
+		rec := newStatusRecorder(w)
+
+		defer func() {
+			attrs := []any{
+				"request_id", s.contextGetRequestID(r),
+				"method", r.Method,
+				"route", routePattern(r),
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+				"remote_ip", clientIP(r, s.RateLimit.TrustedProxies),
+			}
+
+			if entry.user != nil && !entry.user.IsAnonymous() {
+				attrs = append(attrs, "user_id", entry.user.ID)
+			}
+
+			s.logger.Info("request", attrs...)
+		}()
+
+		rec.serve(next, r)
+	})
+}
+
+// routePattern returns the pattern of the route that served r, or
+// "unmatched" for requests that never reached one, such as unknown paths or
+// those refused by the rate limiter. It must be called after the router has
+// handled r.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
+
 func (s *Server) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -17,3 +113,158 @@ func (s *Server) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// enableCORS allows browsers on the trusted origins to call the API, and
+// answers their preflight requests before they reach the router.
+func (s *Server) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+
+		if origin != "" && slices.Contains(s.TrustedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate loads the user identified by the request's bearer token into
+// the request context. Requests without an Authorization header continue as
+// models.AnonymousUser.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+
+		if authorizationHeader == "" {
+			r = s.contextSetUser(r, models.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(authorizationHeader, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			s.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		v := validator.NewValidator()
+
+		if models.ValidateTokenPlaintext(v, token); !v.Valid() {
+			s.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := s.UserService.GetForToken(models.ScopeAuthentication, token)
+		if err != nil {
+			switch {
+			case err == models.ErrRecordNotFound:
+				s.invalidAuthenticationTokenResponse(w, r)
+			default:
+				s.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		r = s.contextSetUser(r, user)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) requireAuthenticatedUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := s.contextGetUser(r)
+
+		if user.IsAnonymous() {
+			s.authenticationRequiredResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requirePermission wraps a route so that only authenticated users holding
+// the given permission code can reach it.
+func (s *Server) requirePermission(code string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user := s.contextGetUser(r)
+
+			permissions, err := s.PermissionService.GetAllForUser(user.ID)
+			if err != nil {
+				s.serverErrorResponse(w, r, err)
+				return
+			}
+
+			if !permissions.Include(code) {
+				s.notPermittedResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return s.requireAuthenticatedUser(http.HandlerFunc(fn))
+	}
+}
+
+// statusRecorder remembers the status code and number of bytes written to
+// the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// serve passes r on to next. If next panics before writing a status, the
+// status is recorded as the 500 that recoverPanic will answer with, and the
+// panic carries on to it.
+func (rec *statusRecorder) serve(next http.Handler, r *http.Request) {
+	panicked := true
+	defer func() {
+		if panicked && !rec.wroteHeader {
+			rec.status = http.StatusInternalServerError
+		}
+	}()
+
+	next.ServeHTTP(rec, r)
+	panicked = false
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
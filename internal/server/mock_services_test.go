@@ -1,21 +1,32 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"time"
 
+	"github.com/grodier/rss-app/internal/feedparser"
+	"github.com/grodier/rss-app/internal/fetcher"
 	"github.com/grodier/rss-app/internal/models"
 )
 
 // mockFeedService is a mock implementation of models.FeedService for testing
 type mockFeedService struct {
-	createFn func(feed *models.Feed) error
-	getFn    func(id int64) (*models.Feed, error)
-	updateFn func(feed *models.Feed) error
-	deleteFn func(id int64) error
+	createFn   func(feed *models.Feed) error
+	getFn      func(id int64) (*models.Feed, error)
+	getByURLFn func(url string) (*models.Feed, error)
+	getAllFn   func(title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error)
+	updateFn   func(feed *models.Feed) error
+	deleteFn   func(id int64) error
+
+	claimDueFn         func(limit int, lease time.Duration) ([]*models.Feed, error)
+	updateFetchStateFn func(feed *models.Feed) error
+
+	// getCtx is the context Get was last called with.
+	getCtx context.Context
 }
 
-func (m *mockFeedService) Create(feed *models.Feed) error {
+func (m *mockFeedService) Create(ctx context.Context, feed *models.Feed) error {
 	if m.createFn != nil {
 		return m.createFn(feed)
 	}
@@ -26,23 +37,347 @@ func (m *mockFeedService) Create(feed *models.Feed) error {
 	return nil
 }
 
-func (m *mockFeedService) Get(id int64) (*models.Feed, error) {
+func (m *mockFeedService) Get(ctx context.Context, id int64) (*models.Feed, error) {
+	m.getCtx = ctx
 	if m.getFn != nil {
 		return m.getFn(id)
 	}
 	return nil, errors.New("not implemented")
 }
 
-func (m *mockFeedService) Update(feed *models.Feed) error {
+func (m *mockFeedService) GetByURL(ctx context.Context, url string) (*models.Feed, error) {
+	if m.getByURLFn != nil {
+		return m.getByURLFn(url)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFeedService) GetAll(ctx context.Context, title string, language string, filters models.Filters) ([]*models.Feed, models.Metadata, error) {
+	if m.getAllFn != nil {
+		return m.getAllFn(title, language, filters)
+	}
+	return nil, models.Metadata{}, errors.New("not implemented")
+}
+
+func (m *mockFeedService) Update(ctx context.Context, feed *models.Feed) error {
 	if m.updateFn != nil {
 		return m.updateFn(feed)
 	}
 	return errors.New("not implemented")
 }
 
-func (m *mockFeedService) Delete(id int64) error {
+func (m *mockFeedService) Delete(ctx context.Context, id int64) error {
 	if m.deleteFn != nil {
 		return m.deleteFn(id)
 	}
 	return errors.New("not implemented")
 }
+
+func (m *mockFeedService) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Feed, error) {
+	if m.claimDueFn != nil {
+		return m.claimDueFn(limit, lease)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFeedService) UpdateFetchState(ctx context.Context, feed *models.Feed) error {
+	if m.updateFetchStateFn != nil {
+		return m.updateFetchStateFn(feed)
+	}
+	return errors.New("not implemented")
+}
+
+// mockItemService is a mock implementation of models.ItemService for testing
+type mockItemService struct {
+	upsertFn          func(item *models.Item) error
+	getFn             func(id int64) (*models.Item, error)
+	getAllForFeedFn   func(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error)
+	getAllForFolderFn func(folderID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error)
+	searchFn          func(userID int64, search models.ItemSearch, filters models.Filters) ([]*models.SearchResult, models.Metadata, error)
+}
+
+func (m *mockItemService) Upsert(item *models.Item) error {
+	if m.upsertFn != nil {
+		return m.upsertFn(item)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *mockItemService) Get(id int64) (*models.Item, error) {
+	if m.getFn != nil {
+		return m.getFn(id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockItemService) GetAllForFeed(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	if m.getAllForFeedFn != nil {
+		return m.getAllForFeedFn(feedID, userID, filters)
+	}
+	return nil, models.CursorMetadata{}, errors.New("not implemented")
+}
+
+func (m *mockItemService) GetAllForFolder(folderID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	if m.getAllForFolderFn != nil {
+		return m.getAllForFolderFn(folderID, userID, filters)
+	}
+	return nil, models.CursorMetadata{}, errors.New("not implemented")
+}
+
+func (m *mockItemService) Search(userID int64, search models.ItemSearch, filters models.Filters) ([]*models.SearchResult, models.Metadata, error) {
+	if m.searchFn != nil {
+		return m.searchFn(userID, search, filters)
+	}
+	return nil, models.Metadata{}, errors.New("not implemented")
+}
+
+// mockUserService is a mock implementation of models.UserService for testing
+type mockUserService struct {
+	createFn      func(user *models.User) error
+	getByEmailFn  func(email string) (*models.User, error)
+	getForTokenFn func(scope, tokenPlaintext string) (*models.User, error)
+}
+
+func (m *mockUserService) Create(user *models.User) error {
+	if m.createFn != nil {
+		return m.createFn(user)
+	}
+	// Default behavior: simulate successful creation with ID, timestamp, and version
+	user.ID = 1
+	user.CreatedAt = time.Now()
+	user.Version = 1
+	return nil
+}
+
+func (m *mockUserService) GetByEmail(email string) (*models.User, error) {
+	if m.getByEmailFn != nil {
+		return m.getByEmailFn(email)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserService) GetForToken(scope, tokenPlaintext string) (*models.User, error) {
+	if m.getForTokenFn != nil {
+		return m.getForTokenFn(scope, tokenPlaintext)
+	}
+	// Default behavior: testToken authenticates as testUser
+	if scope == models.ScopeAuthentication && tokenPlaintext == testToken {
+		return testUser, nil
+	}
+	return nil, models.ErrRecordNotFound
+}
+
+// mockTokenService is a mock implementation of models.TokenService for testing
+type mockTokenService struct {
+	createFn           func(token *models.Token) error
+	deleteAllForUserFn func(scope string, userID int64) error
+}
+
+func (m *mockTokenService) Create(token *models.Token) error {
+	if m.createFn != nil {
+		return m.createFn(token)
+	}
+	return nil
+}
+
+func (m *mockTokenService) DeleteAllForUser(scope string, userID int64) error {
+	if m.deleteAllForUserFn != nil {
+		return m.deleteAllForUserFn(scope, userID)
+	}
+	return nil
+}
+
+// mockPermissionService is a mock implementation of models.PermissionService for testing
+type mockPermissionService struct {
+	getAllForUserFn func(userID int64) (models.Permissions, error)
+	addForUserFn    func(userID int64, codes ...string) error
+}
+
+func (m *mockPermissionService) GetAllForUser(userID int64) (models.Permissions, error) {
+	if m.getAllForUserFn != nil {
+		return m.getAllForUserFn(userID)
+	}
+	// Default behavior: testUser holds every permission
+	if userID == testUser.ID {
+		return models.Permissions{models.PermissionFeedsRead, models.PermissionFeedsWrite, models.PermissionAdmin}, nil
+	}
+	return models.Permissions{}, nil
+}
+
+func (m *mockPermissionService) AddForUser(userID int64, codes ...string) error {
+	if m.addForUserFn != nil {
+		return m.addForUserFn(userID, codes...)
+	}
+	return nil
+}
+
+// mockSubscriptionService is a mock implementation of models.SubscriptionService for testing
+type mockSubscriptionService struct {
+	createFn        func(subscription *models.Subscription) error
+	getFn           func(id int64, userID int64) (*models.Subscription, error)
+	getAllForUserFn func(userID int64) ([]*models.Subscription, error)
+	updateFn        func(subscription *models.Subscription) error
+	deleteFn        func(id int64, userID int64) error
+}
+
+func (m *mockSubscriptionService) Create(subscription *models.Subscription) error {
+	if m.createFn != nil {
+		return m.createFn(subscription)
+	}
+	// Default behavior: simulate successful creation with ID, timestamp, and version
+	subscription.ID = 1
+	subscription.CreatedAt = time.Now()
+	subscription.Version = 1
+	return nil
+}
+
+func (m *mockSubscriptionService) Get(id int64, userID int64) (*models.Subscription, error) {
+	if m.getFn != nil {
+		return m.getFn(id, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockSubscriptionService) GetAllForUser(userID int64) ([]*models.Subscription, error) {
+	if m.getAllForUserFn != nil {
+		return m.getAllForUserFn(userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockSubscriptionService) Update(subscription *models.Subscription) error {
+	if m.updateFn != nil {
+		return m.updateFn(subscription)
+	}
+	subscription.Version++
+	return nil
+}
+
+func (m *mockSubscriptionService) Delete(id int64, userID int64) error {
+	if m.deleteFn != nil {
+		return m.deleteFn(id, userID)
+	}
+	return errors.New("not implemented")
+}
+
+// mockItemStateService is a mock implementation of models.ItemStateService for testing
+type mockItemStateService struct {
+	updateFn               func(userID int64, itemIDs []int64, update models.ItemStateUpdate) (int64, error)
+	markSubscriptionReadFn func(subscriptionID int64, userID int64, before time.Time) error
+}
+
+func (m *mockItemStateService) Update(userID int64, itemIDs []int64, update models.ItemStateUpdate) (int64, error) {
+	if m.updateFn != nil {
+		return m.updateFn(userID, itemIDs, update)
+	}
+	return 0, errors.New("not implemented")
+}
+
+func (m *mockItemStateService) MarkSubscriptionRead(subscriptionID int64, userID int64, before time.Time) error {
+	if m.markSubscriptionReadFn != nil {
+		return m.markSubscriptionReadFn(subscriptionID, userID, before)
+	}
+	return errors.New("not implemented")
+}
+
+// mockFeedFetcher is a mock implementation of FeedFetcher for testing
+type mockFeedFetcher struct {
+	fetchFn    func(ctx context.Context, url string) (*fetcher.Response, error)
+	discoverFn func(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error)
+}
+
+func (m *mockFeedFetcher) Fetch(ctx context.Context, url string) (*fetcher.Response, error) {
+	if m.fetchFn != nil {
+		return m.fetchFn(ctx, url)
+	}
+	// Default behavior: a reachable feed that advertises no metadata
+	return &fetcher.Response{
+		URL:    url,
+		Result: &feedparser.Result{Feed: &models.Feed{}},
+	}, nil
+}
+
+func (m *mockFeedFetcher) Discover(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error) {
+	if m.discoverFn != nil {
+		return m.discoverFn(ctx, pageURL)
+	}
+	return []*fetcher.Candidate{}, nil
+}
+
+// mockFolderService is a mock implementation of models.FolderService for testing
+type mockFolderService struct {
+	createFn        func(folder *models.Folder) error
+	getFn           func(id int64, userID int64) (*models.Folder, error)
+	getAllForUserFn func(userID int64) ([]*models.Folder, error)
+	updateFn        func(folder *models.Folder) error
+	deleteFn        func(id int64, userID int64) error
+}
+
+func (m *mockFolderService) Create(folder *models.Folder) error {
+	if m.createFn != nil {
+		return m.createFn(folder)
+	}
+	// Default behavior: simulate successful creation with ID, timestamp, and version
+	folder.ID = 1
+	folder.CreatedAt = time.Now()
+	folder.Version = 1
+	return nil
+}
+
+func (m *mockFolderService) Get(id int64, userID int64) (*models.Folder, error) {
+	if m.getFn != nil {
+		return m.getFn(id, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFolderService) GetAllForUser(userID int64) ([]*models.Folder, error) {
+	if m.getAllForUserFn != nil {
+		return m.getAllForUserFn(userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockFolderService) Update(folder *models.Folder) error {
+	if m.updateFn != nil {
+		return m.updateFn(folder)
+	}
+	folder.Version++
+	return nil
+}
+
+func (m *mockFolderService) Delete(id int64, userID int64) error {
+	if m.deleteFn != nil {
+		return m.deleteFn(id, userID)
+	}
+	return errors.New("not implemented")
+}
+
+// mockTransactor is a mock implementation of models.Transactor for testing.
+// It calls fn with the server's services and counts how transactions end.
+type mockTransactor struct {
+	s *Server
+
+	commits   int
+	rollbacks int
+}
+
+func (m *mockTransactor) WithTx(ctx context.Context, fn func(tx *models.Services) error) error {
+	err := fn(&models.Services{
+		Feeds:         m.s.FeedService,
+		Items:         m.s.ItemService,
+		Users:         m.s.UserService,
+		Tokens:        m.s.TokenService,
+		Permissions:   m.s.PermissionService,
+		Subscriptions: m.s.SubscriptionService,
+		ItemStates:    m.s.ItemStateService,
+		Folders:       m.s.FolderService,
+	})
+	if err != nil {
+		m.rollbacks++
+		return err
+	}
+
+	m.commits++
+	return nil
+}
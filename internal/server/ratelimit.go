@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig sets the token buckets applied to every request. Each
+// client IP gets a bucket, and authenticated users get a second one keyed by
+// user so that clients sharing an address don't exhaust each other's quota.
+type RateLimitConfig struct {
+	Enabled bool
+
+	IPRequestsPerSecond float64
+	IPBurst             int
+
+	UserRequestsPerSecond float64
+	UserBurst             int
+
+	// TrustedProxies are the addresses allowed to report the client IP
+	// through X-Forwarded-For. The header is ignored from anyone else.
+	TrustedProxies []netip.Prefix
+}
+
+// staleBucketAge is how long a bucket may go unused before cleanup removes
+// it. A bucket idle this long has refilled anyway.
+const staleBucketAge = 3 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter holds one token bucket per key.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		limit:   rate.Limit(rps),
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from key's bucket. It returns the tokens left and, when
+// the request is refused, how long until a token is available.
+func (rl *rateLimiter) allow(key string, now time.Time) (ok bool, remaining float64, retryAfter time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, exists := rl.buckets[key]
+	if !exists {
+		b = &bucket{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	ok = b.limiter.AllowN(now, 1)
+	remaining = max(b.limiter.TokensAt(now), 0)
+
+	if !ok {
+		retryAfter = rl.refillTime(1 - remaining)
+	}
+
+	return ok, remaining, retryAfter
+}
+
+// refillTime returns how long the bucket takes to gain the given tokens.
+func (rl *rateLimiter) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / float64(rl.limit) * float64(time.Second))
+}
+
+// cleanup removes buckets that haven't been used since before.
+func (rl *rateLimiter) cleanup(before time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for key, b := range rl.buckets {
+		if b.lastSeen.Before(before) {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// cleanupRateLimiters periodically drops stale buckets until ctx is done.
+func (s *Server) cleanupRateLimiters(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.ipLimiter.cleanup(now.Add(-staleBucketAge))
+			s.userLimiter.cleanup(now.Add(-staleBucketAge))
+		}
+	}
+}
+
+// rateLimit refuses requests once the bucket named by key is empty. Requests
+// for which key returns "" are not limited.
+func (s *Server) rateLimit(rl *rateLimiter, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ok, remaining, retryAfter := rl.allow(k, time.Now())
+
+			reset := rl.refillTime(float64(rl.burst) - remaining)
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(rl.burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(retryAfter), 1)))
+				s.rateLimitExceededResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *Server) ipRateLimitKey(r *http.Request) string {
+	return "ip:" + clientIP(r, s.RateLimit.TrustedProxies)
+}
+
+func (s *Server) userRateLimitKey(r *http.Request) string {
+	user := s.contextGetUser(r)
+	if user.IsAnonymous() {
+		return ""
+	}
+	return "user:" + strconv.FormatInt(user.ID, 10)
+}
+
+// clientIP returns the address of the client that made the request. When the
+// connection comes from a trusted proxy, X-Forwarded-For is read from the
+// right, skipping further trusted proxies, so that clients can't choose their
+// own address by sending the header themselves.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(remote, trusted) {
+		return host
+	}
+
+	client := remote
+
+	for _, header := range slices.Backward(r.Header.Values("X-Forwarded-For")) {
+		hops := strings.Split(header, ",")
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return client.String()
+			}
+
+			client = addr.Unmap()
+			if !isTrusted(client, trusted) {
+				return client.String()
+			}
+		}
+	}
+
+	return client.String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	rl := newRateLimiter(2, 3)
+	now := time.Now()
+
+	for i := range 3 {
+		ok, remaining, _ := rl.allow("client", now)
+		if !ok {
+			t.Fatalf("request %d: expected to be allowed within the burst", i+1)
+		}
+		if want := float64(2 - i); remaining != want {
+			t.Errorf("request %d: got %v remaining, want %v", i+1, remaining, want)
+		}
+	}
+
+	ok, _, retryAfter := rl.allow("client", now)
+	if ok {
+		t.Fatal("expected request over the burst to be refused")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("got retry after %v, want 500ms at 2 requests per second", retryAfter)
+	}
+
+	if ok, _, _ := rl.allow("other", now); !ok {
+		t.Error("expected another key to have its own bucket")
+	}
+
+	if ok, _, _ := rl.allow("client", now.Add(500*time.Millisecond)); !ok {
+		t.Error("expected a token to be available after refilling")
+	}
+}
+
+func TestRateLimiter_Cleanup(t *testing.T) {
+	rl := newRateLimiter(1, 1)
+	now := time.Now()
+
+	rl.allow("stale", now.Add(-time.Hour))
+	rl.allow("fresh", now)
+
+	rl.cleanup(now.Add(-staleBucketAge))
+
+	if _, ok := rl.buckets["stale"]; ok {
+		t.Error("expected stale bucket to be removed")
+	}
+	if _, ok := rl.buckets["fresh"]; !ok {
+		t.Error("expected fresh bucket to be kept")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		wantClientIP string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted proxy is ignored", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed hops left of the client are ignored", "10.0.0.2:1234", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:1234", []string{"198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"multiple headers", "10.0.0.2:1234", []string{"1.1.1.1", "198.51.100.1"}, "198.51.100.1"},
+		{"ipv6 proxy", "[::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"malformed hop", "10.0.0.2:1234", []string{"garbage"}, "10.0.0.2"},
+		{"only trusted hops", "10.0.0.2:1234", []string{"10.0.0.3"}, "10.0.0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := clientIP(req, trusted); got != tt.wantClientIP {
+				t.Errorf("got %q, want %q", got, tt.wantClientIP)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newTestServer(nil)
+	s.RateLimit = RateLimitConfig{
+		Enabled:               true,
+		IPRequestsPerSecond:   1,
+		IPBurst:               3,
+		UserRequestsPerSecond: 1,
+		UserBurst:             1,
+	}
+
+	router := s.router()
+
+	get := func(authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		if authenticated {
+			req.Header.Set("Authorization", "Bearer "+testToken)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(true)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("RateLimit-Limit"); got != "1" {
+		t.Errorf("got RateLimit-Limit %q, want the user bucket's burst of 1", got)
+	}
+	if got := rr.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("got RateLimit-Remaining %q, want 0", got)
+	}
+
+	rr = get(true)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want %d once the user bucket is empty", rr.Code, http.StatusTooManyRequests)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("got Retry-After %q, want 1", got)
+	}
+
+	// Anonymous requests from the same address only draw on the IP bucket,
+	// which has one token left after the two requests above.
+	if rr := get(false); rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+	if rr := get(false); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d once the IP bucket is empty", rr.Code, http.StatusTooManyRequests)
+	}
+}
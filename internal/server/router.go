@@ -4,22 +4,81 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/grodier/rss-app/internal/models"
 )
 
 func (s *Server) router() http.Handler {
 	router := chi.NewRouter()
 
+	// Panics are recovered outside of logging and metrics, so that those
+	// are covered too; they record a panicking request as the 500 that
+	// recoverPanic answers it with.
+	router.Use(s.requestID)
 	router.Use(s.recoverPanic)
+	router.Use(s.logRequest)
+
+	if s.Metrics != nil {
+		s.httpMetrics = newHTTPMetrics(s.Metrics)
+		router.Use(s.instrument)
+	}
+
+	router.Use(s.enableCORS)
+
+	if s.RateLimit.Enabled {
+		s.ipLimiter = newRateLimiter(s.RateLimit.IPRequestsPerSecond, s.RateLimit.IPBurst)
+		s.userLimiter = newRateLimiter(s.RateLimit.UserRequestsPerSecond, s.RateLimit.UserBurst)
+
+		// Limit by IP before authenticating, so that guessing tokens
+		// doesn't cost a database query per attempt.
+		router.Use(s.rateLimit(s.ipLimiter, s.ipRateLimitKey))
+		router.Use(s.authenticate)
+		router.Use(s.rateLimit(s.userLimiter, s.userRateLimitKey))
+	} else {
+		router.Use(s.authenticate)
+	}
 
 	router.NotFound(s.notFoundResponse)
 	router.MethodNotAllowed(s.methodNotAllowedResponse)
 
+	canRead := s.requirePermission(models.PermissionFeedsRead)
+	canWrite := s.requirePermission(models.PermissionFeedsWrite)
+	isAdmin := s.requirePermission(models.PermissionAdmin)
+
 	router.Get("/v1/healthcheck", s.handleHealthcheck)
 
-	router.Post("/v1/admin/feeds", s.handleCreateFeed)
-	router.Get("/v1/feeds/{id}", s.handleShowFeed)
-	router.Patch("/v1/feeds/{id}", s.handleUpdateFeed)
-	router.Delete("/v1/feeds/{id}", s.handleDeleteFeed)
+	router.With(canRead).Get("/v1/feeds", s.handleListFeeds)
+	router.With(canRead).Get("/v1/feeds/{id}", s.handleShowFeed)
+	router.With(canRead).Get("/v1/feeds/{id}/items", s.handleListFeedItems)
+	router.With(canWrite).Post("/v1/feeds/discover", s.handleDiscoverFeeds)
+
+	router.With(canRead).Get("/v1/items/{id}", s.handleShowItem)
+	router.With(canWrite).Patch("/v1/items/state", s.handleBulkUpdateItemState)
+	router.With(canWrite).Patch("/v1/items/{id}/state", s.handleUpdateItemState)
+
+	router.With(canRead).Get("/v1/search", s.handleSearchItems)
+
+	router.With(canRead).Get("/v1/subscriptions", s.handleListSubscriptions)
+	router.With(canWrite).Post("/v1/subscriptions", s.handleCreateSubscription)
+	router.With(canWrite).Patch("/v1/subscriptions/{id}", s.handleUpdateSubscription)
+	router.With(canWrite).Delete("/v1/subscriptions/{id}", s.handleDeleteSubscription)
+	router.With(canWrite).Post("/v1/subscriptions/{id}/read", s.handleMarkSubscriptionRead)
+
+	router.With(canRead).Get("/v1/folders", s.handleListFolders)
+	router.With(canWrite).Post("/v1/folders", s.handleCreateFolder)
+	router.With(canRead).Get("/v1/folders/{id}", s.handleShowFolder)
+	router.With(canWrite).Patch("/v1/folders/{id}", s.handleUpdateFolder)
+	router.With(canWrite).Delete("/v1/folders/{id}", s.handleDeleteFolder)
+	router.With(canRead).Get("/v1/folders/{id}/items", s.handleListFolderItems)
+
+	router.With(canRead).Get("/v1/opml", s.handleExportOPML)
+	router.With(canWrite).Post("/v1/opml", s.handleImportOPML)
+
+	router.Post("/v1/users", s.handleRegisterUser)
+	router.Post("/v1/tokens/authentication", s.handleCreateAuthenticationToken)
+
+	router.With(isAdmin).Post("/v1/admin/feeds", s.handleCreateFeed)
+	router.With(isAdmin).Patch("/v1/admin/feeds/{id}", s.handleUpdateFeed)
+	router.With(isAdmin).Delete("/v1/admin/feeds/{id}", s.handleDeleteFeed)
 
 	return router
 }
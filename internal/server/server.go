@@ -11,18 +11,62 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/grodier/rss-app/internal/fetcher"
+	"github.com/grodier/rss-app/internal/metrics"
 	"github.com/grodier/rss-app/internal/models"
 )
 
-type Server struct {
-	Port    int
-	Env     string
-	Version string
+// writeTimeout bounds how long a handler has to write its response; the
+// connection is dropped without one after that.
+const writeTimeout = 10 * time.Second
+
+// fetchTimeout bounds fetching remote documents while handling a request. It
+// is kept below writeTimeout so that a slow upstream can still be reported to
+// the client.
+const fetchTimeout = 8 * time.Second
+
+// FeedFetcher retrieves and parses remote feed documents.
+type FeedFetcher interface {
+	Fetch(ctx context.Context, url string) (*fetcher.Response, error)
+	Discover(ctx context.Context, pageURL string) ([]*fetcher.Candidate, error)
+}
 
-	FeedService models.FeedService
+type Server struct {
+	Port      int
+	Env       string
+	Version   string
+	RateLimit RateLimitConfig
+
+	// TrustedOrigins are the origins allowed to make cross-origin requests.
+	TrustedOrigins []string
+
+	// Metrics, when set, records request metrics. They are served on
+	// MetricsAddr, which is kept off the public port so that scrapers need
+	// no credentials; an empty address disables the endpoint.
+	Metrics     *metrics.Registry
+	MetricsAddr string
+
+	FeedService  models.FeedService
+	ItemService  models.ItemService
+	UserService  models.UserService
+	TokenService models.TokenService
+
+	PermissionService   models.PermissionService
+	SubscriptionService models.SubscriptionService
+	ItemStateService    models.ItemStateService
+	FolderService       models.FolderService
+	FeedFetcher         FeedFetcher
+
+	// Transactor runs writes that must not be left half done, such as
+	// creating a user together with their permissions.
+	Transactor models.Transactor
 
 	server *http.Server
 	logger *slog.Logger
+
+	ipLimiter   *rateLimiter
+	userLimiter *rateLimiter
+	httpMetrics *httpMetrics
 }
 
 func NewServer(logger *slog.Logger) *Server {
@@ -36,12 +80,31 @@ func NewServer(logger *slog.Logger) *Server {
 	return s
 }
 
+// RegisterOnShutdown registers a function to call when the server begins a
+// graceful shutdown, so that background work can stop on the same signal.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.server.RegisterOnShutdown(f)
+}
+
 func (s *Server) Serve() error {
 	s.server.Handler = s.router()
 	s.server.Addr = fmt.Sprintf(":%d", s.Port)
 	s.server.IdleTimeout = time.Minute
 	s.server.ReadTimeout = 5 * time.Second
-	s.server.WriteTimeout = 10 * time.Second
+	s.server.WriteTimeout = writeTimeout
+
+	if s.RateLimit.Enabled {
+		cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+		defer stopCleanup()
+		s.server.RegisterOnShutdown(stopCleanup)
+
+		go s.cleanupRateLimiters(cleanupCtx)
+	}
+
+	if s.Metrics != nil && s.MetricsAddr != "" {
+		metricsServer := s.serveMetrics()
+		defer metricsServer.Close()
+	}
 
 	shutdownError := make(chan error)
 
@@ -75,3 +138,29 @@ func (s *Server) Serve() error {
 
 	return nil
 }
+
+// serveMetrics starts serving the metrics registry on MetricsAddr in the
+// background.
+func (s *Server) serveMetrics() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", s.Metrics.Handler())
+
+	metricsServer := &http.Server{
+		Addr:         s.MetricsAddr,
+		Handler:      mux,
+		ErrorLog:     s.server.ErrorLog,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		s.logger.Info("starting metrics server", "addr", s.MetricsAddr)
+
+		err := metricsServer.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("metrics server failed", "error", err)
+		}
+	}()
+
+	return metricsServer
+}
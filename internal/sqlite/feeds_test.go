@@ -0,0 +1,14 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/models/modelstest"
+)
+
+func TestFeedService_Conformance(t *testing.T) {
+	modelstest.TestFeedService(t, func(t *testing.T) models.FeedService {
+		return NewFeedService(newTestDB(t))
+	})
+}
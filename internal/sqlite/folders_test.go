@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestFolderService_Create_Errors(t *testing.T) {
+	db := newTestDB(t)
+	fs := NewFolderService(db)
+	alice := createUser(t, NewUserService(db), "alice@example.com")
+	bob := createUser(t, NewUserService(db), "bob@example.com")
+
+	if err := fs.Create(&models.Folder{UserID: alice.ID, Name: "News"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := fs.Create(&models.Folder{UserID: alice.ID, Name: "News"}); err != ErrDuplicateFolder {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateFolder)
+	}
+
+	if err := fs.Create(&models.Folder{UserID: bob.ID, Name: "News"}); err != nil {
+		t.Errorf("got error %v, want names to be unique per user only", err)
+	}
+}
+
+func TestFolderService_GetAllForUser(t *testing.T) {
+	db := newTestDB(t)
+	fs := NewFolderService(db)
+	ss := NewSubscriptionService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+
+	folder := &models.Folder{UserID: user.ID, Name: "News"}
+	if err := fs.Create(folder); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, url := range []string{"https://example.com/one.xml", "https://example.com/two.xml"} {
+		feed := createFeed(t, NewFeedService(db), url)
+		subscription := createSubscription(t, ss, user.ID, feed.ID)
+
+		subscription.FolderID = folder.ID
+		if err := ss.Update(subscription); err != nil {
+			t.Fatalf("failed to move subscription: %v", err)
+		}
+
+		createItems(t, NewItemService(db), feed.ID, start, "one", "two")
+	}
+
+	folders, err := fs.GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(folders) != 1 || folders[0].ID != folder.ID {
+		t.Fatalf("got %+v, want the user's folder", folders)
+	}
+	if folders[0].UnreadCount != 4 {
+		t.Errorf("got %d unread, want 4", folders[0].UnreadCount)
+	}
+
+	got, err := fs.Get(folder.ID, user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.UnreadCount != 4 {
+		t.Errorf("got %d unread, want 4", got.UnreadCount)
+	}
+}
+
+func TestFolderService_Update(t *testing.T) {
+	db := newTestDB(t)
+	fs := NewFolderService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+
+	for _, name := range []string{"News", "Blogs"} {
+		if err := fs.Create(&models.Folder{UserID: user.ID, Name: name}); err != nil {
+			t.Fatalf("failed to create folder: %v", err)
+		}
+	}
+
+	folder, err := fs.Get(2, user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := *folder
+
+	folder.Name = "News"
+	if err := fs.Update(folder); err != ErrDuplicateFolder {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateFolder)
+	}
+
+	folder.Name = "Reading"
+	if err := fs.Update(folder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := fs.Update(&stale); err != ErrEditConflict {
+		t.Errorf("got error %v, want %v", err, ErrEditConflict)
+	}
+}
+
+func TestFolderService_Delete(t *testing.T) {
+	db := newTestDB(t)
+	fs := NewFolderService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+
+	folder := &models.Folder{UserID: user.ID, Name: "News"}
+	if err := fs.Create(folder); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+
+	if err := fs.Delete(folder.ID, user.ID+1); err != ErrRecordNotFound {
+		t.Errorf("other user: got error %v, want %v", err, ErrRecordNotFound)
+	}
+
+	if err := fs.Delete(folder.ID, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := fs.Delete(folder.ID, user.ID); err != ErrRecordNotFound {
+		t.Errorf("got error %v, want %v", err, ErrRecordNotFound)
+	}
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type ItemStateService struct {
+	db DBTX
+}
+
+func NewItemStateService(db DBTX) *ItemStateService {
+	return &ItemStateService{db: db}
+}
+
+// Update only touches items whose feed the user subscribes to. A nil flag in
+// update leaves the stored value alone; a nil read on a new row means the item
+// follows the subscription's read watermark.
+func (ss *ItemStateService) Update(userID int64, itemIDs []int64, update models.ItemStateUpdate) (int64, error) {
+	query := `
+    INSERT INTO user_item_state (user_id, item_id, read, read_at, starred, starred_at)
+    SELECT $1, i.id,
+        $3, CASE WHEN $3 THEN CURRENT_TIMESTAMP END,
+        COALESCE($4, false), CASE WHEN $4 THEN CURRENT_TIMESTAMP END
+    FROM items i
+    INNER JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $1
+    WHERE i.id IN (SELECT value FROM json_each($2))
+    ON CONFLICT (user_id, item_id) DO UPDATE
+    SET read = CASE WHEN $3 IS NULL THEN user_item_state.read ELSE excluded.read END,
+        read_at = CASE WHEN $3 IS NULL THEN user_item_state.read_at ELSE excluded.read_at END,
+        starred = CASE WHEN $4 IS NULL THEN user_item_state.starred ELSE excluded.starred END,
+        starred_at = CASE WHEN $4 IS NULL THEN user_item_state.starred_at ELSE excluded.starred_at END`
+
+	args := []any{userID, jsonArray(itemIDs), nullBool(update.Read), nullBool(update.Starred)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := ss.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MarkSubscriptionRead moves the subscription's read watermark forward to
+// before and drops per-item read overrides it now covers. The watermark never
+// moves backwards, so an older timestamp cannot mark read items unread.
+//
+// SQLite can't update two tables in one statement, so the watermark moves
+// first: should clearing the overrides then fail, items the user marked
+// unread stay unread rather than being marked read behind their back.
+func (ss *ItemStateService) MarkSubscriptionRead(subscriptionID int64, userID int64, before time.Time) error {
+	if subscriptionID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    UPDATE subscriptions
+    SET read_before = CASE WHEN read_before IS NULL OR read_before < $3 THEN $3 ELSE read_before END
+    WHERE id = $1 AND user_id = $2
+    RETURNING feed_id`
+
+	var feedID int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ss.db.QueryRowContext(ctx, query, subscriptionID, userID, before).Scan(&feedID)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query = `
+    UPDATE user_item_state
+    SET read = NULL, read_at = NULL
+    WHERE user_id = $1
+    AND item_id IN (SELECT id FROM items WHERE feed_id = $2 AND published_at <= $3)`
+
+	_, err = ss.db.ExecContext(ctx, query, userID, feedID, before)
+	return err
+}
+
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
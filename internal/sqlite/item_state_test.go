@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestItemStateService_Update(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	ss := NewItemStateService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	other := createFeed(t, NewFeedService(db), "https://example.com/other.xml")
+	createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, is, feed.ID, start, "one", "two")
+	unsubscribed := createItems(t, is, other.ID, start, "three")
+
+	yes, no := true, false
+
+	updated, err := ss.Update(user.ID, append(ids, unsubscribed...), models.ItemStateUpdate{Starred: &yes})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("got %d items updated, want only the 2 subscribed ones", updated)
+	}
+
+	// Marking read keeps the star set above.
+	if _, err := ss.Update(user.ID, ids[:1], models.ItemStateUpdate{Read: &yes}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, _, err := is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[int64]models.ItemState{
+		ids[0]: {Read: true, Starred: true},
+		ids[1]: {Read: false, Starred: true},
+	}
+	for _, item := range items {
+		if *item.State != want[item.ID] {
+			t.Errorf("item %d: got state %+v, want %+v", item.ID, *item.State, want[item.ID])
+		}
+	}
+
+	if _, err := ss.Update(user.ID, ids, models.ItemStateUpdate{Starred: &no}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, _, _ = is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{PageSize: 10})
+	for _, item := range items {
+		if item.State.Starred {
+			t.Errorf("item %d: got starred, want the star removed", item.ID)
+		}
+	}
+}
+
+func TestItemStateService_MarkSubscriptionRead(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	ss := NewItemStateService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	subscription := createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, is, feed.ID, start, "one", "two", "three")
+
+	// An item explicitly marked unread is marked read by a newer watermark.
+	no := false
+	if _, err := ss.Update(user.ID, ids[:1], models.ItemStateUpdate{Read: &no}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ss.MarkSubscriptionRead(subscription.ID, user.ID, start.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An older watermark doesn't undo the newer one.
+	if err := ss.MarkSubscriptionRead(subscription.ID, user.ID, start); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, _, err := is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[int64]bool{ids[0]: true, ids[1]: true, ids[2]: false}
+	for _, item := range items {
+		if item.State.Read != want[item.ID] {
+			t.Errorf("item %d: got read %v, want %v", item.ID, item.State.Read, want[item.ID])
+		}
+	}
+
+	for _, id := range []int64{0, subscription.ID + 1} {
+		if err := ss.MarkSubscriptionRead(id, user.ID, start); err != ErrRecordNotFound {
+			t.Errorf("subscription %d: got error %v, want %v", id, err, ErrRecordNotFound)
+		}
+	}
+
+	if err := ss.MarkSubscriptionRead(subscription.ID, user.ID+1, start); err != ErrRecordNotFound {
+		t.Errorf("other user: got error %v, want %v", err, ErrRecordNotFound)
+	}
+}
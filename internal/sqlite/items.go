@@ -0,0 +1,296 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type ItemService struct {
+	db DBTX
+}
+
+func NewItemService(db DBTX) *ItemService {
+	return &ItemService{db: db}
+}
+
+// Upsert inserts the item, or refreshes the stored copy when the feed already
+// has an item with the same GUID whose content has changed. Unchanged items
+// are left untouched and item.ID is not populated.
+func (is *ItemService) Upsert(item *models.Item) error {
+	item.ContentHash = item.Hash()
+
+	query := `
+    INSERT INTO items (feed_id, guid, title, link, author, content, summary, published_at, updated_at, content_hash)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (feed_id, guid) DO UPDATE
+    SET title = excluded.title, link = excluded.link, author = excluded.author, content = excluded.content,
+        summary = excluded.summary, updated_at = excluded.updated_at, content_hash = excluded.content_hash
+    WHERE items.content_hash <> excluded.content_hash
+    RETURNING id, created_at`
+
+	args := []any{
+		item.FeedID,
+		item.GUID,
+		item.Title,
+		item.Link,
+		item.Author,
+		item.Content,
+		item.Summary,
+		item.PublishedAt,
+		item.UpdatedAt,
+		item.ContentHash,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := is.db.QueryRowContext(ctx, query, args...).Scan(&item.ID, &item.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
+}
+
+func (is *ItemService) Get(id int64) (*models.Item, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    SELECT id, feed_id, guid, title, link, author, content, summary, published_at, updated_at, content_hash, created_at
+    FROM items
+    WHERE id = $1`
+
+	var item models.Item
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := is.db.QueryRowContext(ctx, query, id).Scan(
+		&item.ID,
+		&item.FeedID,
+		&item.GUID,
+		&item.Title,
+		&item.Link,
+		&item.Author,
+		&item.Content,
+		&item.Summary,
+		&item.PublishedAt,
+		&item.UpdatedAt,
+		&item.ContentHash,
+		&item.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+
+// GetAllForFeed returns a page of the feed's items, newest first, starting
+// after filters.After, along with userID's state for each. Content is left
+// empty; clients fetch it per item.
+func (is *ItemService) GetAllForFeed(feedID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	query := `
+    SELECT i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.summary, i.published_at, i.updated_at,
+        COALESCE(st.read, i.published_at <= s.read_before, false), COALESCE(st.starred, false)
+    FROM items i
+    LEFT JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $5
+    LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = $5
+    WHERE i.feed_id = $1
+    AND ($2 = 0 OR (i.published_at, i.id) < ($3, $2))
+    ORDER BY i.published_at DESC, i.id DESC
+    LIMIT $4`
+
+	// Ask for one extra row to learn whether another page follows.
+	args := []any{feedID, filters.After.ID, filters.After.PublishedAt, filters.PageSize + 1, userID}
+
+	return is.queryPage(query, args, filters.PageSize)
+}
+
+// GetAllForFolder is like GetAllForFeed, but merges the items of every feed
+// userID has subscribed to in the folder into a single timeline.
+func (is *ItemService) GetAllForFolder(folderID int64, userID int64, filters models.CursorFilters) ([]*models.Item, models.CursorMetadata, error) {
+	query := `
+    SELECT i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.summary, i.published_at, i.updated_at,
+        COALESCE(st.read, i.published_at <= s.read_before, false), COALESCE(st.starred, false)
+    FROM items i
+    INNER JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $5
+    LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = $5
+    WHERE s.folder_id = $1
+    AND ($2 = 0 OR (i.published_at, i.id) < ($3, $2))
+    ORDER BY i.published_at DESC, i.id DESC
+    LIMIT $4`
+
+	// Ask for one extra row to learn whether another page follows.
+	args := []any{folderID, filters.After.ID, filters.After.PublishedAt, filters.PageSize + 1, userID}
+
+	return is.queryPage(query, args, filters.PageSize)
+}
+
+// Search returns a page of the items in userID's subscriptions that match
+// the search, with snippets highlighting the matching words. Titles weigh
+// more than summaries, which weigh more than the full content, and rank is
+// negated from bm25 so that, as in Postgres, better matches rank higher.
+func (is *ItemService) Search(userID int64, search models.ItemSearch, filters models.Filters) ([]*models.SearchResult, models.Metadata, error) {
+	match := webQuery(search.Query)
+	if match == "" {
+		return []*models.SearchResult{}, models.Metadata{}, nil
+	}
+
+	// FTS5's ranking and snippet functions can't be called from a query
+	// with window functions, so matches are scored before they are joined.
+	query := fmt.Sprintf(`
+    WITH matches AS MATERIALIZED (
+        SELECT rowid AS item_id,
+            -bm25(items_search, 1.0, 0.4, 0.2) AS rank,
+            CASE WHEN content <> ''
+                THEN snippet(items_search, 2, '<mark>', '</mark>', ' ... ', 35)
+                ELSE snippet(items_search, 1, '<mark>', '</mark>', ' ... ', 35)
+            END AS headline
+        FROM items_search
+        WHERE items_search MATCH $2
+    )
+    SELECT count(*) OVER(), i.id, i.feed_id, i.guid, i.title, i.link, i.author, i.summary, i.published_at, i.updated_at,
+        COALESCE(st.read, i.published_at <= s.read_before, false),
+        COALESCE(st.starred, false),
+        m.rank,
+        m.headline
+    FROM matches m
+    INNER JOIN items i ON i.id = m.item_id
+    INNER JOIN subscriptions s ON s.feed_id = i.feed_id AND s.user_id = $1
+    LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = $1
+    WHERE ($3 IS NULL OR i.published_at >= $3)
+    AND ($4 IS NULL OR i.published_at < $4)
+    ORDER BY %s %s, i.id DESC
+    LIMIT $5 OFFSET $6`, filters.SortColumn(), filters.SortDirection())
+
+	args := []any{
+		userID,
+		match,
+		nullTime(search.PublishedAfter),
+		nullTime(search.PublishedBefore),
+		filters.Limit(),
+		filters.Offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := is.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*models.SearchResult{}
+
+	for rows.Next() {
+		var item models.Item
+		var state models.ItemState
+		var result models.SearchResult
+
+		err := rows.Scan(
+			&totalRecords,
+			&item.ID,
+			&item.FeedID,
+			&item.GUID,
+			&item.Title,
+			&item.Link,
+			&item.Author,
+			&item.Summary,
+			&item.PublishedAt,
+			&item.UpdatedAt,
+			&state.Read,
+			&state.Starred,
+			&result.Rank,
+			&result.Headline,
+		)
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		item.State = &state
+		result.Item = &item
+		result.Headline = models.EscapeHeadline(result.Headline)
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return results, metadata, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// queryPage runs a timeline query that selects pageSize+1 items with their
+// state, and trims the extra row into the next cursor.
+func (is *ItemService) queryPage(query string, args []any, pageSize int) ([]*models.Item, models.CursorMetadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := is.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.CursorMetadata{}, err
+	}
+	defer rows.Close()
+
+	items := []*models.Item{}
+
+	for rows.Next() {
+		var item models.Item
+		var state models.ItemState
+
+		err := rows.Scan(
+			&item.ID,
+			&item.FeedID,
+			&item.GUID,
+			&item.Title,
+			&item.Link,
+			&item.Author,
+			&item.Summary,
+			&item.PublishedAt,
+			&item.UpdatedAt,
+			&state.Read,
+			&state.Starred,
+		)
+		if err != nil {
+			return nil, models.CursorMetadata{}, err
+		}
+
+		item.State = &state
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.CursorMetadata{}, err
+	}
+
+	metadata := models.CursorMetadata{PageSize: pageSize}
+
+	if len(items) > pageSize {
+		items = items[:pageSize]
+		last := items[len(items)-1]
+		metadata.NextCursor = models.Cursor{PublishedAt: last.PublishedAt, ID: last.ID}.Encode()
+	}
+
+	return items, metadata, nil
+}
@@ -0,0 +1,365 @@
+package sqlite
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// createItems stores an item per title in feedID, published an hour apart
+// from start, and returns their IDs oldest first.
+func createItems(t *testing.T, is *ItemService, feedID int64, start time.Time, titles ...string) []int64 {
+	t.Helper()
+
+	var ids []int64
+	for i, title := range titles {
+		published := start.Add(time.Duration(i) * time.Hour)
+
+		item := &models.Item{
+			FeedID:      feedID,
+			GUID:        title,
+			Title:       title,
+			Link:        "https://example.com/" + title,
+			Summary:     "Summary of " + title,
+			PublishedAt: published,
+			UpdatedAt:   published,
+		}
+		if err := is.Upsert(item); err != nil {
+			t.Fatalf("failed to create item: %v", err)
+		}
+
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
+
+func itemIDs(items []*models.Item) []int64 {
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestItemService_Upsert(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	item := &models.Item{FeedID: feed.ID, GUID: "guid-1", Title: "First", Content: "<p>Hello</p>", PublishedAt: published}
+	if err := is.Upsert(item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if item.ID < 1 || item.CreatedAt.IsZero() {
+		t.Fatalf("got ID %d and created at %v, want them set", item.ID, item.CreatedAt)
+	}
+
+	got, err := is.Get(item.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Title != "First" || got.Content != "<p>Hello</p>" || !got.PublishedAt.Equal(published) || got.ContentHash != item.Hash() {
+		t.Errorf("got %+v, want the stored item", got)
+	}
+
+	unchanged := &models.Item{FeedID: feed.ID, GUID: "guid-1", Title: "First", Content: "<p>Hello</p>", PublishedAt: published}
+	if err := is.Upsert(unchanged); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if unchanged.ID != 0 {
+		t.Errorf("got ID %d, want unchanged items left untouched", unchanged.ID)
+	}
+
+	changed := &models.Item{FeedID: feed.ID, GUID: "guid-1", Title: "Edited", PublishedAt: published}
+	if err := is.Upsert(changed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed.ID != item.ID {
+		t.Errorf("got ID %d, want the existing item %d refreshed", changed.ID, item.ID)
+	}
+
+	got, _ = is.Get(item.ID)
+	if got.Title != "Edited" {
+		t.Errorf("got title %q, want %q", got.Title, "Edited")
+	}
+}
+
+func TestItemService_Get_Errors(t *testing.T) {
+	is := NewItemService(newTestDB(t))
+
+	for _, id := range []int64{0, 42} {
+		if _, err := is.Get(id); err != ErrRecordNotFound {
+			t.Errorf("Get(%d): got error %v, want %v", id, err, ErrRecordNotFound)
+		}
+	}
+}
+
+func TestItemService_GetAllForFeed(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, is, feed.ID, start, "one", "two", "three")
+
+	items, metadata, err := is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{PageSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{ids[2], ids[1]}; !slices.Equal(got, want) {
+		t.Fatalf("got items %v, want %v", got, want)
+	}
+	if items[0].State == nil || items[0].State.Read || items[0].State.Starred {
+		t.Errorf("got state %+v, want unread and unstarred", items[0].State)
+	}
+	if metadata.NextCursor == "" {
+		t.Fatal("got no next cursor, want one")
+	}
+
+	// Cursors decode to local time; the page must not depend on the zone.
+	cursor, err := models.DecodeCursor(metadata.NextCursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cursor.PublishedAt = cursor.PublishedAt.In(time.FixedZone("UTC+5", 5*60*60))
+
+	items, metadata, err = is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{After: cursor, PageSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{ids[0]}; !slices.Equal(got, want) {
+		t.Errorf("got items %v, want %v", got, want)
+	}
+	if metadata.NextCursor != "" {
+		t.Errorf("got next cursor %q, want none on the last page", metadata.NextCursor)
+	}
+}
+
+func TestItemService_GetAllForFeed_OutOfRangeDates(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	// Bad feed dates beyond what UnixNano can represent must still page.
+	start := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, is, feed.ID, start, "one", "two", "three")
+
+	items, metadata, err := is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{PageSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{ids[2], ids[1]}; !slices.Equal(got, want) {
+		t.Fatalf("got items %v, want %v", got, want)
+	}
+
+	cursor, err := models.DecodeCursor(metadata.NextCursor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cursor.PublishedAt.Equal(items[1].PublishedAt) {
+		t.Errorf("got cursor time %v, want %v", cursor.PublishedAt, items[1].PublishedAt)
+	}
+
+	items, _, err = is.GetAllForFeed(feed.ID, user.ID, models.CursorFilters{After: cursor, PageSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := itemIDs(items), []int64{ids[0]}; !slices.Equal(got, want) {
+		t.Errorf("got items %v, want %v", got, want)
+	}
+}
+
+func TestItemService_GetAllForFolder(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	ss := NewSubscriptionService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+
+	folder := &models.Folder{UserID: user.ID, Name: "News"}
+	if err := NewFolderService(db).Create(folder); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+
+	inFolder := createFeed(t, NewFeedService(db), "https://example.com/one.xml")
+	outside := createFeed(t, NewFeedService(db), "https://example.com/two.xml")
+
+	subscription := createSubscription(t, ss, user.ID, inFolder.ID)
+	subscription.FolderID = folder.ID
+	if err := ss.Update(subscription); err != nil {
+		t.Fatalf("failed to move subscription: %v", err)
+	}
+	createSubscription(t, ss, user.ID, outside.ID)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := createItems(t, is, inFolder.ID, start, "one", "two")
+	createItems(t, is, outside.ID, start, "three")
+
+	items, _, err := is.GetAllForFolder(folder.ID, user.ID, models.CursorFilters{PageSize: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := itemIDs(items); !slices.Equal(got, []int64{want[1], want[0]}) {
+		t.Errorf("got items %v, want %v", got, []int64{want[1], want[0]})
+	}
+}
+
+func TestItemService_Search(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	other := createFeed(t, NewFeedService(db), "https://example.com/other.xml")
+	createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []*models.Item{
+		{GUID: "1", Title: "Go generics explained", Content: "<p>Type <b>parameters</b> arrived in Go 1.18.</p>", PublishedAt: start},
+		{GUID: "2", Title: "Rust ownership", Summary: "Borrowing and parameters of lifetimes.", PublishedAt: start.Add(time.Hour)},
+		{GUID: "3", Title: "Cooking pasta", Summary: "Boil the water first.", PublishedAt: start.Add(2 * time.Hour)},
+	}
+	for _, item := range items {
+		item.FeedID = feed.ID
+		if err := is.Upsert(item); err != nil {
+			t.Fatalf("failed to create item: %v", err)
+		}
+	}
+
+	// Items in feeds the user doesn't subscribe to are never found.
+	createItems(t, is, other.ID, start, "Go elsewhere")
+
+	tests := []struct {
+		name   string
+		search models.ItemSearch
+		want   []int64
+	}{
+		{"word", models.ItemSearch{Query: "go"}, []int64{items[0].ID}},
+		{"stemmed", models.ItemSearch{Query: "parameter"}, []int64{items[0].ID, items[1].ID}},
+		{"every word", models.ItemSearch{Query: "go rust"}, []int64{}},
+		{"or", models.ItemSearch{Query: "go or rust"}, []int64{items[0].ID, items[1].ID}},
+		{"excluded", models.ItemSearch{Query: "parameters -rust"}, []int64{items[0].ID}},
+		{"phrase", models.ItemSearch{Query: `"boil the water"`}, []int64{items[2].ID}},
+		{"phrase out of order", models.ItemSearch{Query: `"water the boil"`}, []int64{}},
+		{"only exclusions", models.ItemSearch{Query: "-go"}, []int64{}},
+		{"punctuation", models.ItemSearch{Query: `go* (! "`}, []int64{items[0].ID}},
+		{"markup is not indexed", models.ItemSearch{Query: "b"}, []int64{}},
+		{"published after", models.ItemSearch{Query: "parameters", PublishedAfter: start.Add(time.Minute)}, []int64{items[1].ID}},
+		{"published before", models.ItemSearch{Query: "parameters", PublishedBefore: start.Add(time.Minute)}, []int64{items[0].ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := models.Filters{Page: 1, PageSize: 20, Sort: "published_at", SortSafelist: []string{"published_at"}}
+
+			results, metadata, err := is.Search(user.ID, tt.search, filters)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := []int64{}
+			for _, result := range results {
+				got = append(got, result.ID)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got items %v, want %v", got, tt.want)
+			}
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("got %d total records, want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestItemService_Search_Ranking(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	inContent := &models.Item{FeedID: feed.ID, GUID: "1", Title: "Weekly notes", Content: "<p>A word on <em>databases</em> and more.</p>"}
+	inTitle := &models.Item{FeedID: feed.ID, GUID: "2", Title: "Databases", Summary: "An overview."}
+	for _, item := range []*models.Item{inContent, inTitle} {
+		if err := is.Upsert(item); err != nil {
+			t.Fatalf("failed to create item: %v", err)
+		}
+	}
+
+	filters := models.Filters{Page: 1, PageSize: 20, Sort: "-rank", SortSafelist: []string{"-rank"}}
+
+	results, _, err := is.Search(user.ID, models.ItemSearch{Query: "databases"}, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	if results[0].ID != inTitle.ID || results[0].Rank <= results[1].Rank {
+		t.Errorf("got item %d first with rank %v over %v, want title matches ranked higher",
+			results[0].ID, results[0].Rank, results[1].Rank)
+	}
+
+	if want := "<mark>databases</mark>"; !strings.Contains(results[1].Headline, want) || strings.Contains(results[1].Headline, "<em>") {
+		t.Errorf("got headline %q, want the content snippet with %q and no markup", results[1].Headline, want)
+	}
+	if want := "An overview."; results[0].Headline != want {
+		t.Errorf("got headline %q, want the summary %q when there is no content", results[0].Headline, want)
+	}
+}
+
+func TestItemService_Search_EscapesHeadline(t *testing.T) {
+	db := newTestDB(t)
+	is := NewItemService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	createSubscription(t, NewSubscriptionService(db), user.ID, feed.ID)
+
+	hostile := &models.Item{
+		FeedID:  feed.ID,
+		GUID:    "1",
+		Title:   "Hostile",
+		Content: `<p>Tom &amp; Jerry</p><script>alert(1)</script> generics <img src=x onerror=alert(2) generics`,
+	}
+	if err := is.Upsert(hostile); err != nil {
+		t.Fatalf("failed to create item: %v", err)
+	}
+
+	filters := models.Filters{Page: 1, PageSize: 20, Sort: "-rank", SortSafelist: []string{"-rank"}}
+
+	results, _, err := is.Search(user.ID, models.ItemSearch{Query: "generics"}, filters)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	headline := results[0].Headline
+	if strings.Count(headline, "<") != 2*strings.Count(headline, "<mark>") {
+		t.Errorf("got headline %q, want no markup besides the highlights", headline)
+	}
+	for _, want := range []string{"Tom &amp; Jerry", "&lt;img src=x onerror=alert(2)", "<mark>generics</mark>"} {
+		if !strings.Contains(headline, want) {
+			t.Errorf("got headline %q, want it to contain %q", headline, want)
+		}
+	}
+}
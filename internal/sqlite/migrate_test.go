@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/grodier/rss-app/migrations"
+)
+
+func newEmptyDB(t *testing.T) *DB {
+	t.Helper()
+
+	db := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func tableExists(t *testing.T, db *DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`, name).Scan(&exists)
+	if err != nil {
+		t.Fatalf("failed to look up table: %v", err)
+	}
+	return exists
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	ctx := context.Background()
+	db := newEmptyDB(t)
+
+	ms, err := migrations.Parse(migrations.SQLiteFS)
+	if err != nil {
+		t.Fatalf("failed to parse migrations: %v", err)
+	}
+	m := NewMigrator(db, ms)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(applied) != len(ms) {
+		t.Errorf("got %d migrations applied, want %d", len(applied), len(ms))
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("got %d migrations applied, want none when up to date", len(applied))
+	}
+
+	rolledBack, err := m.Down(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rolledBack != ms[len(ms)-1] {
+		t.Errorf("got %v rolled back, want the latest migration", rolledBack)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, status := range statuses {
+		if want := i < len(ms)-1; status.Applied() != want {
+			t.Errorf("%s: got applied %v, want %v", status.Name, status.Applied(), want)
+		}
+	}
+
+	if _, err := m.To(ctx, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, table := range []string{"feeds", "items", "items_search", "users", "subscriptions"} {
+		if tableExists(t, db, table) {
+			t.Errorf("got table %s, want every migration rolled back", table)
+		}
+	}
+
+	// Everything can be applied again after a full rollback.
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := newEmptyDB(t)
+
+	m := NewMigrator(db, []*migrations.Migration{
+		{Version: 1, Name: "00001_one.sql", Up: []string{"CREATE TABLE one (id integer);"}, Down: []string{"DROP TABLE one;"}},
+		{Version: 2, Name: "00002_two.sql", Up: []string{"CREATE TABLE two (id integer);", "NOT SQL;"}, Down: []string{"DROP TABLE two;"}},
+	})
+
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("got no error, want the invalid migration to fail")
+	}
+
+	if !tableExists(t, db, "one") || tableExists(t, db, "two") {
+		t.Error("want the first migration kept and the failed one rolled back")
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !statuses[0].Applied() || statuses[1].Applied() {
+		t.Errorf("got applied %v and %v, want only the first migration recorded", statuses[0].Applied(), statuses[1].Applied())
+	}
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+type PermissionService struct {
+	db DBTX
+}
+
+func NewPermissionService(db DBTX) *PermissionService {
+	return &PermissionService{db: db}
+}
+
+func (ps *PermissionService) GetAllForUser(userID int64) (models.Permissions, error) {
+	query := `
+    SELECT permissions.code
+    FROM permissions
+    INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+    WHERE users_permissions.user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ps.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions models.Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (ps *PermissionService) AddForUser(userID int64, codes ...string) error {
+	query := `
+    INSERT INTO users_permissions
+    SELECT $1, permissions.id FROM permissions WHERE permissions.code IN (SELECT value FROM json_each($2))
+    ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := ps.db.ExecContext(ctx, query, userID, jsonArray(codes))
+	return err
+}
+
+// jsonArray encodes values for json_each, as SQLite has no array parameters.
+func jsonArray[T any](values []T) string {
+	if values == nil {
+		return "[]"
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		// Slices of strings and integers always encode.
+		panic(err)
+	}
+	return string(b)
+}
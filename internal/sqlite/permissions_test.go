@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPermissionService_AddForUser(t *testing.T) {
+	db := newTestDB(t)
+	ps := NewPermissionService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+
+	permissions, err := ps.GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(permissions) != 0 {
+		t.Errorf("got permissions %v, want none", permissions)
+	}
+
+	if err := ps.AddForUser(user.ID, "feeds:read", "feeds:write", "unknown"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Adding a permission twice is a no-op.
+	if err := ps.AddForUser(user.ID, "feeds:read"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	permissions, err = ps.GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slices.Sort(permissions)
+	if want := []string{"feeds:read", "feeds:write"}; !slices.Equal(permissions, want) {
+		t.Errorf("got permissions %v, want %v", permissions, want)
+	}
+}
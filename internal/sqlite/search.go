@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The full-text indexes are queried with FTS5's query syntax, in which
+// punctuation and bare keywords are errors. Search input is therefore never
+// passed through as is; these functions rebuild it from quoted terms, which
+// FTS5 treats as literal text.
+
+// plainQuery converts text to a query matching documents that contain every
+// word in it, like Postgres's plainto_tsquery. It returns "" when text has
+// no words.
+func plainQuery(text string) string {
+	terms := []string{}
+	for _, word := range words(text) {
+		terms = append(terms, quoteTerm(word))
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// webQuery converts a query in web search syntax to a full-text query, like
+// Postgres's websearch_to_tsquery: words must all appear unless separated by
+// "or", quoted text must appear as a phrase, and words or phrases with a
+// leading "-" must not appear. It returns "" when nothing can be matched,
+// which includes queries that only exclude terms, as FTS5 can only exclude
+// terms from the matches of another.
+func webQuery(search string) string {
+	var (
+		b        strings.Builder
+		op       = " AND "
+		started  bool
+		excluded []string
+	)
+
+	for _, term := range splitWebSearch(search) {
+		switch {
+		case term.or:
+			if started {
+				op = " OR "
+			}
+		case term.negated && !started:
+			// Held back until there is a term to exclude it from.
+			excluded = append(excluded, term.text)
+		case term.negated:
+			b.WriteString(" NOT " + term.text)
+		default:
+			if started {
+				b.WriteString(op)
+			}
+			b.WriteString(term.text)
+
+			for _, text := range excluded {
+				b.WriteString(" NOT " + text)
+			}
+
+			started, op, excluded = true, " AND ", nil
+		}
+	}
+
+	return b.String()
+}
+
+type webTerm struct {
+	text    string
+	negated bool
+	or      bool
+}
+
+// splitWebSearch splits search into quoted terms and the "or" operators
+// between them. Punctuation inside a word splits it into a phrase, as
+// Postgres does.
+func splitWebSearch(search string) []webTerm {
+	var terms []webTerm
+
+	for s := strings.TrimSpace(search); s != ""; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		negated := false
+		if rest, ok := strings.CutPrefix(s, "-"); ok {
+			negated, s = true, rest
+		}
+
+		var text string
+
+		if rest, ok := strings.CutPrefix(s, `"`); ok {
+			text, s, _ = strings.Cut(rest, `"`)
+		} else {
+			end := strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			if end < 0 {
+				end = len(s)
+			}
+			text, s = s[:end], s[end:]
+
+			if !negated && strings.EqualFold(text, "or") {
+				terms = append(terms, webTerm{or: true})
+				continue
+			}
+		}
+
+		if ws := words(text); len(ws) > 0 {
+			terms = append(terms, webTerm{text: quoteTerm(strings.Join(ws, " ")), negated: negated})
+		}
+	}
+
+	return terms
+}
+
+// words splits text into runs of letters and digits, the way the indexes'
+// tokenizer does.
+func words(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func quoteTerm(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
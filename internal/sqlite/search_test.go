@@ -0,0 +1,52 @@
+package sqlite
+
+import "testing"
+
+func TestPlainQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"go", `"go"`},
+		{"Go Blog", `"Go" AND "Blog"`},
+		{`"quoted" OR NOT -x*`, `"quoted" AND "OR" AND "NOT" AND "x"`},
+		{"!?", ""},
+		{"café über", `"café" AND "über"`},
+	}
+
+	for _, tt := range tests {
+		if got := plainQuery(tt.text); got != tt.want {
+			t.Errorf("plainQuery(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWebQuery(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"", ""},
+		{"go", `"go"`},
+		{"go generics", `"go" AND "generics"`},
+		{"go or rust", `"go" OR "rust"`},
+		{"go OR rust zig", `"go" OR "rust" AND "zig"`},
+		{"or go", `"go"`},
+		{"go -rust", `"go" NOT "rust"`},
+		{"-rust go", `"go" NOT "rust"`},
+		{"-rust", ""},
+		{`"boil the water"`, `"boil the water"`},
+		{`-"boil the water" pasta`, `"pasta" NOT "boil the water"`},
+		{`"unterminated phrase`, `"unterminated phrase"`},
+		{"e-mail", `"e mail"`},
+		{`go* (NEAR) "`, `"go" AND "NEAR"`},
+		{"-or", ""},
+	}
+
+	for _, tt := range tests {
+		if got := webQuery(tt.search); got != tt.want {
+			t.Errorf("webQuery(%q) = %s, want %s", tt.search, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Package sqlite implements the storage services against a SQLite database,
+// as a single-file alternative to Postgres for small installations. Services
+// behave like their counterparts in pgsql, down to the errors they return, as
+// checked by the conformance tests in modelstest.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grodier/rss-app/internal/metrics"
+	"github.com/grodier/rss-app/internal/models"
+	modernc "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// DBTX abstracts query methods shared by *sql.DB and *sql.Tx.
+// This enables services to work with either and facilitates testing.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Verify DB and transactions implement DBTX at compile time.
+var (
+	_ DBTX = (*DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
+
+// The errors are shared with the other storage backends.
+var (
+	ErrRecordNotFound = models.ErrRecordNotFound
+	ErrEditConflict   = models.ErrEditConflict
+	ErrDuplicateEmail = models.ErrDuplicateEmail
+	ErrDuplicateURL   = models.ErrDuplicateURL
+
+	ErrDuplicateSubscription = models.ErrDuplicateSubscription
+	ErrDuplicateFolder       = models.ErrDuplicateFolder
+)
+
+// defaultQueryTimeout bounds queries when a service's QueryTimeout isn't
+// configured.
+const defaultQueryTimeout = 3 * time.Second
+
+// connParams configure every connection: foreign keys are off by default in
+// SQLite, writers wait for each other rather than failing, and transactions
+// take the write lock up front so they never fail to upgrade it midway.
+// Times are written in UTC at second precision, matching both the
+// CURRENT_TIMESTAMP column defaults and Postgres's timestamp(0), so that they
+// compare correctly as text.
+var connParams = []string{
+	"_pragma=foreign_keys(1)",
+	"_pragma=busy_timeout(5000)",
+	"_pragma=journal_mode(WAL)",
+	"_txlock=immediate",
+	"_time_format=datetime",
+	"_timezone=UTC",
+	"_texttotime=1",
+}
+
+// tagPattern matches the HTML tags stripped from item text before it is
+// indexed for search.
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+func init() {
+	// The search triggers call strip_tags, so it must be registered before
+	// any connection is opened.
+	modernc.MustRegisterDeterministicScalarFunction("strip_tags", 1, func(ctx *modernc.FunctionContext, args []driver.Value) (driver.Value, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return args[0], nil
+		}
+		return tagPattern.ReplaceAllString(s, " "), nil
+	})
+}
+
+// contextErr returns the context's error in place of err when the query failed
+// because ctx ended. SQLite reports a query interrupted by cancellation as an
+// ordinary error, which would otherwise hide that the client went away or the
+// query ran out of time.
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is SQLite rejecting a write because
+// it would break the unique constraint on columns, written as SQLite reports
+// them, e.g. "folders.user_id, folders.name".
+func isUniqueViolation(err error, columns string) bool {
+	var sqliteErr *modernc.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+		strings.Contains(sqliteErr.Error(), "UNIQUE constraint failed: "+columns)
+}
+
+type DB struct {
+	path string
+	db   *sql.DB
+
+	MaxOpenConnections int
+	MaxIdleConnections int
+	MaxIdleTime        time.Duration
+}
+
+// NewDB returns a DB for the database file at path, which is created on Open
+// if it doesn't exist. Connection parameters understood by the driver, such
+// as ?mode=ro, may follow the path.
+func NewDB(path string) *DB {
+	return &DB{path: path}
+}
+
+func (s *DB) Open() error {
+	sep := "?"
+	if strings.Contains(s.path, "?") {
+		sep = "&"
+	}
+
+	db, err := sql.Open("sqlite", "file:"+s.path+sep+strings.Join(connParams, "&"))
+	if err != nil {
+		return err
+	}
+	s.db = db
+
+	s.db.SetMaxOpenConns(s.MaxOpenConnections)
+	s.db.SetMaxIdleConns(s.MaxIdleConnections)
+	s.db.SetConnMaxIdleTime(s.MaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = s.db.PingContext(ctx)
+	if err != nil {
+		s.db.Close()
+		return err
+	}
+
+	return nil
+}
+
+func (s *DB) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back if it returns an error or panics. Services are bound to the
+// transaction by passing tx to their constructors, e.g. NewFeedService(tx).
+//
+// SQLite transactions are always serializable and take the write lock when
+// they begin, so unlike Postgres they never need to be retried.
+func (s *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx DBTX) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stats returns the connection pool statistics.
+func (s *DB) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// RegisterMetrics exposes the connection pool statistics on r, under the
+// same names as pgsql so that dashboards work with either backend.
+func (s *DB) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.", func() float64 {
+		return float64(s.Stats().MaxOpenConnections)
+	})
+	r.NewGaugeFunc("db_open_connections", "Number of established connections, both in use and idle.", func() float64 {
+		return float64(s.Stats().OpenConnections)
+	})
+	r.NewGaugeFunc("db_in_use_connections", "Number of connections currently in use.", func() float64 {
+		return float64(s.Stats().InUse)
+	})
+	r.NewGaugeFunc("db_idle_connections", "Number of idle connections.", func() float64 {
+		return float64(s.Stats().Idle)
+	})
+	r.NewCounterFunc("db_wait_count_total", "Number of connections waited for.", func() float64 {
+		return float64(s.Stats().WaitCount)
+	})
+	r.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for new connections.", func() float64 {
+		return s.Stats().WaitDuration.Seconds()
+	})
+	r.NewCounterFunc("db_max_idle_closed_total", "Number of connections closed due to the idle connection limit.", func() float64 {
+		return float64(s.Stats().MaxIdleClosed)
+	})
+	r.NewCounterFunc("db_max_idle_time_closed_total", "Number of connections closed due to the maximum idle time.", func() float64 {
+		return float64(s.Stats().MaxIdleTimeClosed)
+	})
+	r.NewCounterFunc("db_max_lifetime_closed_total", "Number of connections closed due to the maximum connection lifetime.", func() float64 {
+		return float64(s.Stats().MaxLifetimeClosed)
+	})
+}
+
+// DBTX interface implementation
+
+func (s *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return s.db.Exec(query, args...)
+}
+
+func (s *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, query, args...)
+}
+
+func (s *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(query, args...)
+}
+
+func (s *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+func (s *DB) QueryRow(query string, args ...any) *sql.Row {
+	return s.db.QueryRow(query, args...)
+}
+
+func (s *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, query, args...)
+}
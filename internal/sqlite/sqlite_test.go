@@ -0,0 +1,163 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/models"
+	"github.com/grodier/rss-app/internal/models/modelstest"
+	"github.com/grodier/rss-app/migrations"
+)
+
+// newTestDB returns a migrated database in a temporary file, closed when the
+// test ends.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	db := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err := db.Open(); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ms, err := migrations.Parse(migrations.SQLiteFS)
+	if err != nil {
+		t.Fatalf("failed to parse migrations: %v", err)
+	}
+
+	if _, err := NewMigrator(db, ms).Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return db
+}
+
+func createUser(t *testing.T, us *UserService, email string) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, Password: models.Password{Hash: []byte("hash")}}
+	if err := us.Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func createFeed(t *testing.T, fs *FeedService, url string) *models.Feed {
+	t.Helper()
+
+	feed := &models.Feed{Title: "Feed", URL: url, SiteURL: "https://example.com"}
+	if err := fs.Create(context.Background(), feed); err != nil {
+		t.Fatalf("failed to create feed: %v", err)
+	}
+	return feed
+}
+
+func createSubscription(t *testing.T, ss *SubscriptionService, userID, feedID int64) *models.Subscription {
+	t.Helper()
+
+	subscription := &models.Subscription{UserID: userID, FeedID: feedID}
+	if err := ss.Create(subscription); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	return subscription
+}
+
+func TestServices_Conformance(t *testing.T) {
+	modelstest.TestServices(t, func(t *testing.T) *models.Services {
+		db := newTestDB(t)
+
+		return &models.Services{
+			Feeds:         NewFeedService(db),
+			Items:         NewItemService(db),
+			Users:         NewUserService(db),
+			Tokens:        NewTokenService(db),
+			Permissions:   NewPermissionService(db),
+			Subscriptions: NewSubscriptionService(db),
+			ItemStates:    NewItemStateService(db),
+			Folders:       NewFolderService(db),
+		}
+	})
+}
+
+func TestDB_WithTx_Commit(t *testing.T) {
+	db := newTestDB(t)
+
+	err := db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		_, err := tx.Exec(`INSERT INTO permissions (code) VALUES ('feeds:admin')`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var count int
+	db.QueryRow(`SELECT count(*) FROM permissions WHERE code = 'feeds:admin'`).Scan(&count)
+	if count != 1 {
+		t.Errorf("got %d rows, want the insert committed", count)
+	}
+}
+
+func TestDB_WithTx_RollbackOnError(t *testing.T) {
+	db := newTestDB(t)
+	wantErr := errors.New("boom")
+
+	err := db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		if _, err := tx.Exec(`INSERT INTO permissions (code) VALUES ('feeds:admin')`); err != nil {
+			return err
+		}
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got error %v, want %v", err, wantErr)
+	}
+
+	var count int
+	db.QueryRow(`SELECT count(*) FROM permissions WHERE code = 'feeds:admin'`).Scan(&count)
+	if count != 0 {
+		t.Errorf("got %d rows, want the insert rolled back", count)
+	}
+}
+
+func TestDB_WithTx_RollbackOnPanic(t *testing.T) {
+	db := newTestDB(t)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("got panic %v, want it re-raised", p)
+		}
+
+		var count int
+		db.QueryRow(`SELECT count(*) FROM permissions WHERE code = 'feeds:admin'`).Scan(&count)
+		if count != 0 {
+			t.Errorf("got %d rows, want the insert rolled back", count)
+		}
+	}()
+
+	db.WithTx(context.Background(), nil, func(tx DBTX) error {
+		tx.Exec(`INSERT INTO permissions (code) VALUES ('feeds:admin')`)
+		panic("boom")
+	})
+}
+
+func TestDB_WithTx_ServicesShareTransaction(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	wantErr := errors.New("boom")
+
+	err := db.WithTx(ctx, nil, func(tx DBTX) error {
+		user := createUser(t, NewUserService(tx), "alice@example.com")
+		feed := createFeed(t, NewFeedService(tx), "https://example.com/feed.xml")
+
+		createSubscription(t, NewSubscriptionService(tx), user.ID, feed.ID)
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got error %v, want %v", err, wantErr)
+	}
+
+	if _, err := NewFeedService(db).GetByURL(ctx, "https://example.com/feed.xml"); err != ErrRecordNotFound {
+		t.Errorf("got error %v, want the feed rolled back", err)
+	}
+}
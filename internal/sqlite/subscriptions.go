@@ -0,0 +1,228 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+// unreadCountExpr counts the unread items of the subscription aliased as s.
+// It only scans items newer than the read watermark plus explicit overrides,
+// so it stays cheap for users who mark feeds read in bulk.
+const unreadCountExpr = `(
+            SELECT count(*)
+            FROM items i
+            LEFT JOIN user_item_state st ON st.item_id = i.id AND st.user_id = s.user_id
+            WHERE i.feed_id = s.feed_id
+            AND (s.read_before IS NULL OR i.published_at > s.read_before)
+            AND st.read IS NOT TRUE
+        ) + (
+            SELECT count(*)
+            FROM user_item_state st
+            INNER JOIN items i ON i.id = st.item_id
+            WHERE st.user_id = s.user_id
+            AND st.read = false
+            AND i.feed_id = s.feed_id
+            AND i.published_at <= s.read_before
+        )`
+
+type SubscriptionService struct {
+	db DBTX
+}
+
+func NewSubscriptionService(db DBTX) *SubscriptionService {
+	return &SubscriptionService{db: db}
+}
+
+func (ss *SubscriptionService) Create(subscription *models.Subscription) error {
+	query := `
+    INSERT INTO subscriptions (user_id, feed_id, title, folder_id)
+    VALUES ($1, $2, $3, NULLIF($4, 0))
+    RETURNING id, created_at, version`
+
+	args := []any{subscription.UserID, subscription.FeedID, subscription.Title, subscription.FolderID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ss.db.QueryRowContext(ctx, query, args...).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "subscriptions.user_id, subscriptions.feed_id"):
+			return ErrDuplicateSubscription
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns the subscription if it belongs to userID. Subscriptions of other
+// users are reported as not found.
+func (ss *SubscriptionService) Get(id int64, userID int64) (*models.Subscription, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    SELECT id, user_id, feed_id, title, COALESCE(folder_id, 0), created_at, version
+    FROM subscriptions
+    WHERE id = $1 AND user_id = $2`
+
+	var subscription models.Subscription
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ss.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&subscription.ID,
+		&subscription.UserID,
+		&subscription.FeedID,
+		&subscription.Title,
+		&subscription.FolderID,
+		&subscription.CreatedAt,
+		&subscription.Version,
+	)
+
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &subscription, nil
+}
+
+// GetAllForUser returns the user's subscriptions with their feeds and unread
+// counts, ordered by folder and then by the title the user sees.
+func (ss *SubscriptionService) GetAllForUser(userID int64) ([]*models.Subscription, error) {
+	query := `
+    SELECT s.id, s.user_id, s.feed_id, s.title, COALESCE(s.folder_id, 0), s.created_at, s.version,
+        f.id, f.title, f.description, f.url, f.site_url, f.language, f.created_at, f.version,
+        ` + unreadCountExpr + `
+    FROM subscriptions s
+    INNER JOIN feeds f ON f.id = s.feed_id
+    WHERE s.user_id = $1
+    ORDER BY s.folder_id NULLS FIRST, lower(COALESCE(NULLIF(s.title, ''), f.title)), s.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ss.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*models.Subscription{}
+
+	for rows.Next() {
+		var subscription models.Subscription
+		var feed models.Feed
+
+		err := rows.Scan(
+			&subscription.ID,
+			&subscription.UserID,
+			&subscription.FeedID,
+			&subscription.Title,
+			&subscription.FolderID,
+			&subscription.CreatedAt,
+			&subscription.Version,
+			&feed.ID,
+			&feed.Title,
+			&feed.Description,
+			&feed.URL,
+			&feed.SiteURL,
+			&feed.Language,
+			&feed.CreatedAt,
+			&feed.Version,
+			&subscription.UnreadCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		subscription.Feed = &feed
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// Update saves the subscription's title and folder, failing with
+// ErrEditConflict if it was changed since it was read.
+func (ss *SubscriptionService) Update(subscription *models.Subscription) error {
+	if subscription.ID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    UPDATE subscriptions
+    SET title = $1, folder_id = NULLIF($2, 0), version = version + 1
+    WHERE id = $3 AND user_id = $4 AND version = $5
+    RETURNING version`
+
+	args := []any{
+		subscription.Title,
+		subscription.FolderID,
+		subscription.ID,
+		subscription.UserID,
+		subscription.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := ss.db.QueryRowContext(ctx, query, args...).Scan(&subscription.Version)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the subscription if it belongs to userID. Subscriptions of
+// other users are reported as not found.
+func (ss *SubscriptionService) Delete(id int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    DELETE FROM subscriptions
+    WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := ss.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestSubscriptionService_Create_Errors(t *testing.T) {
+	db := newTestDB(t)
+	ss := NewSubscriptionService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	createSubscription(t, ss, user.ID, feed.ID)
+
+	err := ss.Create(&models.Subscription{UserID: user.ID, FeedID: feed.ID})
+	if err != ErrDuplicateSubscription {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateSubscription)
+	}
+}
+
+func TestSubscriptionService_GetAllForUser(t *testing.T) {
+	db := newTestDB(t)
+	ss := NewSubscriptionService(db)
+	is := NewItemService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	subscription := createSubscription(t, ss, user.ID, feed.ID)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := createItems(t, is, feed.ID, start, "one", "two", "three")
+
+	if err := NewItemStateService(db).MarkSubscriptionRead(subscription.ID, user.ID, start); err != nil {
+		t.Fatalf("failed to mark read: %v", err)
+	}
+
+	no := false
+	if _, err := NewItemStateService(db).Update(user.ID, ids[:1], models.ItemStateUpdate{Read: &no}); err != nil {
+		t.Fatalf("failed to mark unread: %v", err)
+	}
+
+	subscriptions, err := ss.GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(subscriptions) != 1 {
+		t.Fatalf("got %d subscriptions, want 1", len(subscriptions))
+	}
+
+	got := subscriptions[0]
+	if got.ID != subscription.ID || got.Feed == nil || got.Feed.URL != feed.URL {
+		t.Errorf("got %+v, want the subscription with its feed", got)
+	}
+	if got.UnreadCount != 3 {
+		t.Errorf("got %d unread, want 3", got.UnreadCount)
+	}
+}
+
+func TestSubscriptionService_Update(t *testing.T) {
+	db := newTestDB(t)
+	ss := NewSubscriptionService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	subscription := createSubscription(t, ss, user.ID, feed.ID)
+
+	folder := &models.Folder{UserID: user.ID, Name: "News"}
+	if err := NewFolderService(db).Create(folder); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+
+	stale := *subscription
+
+	subscription.Title = "Renamed"
+	subscription.FolderID = folder.ID
+	if err := ss.Update(subscription); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ss.Update(&stale); err != ErrEditConflict {
+		t.Errorf("got error %v, want %v", err, ErrEditConflict)
+	}
+
+	// Deleting the folder leaves the subscription outside any folder.
+	if err := NewFolderService(db).Delete(folder.ID, user.ID); err != nil {
+		t.Fatalf("failed to delete folder: %v", err)
+	}
+
+	got, err := ss.Get(subscription.ID, user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Title != "Renamed" || got.FolderID != 0 || got.Version != 2 {
+		t.Errorf("got %+v, want the renamed subscription without a folder", got)
+	}
+}
+
+func TestSubscriptionService_Delete(t *testing.T) {
+	db := newTestDB(t)
+	ss := NewSubscriptionService(db)
+	user := createUser(t, NewUserService(db), "alice@example.com")
+	feed := createFeed(t, NewFeedService(db), "https://example.com/feed.xml")
+	subscription := createSubscription(t, ss, user.ID, feed.ID)
+
+	if err := ss.Delete(subscription.ID, user.ID+1); err != ErrRecordNotFound {
+		t.Errorf("other user: got error %v, want %v", err, ErrRecordNotFound)
+	}
+
+	if err := ss.Delete(subscription.ID, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := ss.Get(subscription.ID, user.ID); err != ErrRecordNotFound {
+		t.Errorf("got error %v, want %v", err, ErrRecordNotFound)
+	}
+}
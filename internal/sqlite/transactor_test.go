@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestTransactor_WithTx(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	var user *models.User
+
+	err := NewTransactor(db).WithTx(ctx, func(tx *models.Services) error {
+		user = &models.User{Email: "alice@example.com", Password: models.Password{Hash: []byte("hash")}}
+		if err := tx.Users.Create(user); err != nil {
+			return err
+		}
+
+		return tx.Permissions.AddForUser(user.ID, models.DefaultPermissions...)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	permissions, err := NewPermissionService(db).GetAllForUser(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(permissions) != len(models.DefaultPermissions) {
+		t.Errorf("got permissions %v, want %v", permissions, models.DefaultPermissions)
+	}
+}
+
+func TestTransactor_WithTx_Rollback(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	wantErr := errors.New("permissions failed")
+
+	err := NewTransactor(db).WithTx(ctx, func(tx *models.Services) error {
+		user := &models.User{Email: "alice@example.com", Password: models.Password{Hash: []byte("hash")}}
+		if err := tx.Users.Create(user); err != nil {
+			return err
+		}
+
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got error %v, want %v", err, wantErr)
+	}
+
+	if _, err := NewUserService(db).GetByEmail("alice@example.com"); err != ErrRecordNotFound {
+		t.Errorf("got error %v, want the user rolled back", err)
+	}
+}
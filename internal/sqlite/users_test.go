@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grodier/rss-app/internal/models"
+)
+
+func TestUserService_Create_Errors(t *testing.T) {
+	db := newTestDB(t)
+	us := NewUserService(db)
+	createUser(t, us, "alice@example.com")
+
+	// Emails are case insensitive, as with Postgres's citext.
+	err := us.Create(&models.User{Email: "Alice@Example.com", Password: models.Password{Hash: []byte("hash")}})
+	if err != ErrDuplicateEmail {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateEmail)
+	}
+}
+
+func TestUserService_GetByEmail(t *testing.T) {
+	db := newTestDB(t)
+	us := NewUserService(db)
+	user := createUser(t, us, "alice@example.com")
+
+	got, err := us.GetByEmail("ALICE@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.ID != user.ID || string(got.Password.Hash) != "hash" || !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("got %+v, want %+v", got, user)
+	}
+
+	if _, err := us.GetByEmail("bob@example.com"); err != ErrRecordNotFound {
+		t.Errorf("got error %v, want %v", err, ErrRecordNotFound)
+	}
+}
+
+func TestUserService_GetForToken(t *testing.T) {
+	db := newTestDB(t)
+	us := NewUserService(db)
+	ts := NewTokenService(db)
+	user := createUser(t, us, "alice@example.com")
+
+	token := models.GenerateToken(user.ID, time.Hour, models.ScopeAuthentication)
+	expired := models.GenerateToken(user.ID, -time.Hour, models.ScopeAuthentication)
+	for _, tok := range []*models.Token{token, expired} {
+		if err := ts.Create(tok); err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+	}
+
+	got, err := us.GetForToken(models.ScopeAuthentication, token.Plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("got user %d, want %d", got.ID, user.ID)
+	}
+
+	tests := []struct {
+		name      string
+		scope     string
+		plaintext string
+	}{
+		{"expired", models.ScopeAuthentication, expired.Plaintext},
+		{"other scope", "activation", token.Plaintext},
+		{"unknown", models.ScopeAuthentication, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+	}
+
+	for _, tt := range tests {
+		if _, err := us.GetForToken(tt.scope, tt.plaintext); err != ErrRecordNotFound {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, ErrRecordNotFound)
+		}
+	}
+
+	if err := ts.DeleteAllForUser(models.ScopeAuthentication, user.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := us.GetForToken(models.ScopeAuthentication, token.Plaintext); err != ErrRecordNotFound {
+		t.Errorf("after DeleteAllForUser: got error %v, want %v", err, ErrRecordNotFound)
+	}
+}
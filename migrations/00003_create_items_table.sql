@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS items (
+  id bigserial PRIMARY KEY,
+  feed_id bigint NOT NULL REFERENCES feeds ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  guid text NOT NULL,
+  title text NOT NULL,
+  link text NOT NULL,
+  author text NOT NULL,
+  content text NOT NULL,
+  summary text NOT NULL,
+  published_at timestamp(0) with time zone NOT NULL,
+  updated_at timestamp(0) with time zone NOT NULL,
+  content_hash text NOT NULL,
+  UNIQUE (feed_id, guid)
+);
+
+CREATE INDEX IF NOT EXISTS items_feed_id_published_at_idx ON items (feed_id, published_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS items;
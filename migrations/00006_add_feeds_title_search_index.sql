@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS feeds_title_idx ON feeds USING GIN (to_tsvector('simple', title));
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_title_idx;
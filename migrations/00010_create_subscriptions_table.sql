@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS subscriptions (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  feed_id bigint NOT NULL REFERENCES feeds ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  title text NOT NULL DEFAULT '',
+  folder text NOT NULL DEFAULT '',
+  version integer NOT NULL DEFAULT 1,
+  UNIQUE (user_id, feed_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS subscriptions;
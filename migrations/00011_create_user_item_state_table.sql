@@ -0,0 +1,21 @@
+-- +goose Up
+-- Items published at or before read_before count as read unless a
+-- user_item_state row says otherwise, so marking a whole feed read is a single
+-- update rather than a row per item.
+ALTER TABLE subscriptions ADD COLUMN read_before timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS user_item_state (
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  item_id bigint NOT NULL REFERENCES items ON DELETE CASCADE,
+  read boolean,
+  starred boolean NOT NULL DEFAULT false,
+  read_at timestamp(0) with time zone,
+  starred_at timestamp(0) with time zone,
+  PRIMARY KEY (user_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_item_state_item_id_idx ON user_item_state (item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_item_state;
+ALTER TABLE subscriptions DROP COLUMN read_before;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS folders (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  name text NOT NULL,
+  version integer NOT NULL DEFAULT 1,
+  UNIQUE (user_id, name)
+);
+
+ALTER TABLE subscriptions ADD COLUMN folder_id bigint REFERENCES folders ON DELETE SET NULL;
+
+INSERT INTO folders (user_id, name)
+SELECT DISTINCT user_id, folder FROM subscriptions WHERE folder <> '';
+
+UPDATE subscriptions s
+SET folder_id = f.id
+FROM folders f
+WHERE f.user_id = s.user_id AND f.name = s.folder;
+
+ALTER TABLE subscriptions DROP COLUMN folder;
+
+CREATE INDEX IF NOT EXISTS subscriptions_folder_id_idx ON subscriptions (folder_id);
+
+-- +goose Down
+ALTER TABLE subscriptions ADD COLUMN folder text NOT NULL DEFAULT '';
+
+UPDATE subscriptions s
+SET folder = f.name
+FROM folders f
+WHERE f.id = s.folder_id;
+
+ALTER TABLE subscriptions DROP COLUMN folder_id;
+
+DROP TABLE IF EXISTS folders;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Entries are searched with the english configuration so that stemming
+-- matches "generic" against "generics". Titles rank above summaries, which
+-- rank above the full content.
+ALTER TABLE items ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(summary, '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS items_search_idx ON items USING GIN (search);
+
+-- +goose Down
+DROP INDEX IF EXISTS items_search_idx;
+
+ALTER TABLE items DROP COLUMN IF EXISTS search;
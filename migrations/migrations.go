@@ -0,0 +1,29 @@
+// Package migrations embeds the SQL migrations so the binary can apply them
+// without the files being deployed alongside it, and parses goose-style
+// migration files for the storage backends to apply.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS holds the goose-style migration files for the Postgres schema.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// SQLiteFS holds the migration files for the SQLite schema. SQLite databases
+// start from the current schema, so it has none of the history in FS.
+var SQLiteFS = mustSub(sqliteFiles, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,168 @@
+package migrations
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is a goose-style SQL migration. Statements are split the way
+// goose splits them: at a semicolon ending a line, except between
+// StatementBegin and StatementEnd annotations.
+type Migration struct {
+	Version int64
+	Name    string
+
+	Up   []string
+	Down []string
+
+	// NoTransaction is set by the NO TRANSACTION annotation, for statements
+	// such as CREATE INDEX CONCURRENTLY that can't run in a transaction.
+	NoTransaction bool
+}
+
+// Status reports whether a migration has been applied, and when.
+type Status struct {
+	*Migration
+	AppliedAt time.Time
+}
+
+func (s *Status) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// Parse reads the .sql files at the root of fsys, ordered by version. Files
+// are named like goose's, with a numeric version prefix:
+// 00001_create_feeds_table.sql.
+func Parse(fsys fs.FS) ([]*Migration, error) {
+	paths, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []*Migration{}
+	seen := make(map[int64]string)
+
+	for _, p := range paths {
+		prefix, _, ok := strings.Cut(p, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("%s: migration names must start with a positive version followed by _", p)
+		}
+
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("%s: version %d is also used by %s", p, version, other)
+		}
+		seen[version] = p
+
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		m.Version = version
+		m.Name = path.Base(p)
+
+		migrations = append(migrations, m)
+	}
+
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+func parse(src string) (*Migration, error) {
+	m := &Migration{}
+
+	var (
+		section   *[]string
+		buf       strings.Builder
+		inBlock   bool
+		sawUp     bool
+		lineCount int
+	)
+
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" && section != nil {
+			*section = append(*section, stmt)
+		}
+		buf.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineCount++
+		trimmed := strings.TrimSpace(line)
+
+		if annotation, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				flush()
+				section, sawUp = &m.Up, true
+			case "Down":
+				if inBlock {
+					return nil, fmt.Errorf("line %d: StatementBegin is not closed", lineCount)
+				}
+				flush()
+				section = &m.Down
+			case "StatementBegin":
+				flush()
+				inBlock = true
+			case "StatementEnd":
+				if !inBlock {
+					return nil, fmt.Errorf("line %d: StatementEnd without StatementBegin", lineCount)
+				}
+				inBlock = false
+				flush()
+			case "NO TRANSACTION":
+				m.NoTransaction = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown annotation %q", lineCount, annotation)
+			}
+			continue
+		}
+
+		if section == nil || (!inBlock && strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if inBlock {
+		return nil, errors.New("StatementBegin is not closed")
+	}
+
+	if !sawUp {
+		return nil, errors.New("missing +goose Up annotation")
+	}
+
+	flush()
+
+	return m, nil
+}
@@ -0,0 +1,139 @@
+package migrations
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"00002_create_items.sql": {Data: []byte(`-- +goose Up
+-- A comment that is not part of any statement.
+CREATE TABLE items (
+  id bigserial PRIMARY KEY
+);
+CREATE INDEX items_id_idx ON items (id);
+
+-- +goose Down
+DROP TABLE items;
+`)},
+		"00001_create_function.sql": {Data: []byte(`-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+  -- Kept, since it is inside the statement.
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION touch;
+`)},
+		"00003_concurrent_index.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY items_created_idx ON items (created_at);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY items_created_idx;
+`)},
+		"README.md": {Data: []byte("not a migration")},
+	}
+
+	got, err := Parse(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d migrations, want 3", len(got))
+	}
+
+	for i, want := range []int64{1, 2, 3} {
+		if got[i].Version != want {
+			t.Errorf("migration %d: got version %d, want %d", i, got[i].Version, want)
+		}
+	}
+
+	if got[0].Name != "00001_create_function.sql" {
+		t.Errorf("got name %q, want %q", got[0].Name, "00001_create_function.sql")
+	}
+
+	if len(got[0].Up) != 1 || !strings.Contains(got[0].Up[0], "Kept, since") || !strings.HasSuffix(got[0].Up[0], "plpgsql;") {
+		t.Errorf("expected the function to be a single statement, got %q", got[0].Up)
+	}
+
+	if len(got[1].Up) != 2 || strings.Contains(got[1].Up[0], "A comment") {
+		t.Errorf("expected two statements without comments, got %q", got[1].Up)
+	}
+
+	if !slices.Equal(got[1].Down, []string{"DROP TABLE items;"}) {
+		t.Errorf("got down statements %q", got[1].Down)
+	}
+
+	if got[1].NoTransaction || !got[2].NoTransaction {
+		t.Error("expected only the concurrent index migration to run outside a transaction")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		src     string
+		wantErr string
+	}{
+		{"bad name", "create_items.sql", "-- +goose Up\nSELECT 1;\n", "positive version"},
+		{"missing up", "00001_x.sql", "SELECT 1;\n", "missing +goose Up"},
+		{"unclosed block", "00001_x.sql", "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n", "not closed"},
+		{"stray end", "00001_x.sql", "-- +goose Up\n-- +goose StatementEnd\n", "without StatementBegin"},
+		{"unknown annotation", "00001_x.sql", "-- +goose Sideways\n", "unknown annotation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(fstest.MapFS{tt.file: {Data: []byte(tt.src)}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("duplicate version", func(t *testing.T) {
+		_, err := Parse(fstest.MapFS{
+			"00001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			"00001_b.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		})
+		if err == nil || !strings.Contains(err.Error(), "also used by") {
+			t.Errorf("got error %v, want a duplicate version error", err)
+		}
+	})
+}
+
+func TestParse_Embedded(t *testing.T) {
+	for name, fsys := range map[string]fs.FS{"postgres": FS, "sqlite": SQLiteFS} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse(fsys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) == 0 {
+				t.Fatal("expected embedded migrations")
+			}
+
+			for i, m := range got {
+				if m.Version != int64(i+1) {
+					t.Errorf("got version %d at position %d, want versions without gaps", m.Version, i)
+				}
+				if len(m.Up) == 0 || len(m.Down) == 0 {
+					t.Errorf("%s: expected both up and down statements", m.Name)
+				}
+			}
+		})
+	}
+}
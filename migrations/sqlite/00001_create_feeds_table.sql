@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS feeds (
+  id integer PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  title text NOT NULL,
+  description text NOT NULL,
+  url text NOT NULL UNIQUE,
+  site_url text NOT NULL,
+  language text,
+  version integer NOT NULL DEFAULT 1,
+  next_fetch_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  etag text NOT NULL DEFAULT '',
+  last_modified text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+-- Titles are searched like Postgres's 'simple' configuration: split into
+-- words and lowercased, without stemming.
+CREATE VIRTUAL TABLE IF NOT EXISTS feeds_search USING fts5 (
+  title,
+  content = 'feeds',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 0'
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS feeds_search_insert AFTER INSERT ON feeds BEGIN
+  INSERT INTO feeds_search (rowid, title) VALUES (new.id, new.title);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS feeds_search_update AFTER UPDATE OF title ON feeds BEGIN
+  INSERT INTO feeds_search (feeds_search, rowid, title) VALUES ('delete', old.id, old.title);
+  INSERT INTO feeds_search (rowid, title) VALUES (new.id, new.title);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS feeds_search_delete AFTER DELETE ON feeds BEGIN
+  INSERT INTO feeds_search (feeds_search, rowid, title) VALUES ('delete', old.id, old.title);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS feeds_search_delete;
+DROP TRIGGER IF EXISTS feeds_search_update;
+DROP TRIGGER IF EXISTS feeds_search_insert;
+DROP TABLE IF EXISTS feeds_search;
+DROP TABLE IF EXISTS feeds;
@@ -0,0 +1,57 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS items (
+  id integer PRIMARY KEY,
+  feed_id integer NOT NULL REFERENCES feeds ON DELETE CASCADE,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  guid text NOT NULL,
+  title text NOT NULL,
+  link text NOT NULL,
+  author text NOT NULL,
+  content text NOT NULL,
+  summary text NOT NULL,
+  published_at timestamp NOT NULL,
+  updated_at timestamp NOT NULL,
+  content_hash text NOT NULL,
+  UNIQUE (feed_id, guid)
+);
+
+CREATE INDEX IF NOT EXISTS items_feed_id_published_at_idx ON items (feed_id, published_at DESC);
+
+-- Entries are stemmed with the porter stemmer so that "generic" matches
+-- "generics", as with Postgres's english configuration. The index keeps its
+-- own copy of the text with HTML tags stripped, so that snippets built from
+-- it are plain text. strip_tags is registered by the application.
+CREATE VIRTUAL TABLE IF NOT EXISTS items_search USING fts5 (
+  title,
+  summary,
+  content,
+  tokenize = 'porter unicode61'
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS items_search_insert AFTER INSERT ON items BEGIN
+  INSERT INTO items_search (rowid, title, summary, content)
+  VALUES (new.id, new.title, strip_tags(new.summary), strip_tags(new.content));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS items_search_update AFTER UPDATE OF title, summary, content ON items BEGIN
+  UPDATE items_search
+  SET title = new.title, summary = strip_tags(new.summary), content = strip_tags(new.content)
+  WHERE rowid = new.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS items_search_delete AFTER DELETE ON items BEGIN
+  DELETE FROM items_search WHERE rowid = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS items_search_delete;
+DROP TRIGGER IF EXISTS items_search_update;
+DROP TRIGGER IF EXISTS items_search_insert;
+DROP TABLE IF EXISTS items_search;
+DROP TABLE IF EXISTS items;
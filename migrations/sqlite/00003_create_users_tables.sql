@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS users (
+  id integer PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  email text NOT NULL UNIQUE COLLATE NOCASE,
+  password_hash blob NOT NULL,
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS tokens (
+  hash blob PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  expiry timestamp NOT NULL,
+  scope text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+  id integer PRIMARY KEY,
+  code text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS users_permissions (
+  user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  permission_id integer NOT NULL REFERENCES permissions ON DELETE CASCADE,
+  PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT INTO permissions (code)
+VALUES
+  ('feeds:read'),
+  ('feeds:write'),
+  ('admin');
+
+-- +goose Down
+DROP TABLE IF EXISTS users_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS folders (
+  id integer PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  name text NOT NULL,
+  version integer NOT NULL DEFAULT 1,
+  UNIQUE (user_id, name)
+);
+
+-- Items published at or before read_before count as read unless a
+-- user_item_state row says otherwise, so marking a whole feed read is a single
+-- update rather than a row per item.
+CREATE TABLE IF NOT EXISTS subscriptions (
+  id integer PRIMARY KEY,
+  user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  feed_id integer NOT NULL REFERENCES feeds ON DELETE CASCADE,
+  folder_id integer REFERENCES folders ON DELETE SET NULL,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  title text NOT NULL DEFAULT '',
+  read_before timestamp,
+  version integer NOT NULL DEFAULT 1,
+  UNIQUE (user_id, feed_id)
+);
+
+CREATE INDEX IF NOT EXISTS subscriptions_folder_id_idx ON subscriptions (folder_id);
+
+CREATE TABLE IF NOT EXISTS user_item_state (
+  user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+  item_id integer NOT NULL REFERENCES items ON DELETE CASCADE,
+  read boolean,
+  starred boolean NOT NULL DEFAULT false,
+  read_at timestamp,
+  starred_at timestamp,
+  PRIMARY KEY (user_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_item_state_item_id_idx ON user_item_state (item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_item_state;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS folders;